@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository for operations spanning all data of a user, like the deletion and export of an account
+type AccountRepository interface {
+	InitRepo() error
+	// requests the deletion of the account of <userID> after <deleteAfter>.
+	// An already requested deletion is returned unchanged
+	ScheduleDeletion(userID Snowflake, requestedAt time.Time, deleteAfter time.Time) (*AccountDeletion, error)
+	// returns the requested deletion of <userID> or nil, if there is none
+	FetchDeletion(userID Snowflake) (*AccountDeletion, error)
+	CancelDeletion(userID Snowflake) error
+	// returns all deletions, whose grace period is over at <now>
+	FetchDueDeletions(now time.Time) ([]AccountDeletion, error)
+	// deletes the user <userID> and all of his data in one transaction
+	Purge(userID Snowflake) error
+	// returns everything stored about the user <userID>
+	Export(userID Snowflake) (*AccountExport, error)
+}
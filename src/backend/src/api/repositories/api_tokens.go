@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for APIToken table
+type APITokenRepository interface {
+	InitRepo() error
+	Create(token *APIToken) (*APIToken, error)
+	// returns the token with the SHA-256 <hash> or nil, if there is none
+	FetchByHash(hash string) (*APIToken, error)
+	FetchByUserID(userID Snowflake) ([]APIToken, error)
+	// revokes the token with <id>, if it belongs to <userID>
+	Revoke(id Snowflake, userID Snowflake, now time.Time) error
+	// sets the last usage of the token with <id> to <now>
+	Touch(id Snowflake, now time.Time) error
+}
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for the CalendarFeed table
+type CalendarFeedRepository interface {
+	InitRepo() error
+	// returns the feed of <userID> or nil, if there is none
+	FetchByUserID(userID Snowflake) (*CalendarFeed, error)
+	// returns the feed with the token with the SHA-256 <hash> or nil, if there is none
+	FetchByHash(hash string) (*CalendarFeed, error)
+	// creates or replaces the feed of its user, which revokes the previous token
+	Save(feed *CalendarFeed) (*CalendarFeed, error)
+	Delete(userID Snowflake) error
+	// sets the last fetch of the feed of <userID> to <now>
+	Touch(userID Snowflake, now time.Time) error
+}
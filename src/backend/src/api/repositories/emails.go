@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository for the email subscriptions of users and the log of sent emails
+type EmailRepository interface {
+	InitRepo() error
+	// returns the notification types, which <userID> receives by email
+	FetchSubscriptions(userID Snowflake) ([]NotificationType, error)
+	IsSubscribed(userID Snowflake, notificationType NotificationType) (bool, error)
+	// replaces the subscriptions of <userID> with <types>
+	SaveSubscriptions(userID Snowflake, types []NotificationType, now time.Time) error
+	Unsubscribe(userID Snowflake, notificationType NotificationType) error
+	LogDelivery(delivery *EmailDelivery) error
+	// returns the emails sent to <userID>, newest first
+	FetchDeliveries(userID Snowflake, limit int) ([]EmailDelivery, error)
+}
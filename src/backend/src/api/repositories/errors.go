@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors of the repositories. Errors returned by repositories wrap one of them,
+// so that the API can answer with the matching status code.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrConflict  = errors.New("conflict")
+	ErrGone      = errors.New("gone")
+)
+
+// Error is a repository error with a message, which matches its sentinel <Kind> with errors.Is
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NotFound returns an error matching ErrNotFound
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden returns an error matching ErrForbidden
+func Forbidden(format string, args ...any) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict returns an error matching ErrConflict
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Gone returns an error matching ErrGone
+func Gone(format string, args ...any) error {
+	return &Error{Kind: ErrGone, Message: fmt.Sprintf(format, args...)}
+}
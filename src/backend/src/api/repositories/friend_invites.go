@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for FriendInvite table
+type FriendInviteRepository interface {
+	InitRepo() error
+	Create(invite *FriendInvite) (*FriendInvite, error)
+	FetchByCode(code string) (*FriendInvite, error)
+	FetchByCreator(creatorID Snowflake) ([]FriendInvite, error)
+	// revokes the invite with <code>, if it was created by <creatorID>
+	Revoke(code string, creatorID Snowflake, now time.Time) error
+	// counts one use of the invite with <code>, if it is still usable at <now>
+	Use(code string, now time.Time) (*FriendInvite, error)
+	// makes <redeemerID> an accepted friend of the creator of the invite with <code> and counts
+	// one use, both in one transaction. Returns whether they were not friends before
+	Redeem(code string, redeemerID Snowflake, now time.Time) (*FriendInvite, bool, error)
+	// deletes all invites, which expired, were revoked or used up at <now>
+	DeleteUnusable(now time.Time) (int64, error)
+}
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository for the accounts linked to game-stats connectors and their processed matches
+type GameAccountRepository interface {
+	InitRepo() error
+	// links <account>. Fails with ErrConflict, if the user already linked an account of the connector
+	Create(account *GameAccount) (*GameAccount, error)
+	FetchByUserID(userID Snowflake) ([]GameAccount, error)
+	// unlinks the account <id>, if it belongs to <userID>
+	Delete(id Snowflake, userID Snowflake) error
+	// returns up to <limit> accounts of <connector>, which were not polled since <polledBefore>
+	FetchDue(connector string, polledBefore time.Time, limit int) ([]GameAccount, error)
+	// returns the IDs of the processed matches of <account>, which ended after <since>
+	FetchProcessedMatchIDs(account *GameAccount, since time.Time) (map[string]bool, error)
+	// stores the matches of <account> not processed yet, adds their deaths to the overdue deaths and
+	// sets the account to polled at <now>. Returns the newly processed matches
+	RecordMatches(account *GameAccount, matches []ProcessedMatch, now time.Time) ([]ProcessedMatch, error)
+}
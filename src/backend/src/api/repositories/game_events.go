@@ -0,0 +1,21 @@
+package repositories
+
+import . "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+
+// Repository for the signing secrets and the audit log of game events
+type GameEventRepository interface {
+	InitRepo() error
+	// returns the signing secret of <userID> or nil, if there is none
+	FetchSecret(userID Snowflake) (*EventSigningSecret, error)
+	// creates or replaces the signing secret of its user
+	SaveSecret(secret *EventSigningSecret) (*EventSigningSecret, error)
+	DeleteSecret(userID Snowflake) error
+	// stores <event>. Returns false, if an event with the same ID was stored before
+	Record(event *GameEvent) (bool, error)
+	// returns the event <eventID> of <userID>
+	FetchEvent(userID Snowflake, eventID string) (*GameEvent, error)
+	// sets status and error of the stored <event>
+	UpdateStatus(event *GameEvent) error
+	// returns the events of <userID>, newest first
+	FetchByUserID(userID Snowflake, limit int) ([]GameEvent, error)
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for the GameSession table
+type GameSessionRepository interface {
+	InitRepo() error
+	// creates a live session. Fails with ErrConflict, if the user already has a live session
+	Create(session *GameSession) (*GameSession, error)
+	Fetch(id Snowflake) (*GameSession, error)
+	// returns the live session of <userID> or nil, if there is none
+	FetchLive(userID Snowflake) (*GameSession, error)
+	// returns the sessions of <userID>, newest first
+	FetchByUserID(userID Snowflake, limit int, offset int) ([]GameSession, error)
+	// updates game, deaths and outcome of the session. Fails with ErrConflict, if the deaths are
+	// less than the deaths the live death counter added to the session when it was fetched
+	Update(session *GameSession) (*GameSession, error)
+	// ends the live session <id> of <userID> at <endedAt> and adds <overdueDeaths> of its
+	// deaths to the overdue deaths of its game
+	Close(id Snowflake, userID Snowflake, endedAt time.Time, overdueDeaths int64) (*GameSession, error)
+	// returns the sports linked to the session <id>
+	FetchSports(id Snowflake) ([]Sport, error)
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository for the runs and locks of the background jobs
+type JobRunRepository interface {
+	InitRepo() error
+	// returns the run of the job <name>, which is created, if it doesn't exist yet
+	Fetch(name string) (*JobRun, error)
+	// locks the job <name> for <owner> until <until>. The lock is only acquired, if the job is not
+	// locked at <now> and its last run is still <lastRunAt>, so that a run finished by another
+	// process in the meantime is not repeated. Returns whether the lock was acquired
+	Lock(name string, owner string, lastRunAt *time.Time, now time.Time, until time.Time) (bool, error)
+	// stores the run started at <startedAt> and releases the lock of <owner>
+	Finish(name string, owner string, startedAt time.Time, duration time.Duration, runErr error) error
+}
@@ -0,0 +1,15 @@
+package repositories
+
+import . "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+
+// Repository for the deaths counted by the live death counter
+type LiveDeathRepository interface {
+	InitRepo() error
+	// returns all counts, which were not flushed yet
+	FetchAll() ([]LiveDeathCount, error)
+	// stores <counts> as the current snapshot. Counts of 0 are removed
+	Save(counts []LiveDeathCount) error
+	// adds <counts> to the overdue deaths and the live game session of their game and removes
+	// them from the snapshot in one transaction
+	Flush(counts []LiveDeathCount) error
+}
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository for the in-app notifications of users
+type NotificationRepository interface {
+	InitRepo() error
+	Create(notification *Notification) (*Notification, error)
+	// returns the notifications of <userID> matching <filter>, newest first
+	FetchByUserID(userID Snowflake, filter NotificationFilter) ([]Notification, error)
+	CountUnread(userID Snowflake) (int64, error)
+	// marks the notification <id> of <userID> as read at <now>
+	MarkRead(userID Snowflake, id Snowflake, now time.Time) error
+	// marks all unread notifications of <userID> as read at <now>
+	MarkAllRead(userID Snowflake, now time.Time) (int64, error)
+	// deletes all notifications, which were read before <before>
+	DeleteReadBefore(before time.Time) (int64, error)
+}
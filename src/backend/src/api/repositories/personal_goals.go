@@ -7,6 +7,9 @@ type PersonalGoalsRepository interface {
 	InitRepo() error
 	Insert(goal *PersonalGoal) (*PersonalGoal, error)
 	Update(goal *PersonalGoal) (*PersonalGoal, error)
-	FetchByUserID(userID Snowflake, requester Snowflake) ([]PersonalGoal, error)
+	// returns the goals of <userID>. The visibility has to be checked with the visibility service
+	FetchByUserID(userID Snowflake) ([]PersonalGoal, error)
 	DeleteByID(goal *PersonalGoal) (*PersonalGoal, error)
+	// returns the goals of all users ordered by user
+	FetchAll() ([]PersonalGoal, error)
 }
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for the PushSubscription table
+type PushSubscriptionRepository interface {
+	InitRepo() error
+	// stores <subscription> or replaces the subscription with the same endpoint, since browsers
+	// keep the endpoint, when the user or the keys change
+	Save(subscription *PushSubscription) (*PushSubscription, error)
+	// returns the subscriptions of <userID>, newest first
+	FetchByUserID(userID Snowflake) ([]PushSubscription, error)
+	// deletes the subscription with <id>, if it belongs to <userID>
+	Delete(id Snowflake, userID Snowflake) error
+	// deletes the subscription with <endpoint>, e.g. when the push service reports it as expired
+	DeleteByEndpoint(endpoint string) error
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for the Session table
+type SessionRepository interface {
+	InitRepo() error
+	// returns the session with <id> or nil, if there is none
+	Fetch(id string) (*Session, error)
+	// creates or updates <session>
+	Save(session *Session) error
+	// sets the last usage of the session with <id> to <now>
+	Touch(id string, now time.Time) error
+	Delete(id string) error
+	// returns all sessions of <userID>, most recently used first
+	FetchByUserID(userID Snowflake) ([]Session, error)
+	// deletes the session with the public ID <publicID>, if it belongs to <userID>
+	DeleteByPublicID(publicID string, userID Snowflake) error
+	// deletes sessions which expired at <now> or were not used since <idleSince>
+	DeleteExpired(now time.Time, idleSince time.Time) (int64, error)
+}
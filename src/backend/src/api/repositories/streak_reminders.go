@@ -0,0 +1,12 @@
+package repositories
+
+import . "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+
+// Repository for the streak reminders sent by the Discord bot
+type StreakReminderRepository interface {
+	InitRepo() error
+	// returns the reminder settings of <userID>. Users without settings get enabled reminders
+	Fetch(userID Snowflake) (*StreakReminder, error)
+	// creates or replaces the settings of the user of <reminder>
+	Save(reminder *StreakReminder) error
+}
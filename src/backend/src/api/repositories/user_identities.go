@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Repository with basic operations for UserIdentity table
+type UserIdentityRepository interface {
+	InitRepo() error
+	// returns the identity of <subject> at <provider> or nil, if it's not linked to any user
+	FetchByProviderSubject(provider string, subject string) (*UserIdentity, error)
+	FetchByUserID(userID Snowflake) ([]UserIdentity, error)
+	// returns up to <limit> identities of <provider> with a refresh token, whose user had an active
+	// session since <activeSince> and which were not refreshed since <refreshedBefore>. Least recently
+	// refreshed identities come first
+	FetchRefreshable(provider string, activeSince time.Time, refreshedBefore time.Time, limit int) ([]UserIdentity, error)
+	Create(identity *UserIdentity) (*UserIdentity, error)
+	Update(identity *UserIdentity) (*UserIdentity, error)
+	// removes the identity of <provider> from the user, as long as it's not his last one
+	Delete(userID Snowflake, provider string) error
+}
@@ -0,0 +1,13 @@
+package repositories
+
+import . "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+
+// Repository with basic operations for UserSettings table
+type UserSettingsRepository interface {
+	InitRepo() error
+	// returns the settings of the user or the default settings, if there is no record
+	Get(userID Snowflake) (*UserSettings, error)
+	Save(settings *UserSettings) (*UserSettings, error)
+	GetVisibility(ownerID Snowflake, resource Resource) (Visibility, error)
+	GetVisibilities(ownerIDs []Snowflake, resource Resource) (map[Snowflake]Visibility, error)
+}
@@ -0,0 +1,180 @@
+// Package apierror contains the error type every error response of the API is rendered from.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// Code is a stable, machine-readable error code. Clients should use it instead of the message.
+type Code string
+
+const (
+	CodeBadRequest      Code = "bad_request"
+	CodeInvalidBody     Code = "invalid_body"
+	CodeUnauthorized    Code = "unauthorized"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeGone            Code = "gone"
+	CodeTooManyRequests Code = "too_many_requests"
+	CodeInternal        Code = "internal_error"
+)
+
+// InternalMessage is the message of every 5xx response. The cause is only logged, since it can
+// contain internals like SQL statements or addresses of other services
+const InternalMessage = "internal error"
+
+// Error is an error with everything needed to render the error response
+type Error struct {
+	Status  int
+	Code    Code
+	Message string
+	// additional information like the invalid fields of a request
+	Details any
+	// the error which caused this error
+	Err error
+}
+
+// Reply is the JSON body of every error response
+type Reply struct {
+	// human readable message. Named error for compatibility with older clients
+	Error   string `json:"error" example:"sport not found"`
+	Code    Code   `json:"code" example:"not_found"`
+	Details any    `json:"details,omitempty"`
+}
+
+// FieldError describes a field of a request body, which failed validation
+type FieldError struct {
+	Field string `json:"field" example:"Amount"`
+	// the failed validation rule like required or gte
+	Rule string `json:"rule" example:"required"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Reply returns the JSON body of the error. Server errors get `InternalMessage` without details
+func (e *Error) Reply() Reply {
+	if e.Status >= http.StatusInternalServerError {
+		return Reply{Error: InternalMessage, Code: e.Code}
+	}
+	return Reply{Error: e.Message, Code: e.Code, Details: e.Details}
+}
+
+// WithDetails returns a copy of the error with <details>
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithStatus returns <err> as Error with <status>. Errors which already are an Error are returned as is.
+func WithStatus(status int, err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return &Error{Status: status, Code: codeForStatus(status), Message: err.Error(), Err: err}
+}
+
+// From returns <err> as Error. The status is derived from the sentinel errors of the
+// repositories and falls back to 500.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	switch {
+	case errors.Is(err, repositories.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return WithStatus(http.StatusNotFound, err)
+	case errors.Is(err, repositories.ErrForbidden):
+		return WithStatus(http.StatusForbidden, err)
+	case errors.Is(err, repositories.ErrConflict):
+		return WithStatus(http.StatusConflict, err)
+	case errors.Is(err, repositories.ErrGone):
+		return WithStatus(http.StatusGone, err)
+	default:
+		return WithStatus(http.StatusInternalServerError, err)
+	}
+}
+
+func BadRequest(format string, args ...any) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, fmt.Sprintf(format, args...))
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return New(http.StatusForbidden, CodeForbidden, fmt.Sprintf(format, args...))
+}
+
+func NotFound(format string, args ...any) *Error {
+	return New(http.StatusNotFound, CodeNotFound, fmt.Sprintf(format, args...))
+}
+
+// InvalidBody returns the error of a request body, which could not be parsed or validated.
+// The fields which failed validation are listed in the details.
+func InvalidBody(err error) *Error {
+	apiErr := &Error{
+		Status:  http.StatusBadRequest,
+		Code:    CodeInvalidBody,
+		Message: fmt.Sprintf("invalid JSON format: %v", err),
+		Err:     err,
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldErr.Field(), Rule: fieldErr.Tag()})
+		}
+		apiErr.Message = "invalid request body"
+		apiErr.Details = fields
+	}
+	return apiErr
+}
+
+// Abort adds <err> to the errors of the request and stops the handler chain.
+// The response is rendered by the error handler middleware.
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+func codeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	default:
+		return CodeInternal
+	}
+}
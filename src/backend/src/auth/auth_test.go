@@ -0,0 +1,261 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newFakeOAuthServer serves a token endpoint, the Discord /users/@me endpoint and an
+// OpenID Connect discovery document with a userinfo endpoint.
+func newFakeOAuthServer(t *testing.T, discordUser models.JsUser, userInfo oidcUserInfo) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	authorized := func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer test-access-token"
+	}
+
+	token := func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != "valid-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, map[string]any{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}
+	mux.HandleFunc("/oauth2/token", token)
+	mux.HandleFunc("/oidc/token", token)
+
+	mux.HandleFunc("/users/@me", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, discordUser)
+	})
+
+	mux.HandleFunc("/oidc/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, oidcDiscovery{
+			Issuer:                server.URL + "/oidc",
+			AuthorizationEndpoint: server.URL + "/oidc/authorize",
+			TokenEndpoint:         server.URL + "/oidc/token",
+			UserinfoEndpoint:      server.URL + "/oidc/userinfo",
+		})
+	})
+	mux.HandleFunc("/oidc/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, userInfo)
+	})
+	return server
+}
+
+// returns the cipher of the OAuth tokens in tests
+func newTestTokenCipher(t *testing.T) *db.TokenCipher {
+	t.Helper()
+	tokens, err := db.NewTokenCipher("test secret")
+	if err != nil {
+		t.Fatalf("failed to create token cipher: %v", err)
+	}
+	return tokens
+}
+
+// newTestService builds a service with in-memory repositories
+func newTestService(t *testing.T, providers ...Provider) *Service {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	return NewService(
+		providers,
+		db.NewGormUserRepository(database),
+		db.NewGormUserIdentityRepository(database, newTestTokenCipher(t)),
+		func() time.Time { return now },
+	)
+}
+
+var (
+	testDiscordUser = models.JsUser{ID: "123456789", Username: "kurama", Discriminator: "0", Avatar: "abc", Email: "kurama@example.com"}
+	testUserInfo    = oidcUserInfo{Subject: "google-42", PreferredUsername: "kurama.g", Email: "kurama@example.com", Picture: "https://example.com/p.png"}
+)
+
+// TestDiscordProviderExchange verifies the token exchange and the lookup of the Discord account
+func TestDiscordProviderExchange(t *testing.T) {
+	server := newFakeOAuthServer(t, testDiscordUser, testUserInfo)
+	provider := NewDiscordProvider("client", "secret", "http://localhost/callback", server.URL)
+
+	identity, err := provider.Exchange(context.Background(), "valid-code")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Provider != DiscordProviderName || identity.Subject != "123456789" {
+		t.Errorf("got identity %s/%s, want discord/123456789", identity.Provider, identity.Subject)
+	}
+	if identity.PreferredUserID != 123456789 {
+		t.Errorf("got preferred user ID %d, want the Discord ID", identity.PreferredUserID)
+	}
+	if identity.Username != "kurama" {
+		t.Errorf("got username %q, want kurama", identity.Username)
+	}
+
+	if _, err := provider.Exchange(context.Background(), "invalid-code"); err == nil {
+		t.Errorf("expected an error for an invalid code")
+	}
+}
+
+// TestOIDCProviderExchange verifies discovery, token exchange and the userinfo lookup
+func TestOIDCProviderExchange(t *testing.T) {
+	server := newFakeOAuthServer(t, testDiscordUser, testUserInfo)
+	provider, err := NewOIDCProvider(context.Background(), "google", server.URL+"/oidc", "client", "secret", "http://localhost/callback")
+	if err != nil {
+		t.Fatalf("failed to discover provider: %v", err)
+	}
+	if provider.Name() != "google" {
+		t.Errorf("got name %q, want google", provider.Name())
+	}
+
+	identity, err := provider.Exchange(context.Background(), "valid-code")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.Subject != "google-42" || identity.Username != "kurama.g" {
+		t.Errorf("got identity %s (%s), want google-42 (kurama.g)", identity.Subject, identity.Username)
+	}
+	if identity.PreferredUserID != 0 {
+		t.Errorf("OIDC identities must not prefer a user ID, got %d", identity.PreferredUserID)
+	}
+
+	if _, err := NewOIDCProvider(context.Background(), "google", server.URL+"/other", "client", "secret", ""); err == nil {
+		t.Errorf("expected an error for an issuer without discovery document")
+	}
+}
+
+// TestServiceLogin verifies user creation on the first login and linking of further identities
+func TestServiceLogin(t *testing.T) {
+	service := newTestService(t)
+	discord := &Identity{Provider: DiscordProviderName, Subject: "123", Username: "kurama", PreferredUserID: 123}
+	google := &Identity{Provider: "google", Subject: "g-1", Username: "kurama.g"}
+
+	user, err := service.Login(discord, nil)
+	if err != nil {
+		t.Fatalf("first login failed: %v", err)
+	}
+	if user.ID != 123 || user.Username != "kurama" {
+		t.Errorf("got user %d (%s), want 123 (kurama)", user.ID, user.Username)
+	}
+
+	// logging in again returns the same user
+	again, err := service.Login(discord, nil)
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("second login returned %v, %v; want user %d", again, err, user.ID)
+	}
+
+	// linking google while logged in must not change the profile
+	linked, err := service.Login(google, user)
+	if err != nil {
+		t.Fatalf("linking failed: %v", err)
+	}
+	if linked.ID != user.ID || linked.Username != "kurama" {
+		t.Errorf("got user %d (%s) after linking, want %d (kurama)", linked.ID, linked.Username, user.ID)
+	}
+
+	// google now logs in the same user
+	viaGoogle, err := service.Login(google, nil)
+	if err != nil || viaGoogle.ID != user.ID {
+		t.Fatalf("google login returned %v, %v; want user %d", viaGoogle, err, user.ID)
+	}
+
+	identities, err := service.Identities.FetchByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch identities: %v", err)
+	}
+	if len(identities) != 2 {
+		t.Errorf("got %d identities, want 2", len(identities))
+	}
+
+	// another user can't link an account which is already linked
+	other, err := service.Login(&Identity{Provider: "github", Subject: "gh-1", Username: "other"}, nil)
+	if err != nil {
+		t.Fatalf("login of other user failed: %v", err)
+	}
+	if other.ID == 0 || other.ID == user.ID {
+		t.Errorf("expected a new generated ID, got %d", other.ID)
+	}
+	if _, err := service.Login(google, other); !errors.Is(err, ErrIdentityLinkedToOtherUser) {
+		t.Errorf("got %v, want ErrIdentityLinkedToOtherUser", err)
+	}
+}
+
+// TestUnlinkLastIdentity verifies that a user always keeps one login method
+func TestUnlinkLastIdentity(t *testing.T) {
+	service := newTestService(t)
+	user, err := service.Login(&Identity{Provider: DiscordProviderName, Subject: "1", Username: "a", PreferredUserID: 1}, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := service.Login(&Identity{Provider: "google", Subject: "g", Username: "a"}, user); err != nil {
+		t.Fatalf("linking failed: %v", err)
+	}
+
+	if err := service.Identities.Delete(user.ID, "google"); err != nil {
+		t.Fatalf("unlinking failed: %v", err)
+	}
+	if err := service.Identities.Delete(user.ID, DiscordProviderName); !errors.Is(err, db.ErrLastIdentity) {
+		t.Errorf("got %v, want ErrLastIdentity", err)
+	}
+}
+
+// TestServiceLoginGeneratedIDCollision verifies that a generated ID never links an identity to
+// an existing user
+func TestServiceLoginGeneratedIDCollision(t *testing.T) {
+	service := newTestService(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service.IDs = models.NewSnowflakeGenerator(0, func() time.Time { return now })
+
+	// the first ID the generator returns belongs to a user already
+	taken := models.NewSnowflakeGenerator(0, func() time.Time { return now }).Next()
+	if err := service.Users.CreateUser(&models.User{ID: taken, Username: "stranger"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	user, err := service.Login(&Identity{Provider: "google", Subject: "g-1", Username: "newcomer"}, nil)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if user.ID == taken || user.Username != "newcomer" {
+		t.Errorf("got user %d (%s), want a new user instead of %d", user.ID, user.Username, taken)
+	}
+	stranger, err := service.Users.GetUserByID(taken)
+	if err != nil || stranger.Username != "stranger" {
+		t.Errorf("got user %v (%v), want the existing user unchanged", stranger, err)
+	}
+	identities, err := service.Identities.FetchByUserID(taken)
+	if err != nil || len(identities) != 0 {
+		t.Errorf("got %d identities (%v) of the existing user, want none", len(identities), err)
+	}
+}
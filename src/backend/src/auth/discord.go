@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"golang.org/x/oauth2"
+)
+
+const (
+	DiscordProviderName  = "discord"
+	DefaultDiscordAPIURL = "https://discord.com/api"
+)
+
+// DiscordProvider logs users in with their Discord account. Users registered with Discord
+// keep their Discord ID as internal user ID.
+type DiscordProvider struct {
+	OAuthConfig *oauth2.Config
+	// base URL of the Discord API, changed in tests
+	APIURL string
+}
+
+func NewDiscordProvider(clientID string, clientSecret string, redirectURL string, apiURL string) *DiscordProvider {
+	if apiURL == "" {
+		apiURL = DefaultDiscordAPIURL
+	}
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	return &DiscordProvider{
+		OAuthConfig: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"identify", "email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  apiURL + "/oauth2/authorize",
+				TokenURL: apiURL + "/oauth2/token",
+			},
+		},
+		APIURL: apiURL,
+	}
+}
+
+func (p *DiscordProvider) Name() string {
+	return DiscordProviderName
+}
+
+func (p *DiscordProvider) AuthCodeURL(state string) string {
+	return p.OAuthConfig.AuthCodeURL(state)
+}
+
+func (p *DiscordProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.OAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+	}
+	return p.FetchIdentity(ctx, token)
+}
+
+// FetchIdentity returns the Discord account the token belongs to
+func (p *DiscordProvider) FetchIdentity(ctx context.Context, token *oauth2.Token) (*Identity, error) {
+	var d_user models.JsUser
+	if err := fetchJSON(p.OAuthConfig.Client(ctx, token), p.APIURL+"/users/@me", &d_user); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	user, err := d_user.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("user ID was not parsable to int: %w", err)
+	}
+
+	return &Identity{
+		Provider:        DiscordProviderName,
+		Subject:         d_user.ID,
+		Username:        user.Username,
+		Discriminator:   user.Discriminator,
+		Email:           user.Email,
+		Avatar:          user.Avatar,
+		PreferredUserID: user.ID,
+		Token:           token,
+	}, nil
+}
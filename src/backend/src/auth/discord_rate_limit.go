@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// retries of a request, which was answered with 429
+	defaultRateLimitRetries = 3
+	// wait time, when Discord answers with 429 but without retry information
+	defaultRetryAfter = time.Second
+)
+
+// DiscordRateLimiter is a http.RoundTripper respecting the rate limits of the Discord API.
+// When a bucket is exhausted (X-RateLimit-Remaining: 0), further requests wait until it resets.
+// Requests answered with 429 are retried after the time Discord asks for.
+type DiscordRateLimiter struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+	// waits for <d> or until <ctx> is done
+	// used for DI and tests
+	Sleep func(ctx context.Context, d time.Duration) error
+
+	mu           sync.Mutex
+	blockedUntil time.Time
+}
+
+func NewDiscordRateLimiter(base http.RoundTripper, Now func() time.Time) *DiscordRateLimiter {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &DiscordRateLimiter{
+		Base:       base,
+		MaxRetries: defaultRateLimitRetries,
+		Now:        Now,
+		Sleep:      sleepContext,
+	}
+}
+
+func (l *DiscordRateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := l.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := l.Base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		l.update(resp)
+
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= l.MaxRetries {
+			return resp, nil
+		}
+		// the body was already sent and can't be sent again
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		retryAfter := parseRetryAfter(resp)
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		l.block(retryAfter)
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// waits until the rate limit is reset
+func (l *DiscordRateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	delay := l.blockedUntil.Sub(l.Now())
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	return l.Sleep(ctx, delay)
+}
+
+// blocks all requests for <d>
+func (l *DiscordRateLimiter) block(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until := l.Now().Add(d)
+	if until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// blocks further requests, when the bucket of <resp> is exhausted
+func (l *DiscordRateLimiter) update(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	resetAfter, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset-After"), 64)
+	if err != nil {
+		return
+	}
+	l.block(secondsToDuration(resetAfter))
+}
+
+// returns how long to wait after a 429 response. Discord sends the seconds in the
+// Retry-After header and with millisecond precision as retry_after in the body
+func parseRetryAfter(resp *http.Response) time.Duration {
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body); err == nil && body.RetryAfter > 0 {
+		return secondsToDuration(body.RetryAfter)
+	}
+	for _, header := range []string{"Retry-After", "X-RateLimit-Reset-After"} {
+		if seconds, err := strconv.ParseFloat(resp.Header.Get(header), 64); err == nil && seconds > 0 {
+			return secondsToDuration(seconds)
+		}
+	}
+	return defaultRetryAfter
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
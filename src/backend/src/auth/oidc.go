@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// the fields of the OpenID Connect discovery document, which are needed to log in
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// the standard claims returned by the userinfo endpoint
+type oidcUserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	Picture           string `json:"picture"`
+}
+
+// OIDCProvider logs users in with any OpenID Connect issuer like Google, GitLab or Authentik.
+// The account information is read from the userinfo endpoint with the access token, which
+// was obtained directly from the token endpoint of the issuer.
+type OIDCProvider struct {
+	name        string
+	OAuthConfig *oauth2.Config
+	UserInfoURL string
+}
+
+// NewOIDCProvider reads the discovery document of <issuerURL> and builds a provider from it
+func NewOIDCProvider(
+	ctx context.Context,
+	name string,
+	issuerURL string,
+	clientID string,
+	clientSecret string,
+	redirectURL string,
+) (*OIDCProvider, error) {
+	issuerURL = strings.TrimSuffix(issuerURL, "/")
+
+	var discovery oidcDiscovery
+	if err := fetchJSON(oauth2.NewClient(ctx, nil), issuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID configuration of %s: %w", issuerURL, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuerURL {
+		return nil, fmt.Errorf("issuer %q of discovery document does not match %q", discovery.Issuer, issuerURL)
+	}
+	if discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("issuer %s has no userinfo endpoint", issuerURL)
+	}
+
+	return &OIDCProvider{
+		name: name,
+		OAuthConfig: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"openid", "profile", "email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  discovery.AuthorizationEndpoint,
+				TokenURL: discovery.TokenEndpoint,
+			},
+		},
+		UserInfoURL: discovery.UserinfoEndpoint,
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+func (p *OIDCProvider) AuthCodeURL(state string) string {
+	return p.OAuthConfig.AuthCodeURL(state)
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string) (*Identity, error) {
+	token, err := p.OAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
+	}
+
+	var info oidcUserInfo
+	if err := fetchJSON(p.OAuthConfig.Client(ctx, token), p.UserInfoURL, &info); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("userinfo of %s contains no subject", p.name)
+	}
+
+	username := info.PreferredUsername
+	if username == "" {
+		username = info.Name
+	}
+	return &Identity{
+		Provider: p.name,
+		Subject:  info.Subject,
+		Username: username,
+		Email:    info.Email,
+		Avatar:   info.Picture,
+		Token:    token,
+	}, nil
+}
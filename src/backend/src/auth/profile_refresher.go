@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultRefreshActiveWithin = 7 * 24 * time.Hour
+	defaultRefreshMaxAge       = 24 * time.Hour
+	defaultRefreshBatchSize    = 50
+)
+
+// ErrRefreshTokenRevoked is returned, when Discord rejects the stored refresh token,
+// e.g. because the user removed the app. The tokens of the identity are dropped.
+var ErrRefreshTokenRevoked = errors.New("the refresh token was revoked")
+
+// ProfileRefresher re-fetches the Discord profiles (username, avatar, ...) of active users
+// with their stored refresh token. Without it, profiles are only updated on login.
+type ProfileRefresher struct {
+	Service  *Service
+	Provider *DiscordProvider
+	// HTTP client used for all requests to Discord. Rate limited by default
+	Client *http.Client
+	// only users with a session used within this duration are refreshed
+	ActiveWithin time.Duration
+	// profiles are refreshed at most once within this duration
+	MaxAge time.Duration
+	// maximum number of profiles refreshed per run
+	BatchSize int
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewProfileRefresher(service *Service, provider *DiscordProvider, Now func() time.Time) *ProfileRefresher {
+	return &ProfileRefresher{
+		Service:      service,
+		Provider:     provider,
+		Client:       &http.Client{Transport: NewDiscordRateLimiter(nil, Now), Timeout: 30 * time.Second},
+		ActiveWithin: defaultRefreshActiveWithin,
+		MaxAge:       defaultRefreshMaxAge,
+		BatchSize:    defaultRefreshBatchSize,
+		Now:          Now,
+	}
+}
+
+// RefreshAll refreshes the profiles, which are due. Failing profiles are logged and skipped.
+// Returns the number of refreshed profiles
+func (r *ProfileRefresher) RefreshAll(ctx context.Context) (int, error) {
+	now := r.Now().UTC()
+	identities, err := r.Service.Identities.FetchRefreshable(
+		r.Provider.Name(),
+		now.Add(-r.ActiveWithin),
+		now.Add(-r.MaxAge),
+		r.BatchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch identities to refresh: %w", err)
+	}
+
+	refreshed := 0
+	for i := range identities {
+		if err := ctx.Err(); err != nil {
+			return refreshed, err
+		}
+		if err := r.Refresh(ctx, &identities[i]); err != nil {
+			log.Printf("Failed to refresh Discord profile of user %d: %v", identities[i].UserID, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Refresh renews the token of <identity> if needed, fetches the Discord profile and
+// updates the identity and the user
+func (r *ProfileRefresher) Refresh(ctx context.Context, identity *models.UserIdentity) error {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, r.Client)
+
+	token := &oauth2.Token{
+		AccessToken:  identity.AccessToken,
+		RefreshToken: identity.RefreshToken,
+		TokenType:    "Bearer",
+	}
+	if identity.TokenExpiresAt != nil {
+		token.Expiry = *identity.TokenExpiresAt
+	}
+	// refreshes the access token, when it expired
+	token, err := r.Provider.OAuthConfig.TokenSource(ctx, token).Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+			return r.dropTokens(identity)
+		}
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	fetched, err := r.Provider.FetchIdentity(ctx, token)
+	if err != nil {
+		return err
+	}
+	if fetched.Subject != identity.Subject {
+		return fmt.Errorf("token belongs to Discord account %s instead of %s", fetched.Subject, identity.Subject)
+	}
+	_, err = r.Service.refreshLinked(identity, fetched)
+	return err
+}
+
+// removes the tokens of <identity>, so that it's not refreshed anymore until the next login
+func (r *ProfileRefresher) dropTokens(identity *models.UserIdentity) error {
+	identity.AccessToken = ""
+	identity.RefreshToken = ""
+	identity.TokenExpiresAt = nil
+	if _, err := r.Service.Identities.Update(identity); err != nil {
+		return err
+	}
+	return ErrRefreshTokenRevoked
+}
@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"golang.org/x/oauth2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeClock is a clock, which only advances when something sleeps
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+// newFakeDiscordAPI serves the token endpoint for refresh tokens and /users/@me.
+// The first profile request is answered with 429
+func newFakeDiscordAPI(t *testing.T, profiles map[string]models.JsUser) (*httptest.Server, *int) {
+	t.Helper()
+
+	profileRequests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" {
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+		if r.Form.Get("refresh_token") != "valid-refresh" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "fresh-access",
+			"refresh_token": "rotated-refresh",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	})
+	mux.HandleFunc("/users/@me", func(w http.ResponseWriter, r *http.Request) {
+		profileRequests++
+		w.Header().Set("Content-Type", "application/json")
+		if profileRequests == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.5,"global":false}`))
+			return
+		}
+		profile, ok := profiles[r.Header.Get("Authorization")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(profile)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &profileRequests
+}
+
+// TestProfileRefresher verifies that only active users are refreshed, that rate limits are
+// respected and that revoked refresh tokens are dropped
+func TestProfileRefresher(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	server, profileRequests := newFakeDiscordAPI(t, map[string]models.JsUser{
+		"Bearer fresh-access": {ID: "1", Username: "kurama-renamed", Discriminator: "0", Avatar: "a_animated"},
+	})
+	provider := NewDiscordProvider("client", "secret", "http://localhost/callback", server.URL)
+	service := NewService(
+		[]Provider{provider},
+		db.NewGormUserRepository(database),
+		db.NewGormUserIdentityRepository(database, newTestTokenCipher(t)),
+		clock.Now,
+	)
+	sessions := db.NewGormSessionRepository(database)
+
+	expired := clock.now.Add(-time.Hour)
+	for _, identity := range []*Identity{
+		{Subject: "1", Username: "kurama", PreferredUserID: 1},
+		{Subject: "2", Username: "revoked", PreferredUserID: 2},
+		{Subject: "3", Username: "inactive", PreferredUserID: 3},
+	} {
+		identity.Provider = DiscordProviderName
+		identity.Token = &oauth2.Token{AccessToken: "old-access", RefreshToken: "valid-refresh", Expiry: expired}
+		if identity.Subject == "2" {
+			identity.Token.RefreshToken = "revoked-refresh"
+		}
+		if _, err := service.Login(identity, nil); err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+	}
+
+	// profiles are refreshed at most once a day
+	clock.now = clock.now.Add(25 * time.Hour)
+	for _, userID := range []models.Snowflake{1, 2} {
+		err := sessions.Save(&models.Session{
+			ID:         fmt.Sprintf("session-%d", userID),
+			PublicID:   fmt.Sprintf("public-%d", userID),
+			UserID:     userID,
+			LastSeenAt: clock.now.Add(-time.Hour),
+			ExpiresAt:  clock.now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to save session: %v", err)
+		}
+	}
+
+	limiter := NewDiscordRateLimiter(nil, clock.Now)
+	limiter.Sleep = clock.Sleep
+	refresher := NewProfileRefresher(service, provider, clock.Now)
+	refresher.Client = &http.Client{Transport: limiter}
+
+	refreshed, err := refresher.RefreshAll(context.Background())
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if refreshed != 1 {
+		t.Errorf("got %d refreshed profiles, want 1", refreshed)
+	}
+	if *profileRequests != 2 {
+		t.Errorf("got %d profile requests, want 2 (one rate limited)", *profileRequests)
+	}
+	if len(clock.sleeps) != 1 || clock.sleeps[0] != 500*time.Millisecond {
+		t.Errorf("got sleeps %v, want [500ms]", clock.sleeps)
+	}
+
+	user, err := service.Users.GetUserByID(1)
+	if err != nil {
+		t.Fatalf("failed to fetch user: %v", err)
+	}
+	if user.Username != "kurama-renamed" || user.Avatar != "a_animated" {
+		t.Errorf("got profile %s/%s, want kurama-renamed/a_animated", user.Username, user.Avatar)
+	}
+
+	identities := map[string]models.UserIdentity{}
+	for _, userID := range []models.Snowflake{1, 2, 3} {
+		linked, err := service.Identities.FetchByUserID(userID)
+		if err != nil || len(linked) != 1 {
+			t.Fatalf("failed to fetch identity of %d: %v", userID, err)
+		}
+		identities[linked[0].Subject] = linked[0]
+	}
+	if got := identities["1"]; got.AccessToken != "fresh-access" || got.RefreshToken != "rotated-refresh" {
+		t.Errorf("got tokens %s/%s, want the refreshed tokens", got.AccessToken, got.RefreshToken)
+	}
+	if got := identities["1"]; got.RefreshedAt == nil || !got.RefreshedAt.Equal(clock.now) {
+		t.Errorf("got refreshed at %v, want %v", got.RefreshedAt, clock.now)
+	}
+	if got := identities["2"]; got.RefreshToken != "" || got.AccessToken != "" {
+		t.Errorf("revoked tokens were not dropped")
+	}
+	if got := identities["3"]; got.RefreshToken != "valid-refresh" || got.Username != "inactive" {
+		t.Errorf("inactive user must not be refreshed")
+	}
+
+	// nothing is due right after a run
+	refreshed, err = refresher.RefreshAll(context.Background())
+	if err != nil || refreshed != 0 {
+		t.Errorf("second run refreshed %d profiles (%v), want 0", refreshed, err)
+	}
+}
+
+// TestDiscordRateLimiter verifies waiting for exhausted buckets and retrying 429 responses
+func TestDiscordRateLimiter(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			// bucket exhausted, resets in 2 seconds
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset-After", "2")
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewDiscordRateLimiter(nil, clock.Now)
+	limiter.Sleep = clock.Sleep
+	client := &http.Client{Transport: limiter}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("got status %d, want 200", resp.StatusCode)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+	want := []time.Duration{2 * time.Second, time.Second}
+	if len(clock.sleeps) != len(want) || clock.sleeps[0] != want[0] || clock.sleeps[1] != want[1] {
+		t.Errorf("got sleeps %v, want %v", clock.sleeps, want)
+	}
+}
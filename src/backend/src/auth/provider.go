@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"golang.org/x/oauth2"
+)
+
+// Identity is the account information a provider returns after a successful login
+type Identity struct {
+	// name of the provider, e.g. discord
+	Provider string
+	// unique and stable ID of the account at the provider
+	Subject       string
+	Username      string
+	Discriminator string
+	Email         string
+	Avatar        string
+	// ID the internal user should get, when he registers with this identity.
+	// 0 means a new ID is generated
+	PreferredUserID models.Snowflake
+	Token           *oauth2.Token
+}
+
+// Provider is an OAuth2 based login provider like Discord or any OpenID Connect issuer
+type Provider interface {
+	// name used in the routes /api/auth/<name> and /api/auth/<name>/callback
+	Name() string
+	// URL the user is redirected to, to log in at the provider
+	AuthCodeURL(state string) string
+	// exchanges the code from the callback for a token and fetches the account information
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+// fetches <url> with <client> and decodes the JSON body into <target>
+func fetchJSON(client *http.Client, url string, target any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s returned %d: %s", url, resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdentityLinkedToOtherUser = errors.New("this account is already linked to another user")
+	ErrUserIDCollision           = errors.New("failed to generate an unused user ID")
+)
+
+// how often a generated user ID is replaced, when a user with it exists already
+const maxIDAttempts = 5
+
+// Service resolves the identities returned by the providers to internal users.
+// It creates users on the first login and links further identities to existing users.
+type Service struct {
+	Providers  map[string]Provider
+	Users      db.UserRepository
+	Identities repositories.UserIdentityRepository
+	// generates IDs for users, whose identity has no preferred ID
+	IDs *models.SnowflakeGenerator
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewService(
+	providers []Provider,
+	users db.UserRepository,
+	identities repositories.UserIdentityRepository,
+	Now func() time.Time,
+) *Service {
+	byName := make(map[string]Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &Service{
+		Providers:  byName,
+		Users:      users,
+		Identities: identities,
+		IDs:        models.NewSnowflakeGenerator(0, Now),
+		Now:        Now,
+	}
+}
+
+// Provider returns the provider registered with <name>
+func (s *Service) Provider(name string) (Provider, bool) {
+	provider, ok := s.Providers[name]
+	return provider, ok
+}
+
+// Login returns the internal user of <identity>.
+//
+// If <currentUser> is given (the user is already logged in), the identity is linked to him.
+// Otherwise the user the identity is linked to is returned, or a new user is created.
+// The profile (username, avatar, ...) of the user is updated with the identity of his
+// first provider.
+func (s *Service) Login(identity *Identity, currentUser *models.User) (*models.User, error) {
+	linked, err := s.Identities.FetchByProviderSubject(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if linked != nil {
+		if currentUser != nil && currentUser.ID != linked.UserID {
+			return nil, ErrIdentityLinkedToOtherUser
+		}
+		return s.refreshLinked(linked, identity)
+	}
+
+	if currentUser != nil {
+		// link an additional login method to the logged in user
+		if err := s.link(currentUser.ID, identity); err != nil {
+			return nil, err
+		}
+		return s.Users.GetUserByID(currentUser.ID)
+	}
+
+	// first login with this identity
+	if identity.PreferredUserID == 0 {
+		userID, err := s.newUserID()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Users.CreateUser(userFromIdentity(userID, identity)); err != nil {
+			return nil, err
+		}
+		if err := s.link(userID, identity); err != nil {
+			return nil, err
+		}
+		return s.updateProfile(userID, identity)
+	}
+
+	// the ID came from the provider, so a user with this ID is the same person. Users created
+	// before identities existed are linked here
+	userID := identity.PreferredUserID
+	existing, err := s.Users.GetUserByID(userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if existing == nil {
+		if err := s.Users.CreateUser(userFromIdentity(userID, identity)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.link(userID, identity); err != nil {
+		return nil, err
+	}
+	return s.updateProfile(userID, identity)
+}
+
+// returns a generated ID, which no user has yet. Generated IDs never adopt an existing
+// account, since that would link the identity to a stranger
+func (s *Service) newUserID() (models.Snowflake, error) {
+	for range maxIDAttempts {
+		userID := s.IDs.Next()
+		existing, err := s.Users.GetUserByID(userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && existing == nil) {
+			return userID, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return 0, ErrUserIDCollision
+}
+
+// links <identity> to the user with <userID>
+func (s *Service) link(userID models.Snowflake, identity *Identity) error {
+	linked := &models.UserIdentity{
+		UserID:    userID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Username:  identity.Username,
+		CreatedAt: s.Now().UTC(),
+	}
+	s.storeToken(linked, identity)
+	_, err := s.Identities.Create(linked)
+	if err != nil {
+		return fmt.Errorf("failed to link %s account: %w", identity.Provider, err)
+	}
+	return nil
+}
+
+// updates the <linked> identity and the profile of its user with the freshly fetched <identity>
+func (s *Service) refreshLinked(linked *models.UserIdentity, identity *Identity) (*models.User, error) {
+	linked.Username = identity.Username
+	s.storeToken(linked, identity)
+	if _, err := s.Identities.Update(linked); err != nil {
+		return nil, err
+	}
+	return s.updateProfile(linked.UserID, identity)
+}
+
+// stores the OAuth token of <identity> in <linked>, so that the profile can be refreshed later.
+// Tokens without refresh token keep the previous refresh token
+func (s *Service) storeToken(linked *models.UserIdentity, identity *Identity) {
+	now := s.Now().UTC()
+	linked.RefreshedAt = &now
+	if identity.Token == nil {
+		return
+	}
+	linked.AccessToken = identity.Token.AccessToken
+	if identity.Token.RefreshToken != "" {
+		linked.RefreshToken = identity.Token.RefreshToken
+	}
+	linked.TokenExpiresAt = nil
+	if !identity.Token.Expiry.IsZero() {
+		expiry := identity.Token.Expiry.UTC()
+		linked.TokenExpiresAt = &expiry
+	}
+}
+
+// updates the profile of the user with <identity>, if it's the identity he registered with
+func (s *Service) updateProfile(userID models.Snowflake, identity *Identity) (*models.User, error) {
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := s.Identities.FetchByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	isPrimary := len(identities) > 0 &&
+		identities[0].Provider == identity.Provider &&
+		identities[0].Subject == identity.Subject
+	if !isPrimary {
+		return user, nil
+	}
+
+	updated := userFromIdentity(userID, identity)
+	if err := s.Users.UpdateUser(updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func userFromIdentity(userID models.Snowflake, identity *Identity) *models.User {
+	return &models.User{
+		ID:            userID,
+		Username:      identity.Username,
+		Discriminator: identity.Discriminator,
+		Avatar:        identity.Avatar,
+		Email:         identity.Email,
+	}
+}
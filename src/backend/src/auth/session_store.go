@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+)
+
+// the last usage of a session is written at most once per interval, to avoid a write on every request
+const sessionTouchInterval = time.Minute
+
+// SessionStore is a server-side implementation of the gin `sessions.Store`. The cookie only
+// contains the signed session ID, while the values are stored in the SessionRepository.
+// This allows listing and revoking sessions, which is not possible with cookie sessions.
+type SessionStore struct {
+	Repo   repositories.SessionRepository
+	Codecs []securecookie.Codec
+	// sessions which were not used for this duration expire
+	IdleTimeout time.Duration
+	// sessions expire after this duration, even when they are used
+	AbsoluteTimeout time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+
+	options *gsessions.Options
+}
+
+// NewSessionStore creates a store, which signs the session IDs in the cookie with <keyPairs>
+func NewSessionStore(
+	repo repositories.SessionRepository,
+	idleTimeout time.Duration,
+	absoluteTimeout time.Duration,
+	Now func() time.Time,
+	keyPairs ...[]byte,
+) *SessionStore {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(int(absoluteTimeout.Seconds()))
+		}
+	}
+	return &SessionStore{
+		Repo:            repo,
+		Codecs:          codecs,
+		IdleTimeout:     idleTimeout,
+		AbsoluteTimeout: absoluteTimeout,
+		Now:             Now,
+		options: &gsessions.Options{
+			Path:     "/",
+			MaxAge:   int(absoluteTimeout.Seconds()),
+			HttpOnly: true,
+		},
+	}
+}
+
+func (s *SessionStore) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+}
+
+// Get returns the session of the request, which is cached for the duration of the request
+func (s *SessionStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session referenced by the cookie of the request. Missing, invalid or expired
+// sessions result in a new, empty session.
+func (s *SessionStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	options := *s.options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	record, err := s.Repo.Fetch(id)
+	if err != nil {
+		return session, err
+	}
+	now := s.Now().UTC()
+	if record == nil || !record.IsActive(now, s.IdleTimeout) {
+		return session, nil
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(record.Data, &session.Values); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+
+	if now.Sub(record.LastSeenAt) >= sessionTouchInterval {
+		if err := s.Repo.Touch(id, now); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Save stores the values of the session and sets the cookie. A negative MaxAge deletes the session.
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.Repo.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	now := s.Now().UTC()
+	userID := sessionUserID(session)
+
+	var record *models.Session
+	if session.ID != "" {
+		var err error
+		if record, err = s.Repo.Fetch(session.ID); err != nil {
+			return err
+		}
+	}
+	// a new ID is used when the user logs in or out, so that a session ID planted
+	// before the login can't be used to take over the session
+	if record != nil && record.UserID != userID {
+		if err := s.Repo.Delete(record.ID); err != nil {
+			return err
+		}
+		record = nil
+	}
+	if record == nil {
+		id, err := randomHex(32)
+		if err != nil {
+			return err
+		}
+		publicID, err := randomHex(8)
+		if err != nil {
+			return err
+		}
+		record = &models.Session{
+			ID:        id,
+			PublicID:  publicID,
+			CreatedAt: now,
+			ExpiresAt: now.Add(s.AbsoluteTimeout),
+		}
+		session.ID = id
+	}
+
+	data, err := (securecookie.GobEncoder{}).Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	record.UserID = userID
+	record.Data = data
+	record.UserAgent = r.UserAgent()
+	record.IP = clientIP(r)
+	record.LastSeenAt = now
+	if err := s.Repo.Save(record); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	// the cookie lives as long as the session
+	options := *session.Options
+	if options.MaxAge > 0 {
+		options.MaxAge = int(record.ExpiresAt.Sub(now).Seconds())
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, &options))
+	return nil
+}
+
+// DeleteExpired removes all sessions, which expired or were idle for too long
+func (s *SessionStore) DeleteExpired() (int64, error) {
+	now := s.Now().UTC()
+	idleSince := time.Time{}
+	if s.IdleTimeout > 0 {
+		idleSince = now.Add(-s.IdleTimeout)
+	}
+	return s.Repo.DeleteExpired(now, idleSince)
+}
+
+// returns the ID of the user logged in with <session> or 0
+func sessionUserID(session *gsessions.Session) models.Snowflake {
+	if user, ok := session.Values[middleware.UserKey].(models.User); ok {
+		return user.ID
+	}
+	return 0
+}
+
+// returns the IP of the client. The headers of a reverse proxy are preferred, which is only
+// used to show the sessions to their owner
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func randomHex(bytes int) (string, error) {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"encoding/gob"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gob.Register(models.User{})
+}
+
+// newTestSessionRouter builds a router with routes to set values, log in, read the user and log out
+func newTestSessionRouter(store *SessionStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(sessions.Sessions("test_session", store))
+	r.GET("/state", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("state", "abc")
+		session.Save()
+	})
+	r.GET("/login", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Set("user", models.User{ID: 1, Username: "kurama"})
+		session.Save()
+	})
+	r.GET("/user", func(c *gin.Context) {
+		user, ok := sessions.Default(c).Get("user").(models.User)
+		if !ok {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.String(http.StatusOK, user.Username)
+	})
+	r.GET("/logout", func(c *gin.Context) {
+		session := sessions.Default(c)
+		session.Clear()
+		session.Options(sessions.Options{Path: "/", MaxAge: -1})
+		session.Save()
+	})
+	return r
+}
+
+// sends a GET request with <cookie> and returns the response
+func getWithCookie(r *gin.Engine, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", "test-agent")
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "test_session" {
+			return cookie
+		}
+	}
+	t.Fatalf("response has no session cookie")
+	return nil
+}
+
+func TestSessionStoreLoginAndLogout(t *testing.T) {
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo := db.NewMemorySessionRepository()
+	store := NewSessionStore(repo, time.Hour, 24*time.Hour, func() time.Time { return now }, []byte("secret"))
+	r := newTestSessionRouter(store)
+
+	anonymous := sessionCookie(t, getWithCookie(r, "/state", nil))
+	loggedIn := sessionCookie(t, getWithCookie(r, "/login", anonymous))
+	if loggedIn.Value == anonymous.Value {
+		t.Errorf("the session ID must change on login")
+	}
+	if w := getWithCookie(r, "/user", anonymous); w.Code != http.StatusUnauthorized {
+		t.Errorf("the session before the login must not be logged in, got %d", w.Code)
+	}
+	if w := getWithCookie(r, "/user", loggedIn); w.Code != http.StatusOK || w.Body.String() != "kurama" {
+		t.Errorf("got %d %q, want 200 kurama", w.Code, w.Body.String())
+	}
+
+	userSessions, _ := repo.FetchByUserID(1)
+	if len(userSessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(userSessions))
+	}
+	if userSessions[0].UserAgent != "test-agent" {
+		t.Errorf("got user agent %q, want test-agent", userSessions[0].UserAgent)
+	}
+
+	// a stolen cookie is useless after the logout
+	getWithCookie(r, "/logout", loggedIn)
+	if w := getWithCookie(r, "/user", loggedIn); w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d after logout, want 401", w.Code)
+	}
+	if userSessions, _ := repo.FetchByUserID(1); len(userSessions) != 0 {
+		t.Errorf("got %d sessions after logout, want 0", len(userSessions))
+	}
+}
+
+func TestSessionStoreExpiry(t *testing.T) {
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	repo := db.NewMemorySessionRepository()
+	store := NewSessionStore(repo, time.Hour, 3*time.Hour, func() time.Time { return now }, []byte("secret"))
+	r := newTestSessionRouter(store)
+
+	cookie := sessionCookie(t, getWithCookie(r, "/login", nil))
+
+	var tests = []struct {
+		name    string
+		elapsed time.Duration
+		want    int
+	}{
+		{"Used within the idle timeout", 50 * time.Minute, http.StatusOK},
+		{"Idle timeout is extended by usage", 100 * time.Minute, http.StatusOK},
+		{"Used again within the idle timeout", 150 * time.Minute, http.StatusOK},
+		{"Absolute timeout ends active sessions", 3 * time.Hour, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = start.Add(tt.elapsed)
+			if w := getWithCookie(r, "/user", cookie); w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	// idle sessions expire
+	now = start
+	idle := sessionCookie(t, getWithCookie(r, "/login", nil))
+	now = start.Add(61 * time.Minute)
+	if w := getWithCookie(r, "/user", idle); w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d for an idle session, want 401", w.Code)
+	}
+
+	deleted, err := store.DeleteExpired()
+	if err != nil {
+		t.Fatalf("failed to delete expired sessions: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted %d sessions, want 1", deleted)
+	}
+}
+
+func TestSessionStoreRejectsForgedCookie(t *testing.T) {
+	repo := db.NewMemorySessionRepository()
+	store := NewSessionStore(repo, time.Hour, 24*time.Hour, time.Now, []byte("secret"))
+	other := NewSessionStore(repo, time.Hour, 24*time.Hour, time.Now, []byte("other-secret"))
+
+	cookie := sessionCookie(t, getWithCookie(newTestSessionRouter(other), "/login", nil))
+	if w := getWithCookie(newTestSessionRouter(store), "/user", cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d for a cookie signed with another key, want 401", w.Code)
+	}
+}
@@ -0,0 +1,152 @@
+package calendar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// number of past days, whose check-ins are part of the feed
+const checkInDays = 60
+
+// Feed builds the calendar of a user from his goals and exercises
+type Feed struct {
+	Goals       repositories.PersonalGoalsRepository
+	Sports      db.SportRepository
+	FrontendURL string
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewFeed(
+	goals repositories.PersonalGoalsRepository,
+	sports db.SportRepository,
+	frontendURL string,
+	Now func() time.Time,
+) *Feed {
+	return &Feed{Goals: goals, Sports: sports, FrontendURL: strings.TrimRight(frontendURL, "/"), Now: Now}
+}
+
+// Build returns the calendar of <userID> with the deadlines of his weekly and monthly goals,
+// the days he checked in on and a reminder, when his streak ends today
+func (f *Feed) Build(userID models.Snowflake) (*Calendar, error) {
+	now := f.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	goals, err := f.Goals.FetchByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// the monthly window is the longest one and starts at the latest on the first check-in day
+	from := today.AddDate(0, 0, -checkInDays+1)
+	monthly := models.PersonalGoal{Frequency: models.Monthly}
+	if monthStart, _ := monthly.Window(now); monthStart.Before(from) {
+		from = monthStart
+	}
+	totals, err := f.Sports.GetDailyTotals(userID, from, today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{Name: "GoToHell", Events: f.goalDeadlines(goals, totals)}
+	calendar.Events = append(calendar.Events, f.checkIns(userID, totals, today.AddDate(0, 0, -checkInDays+1))...)
+
+	checkedInToday := slices.ContainsFunc(totals, func(total models.SportDayTotal) bool {
+		return total.Date == today.Format(time.DateOnly)
+	})
+	if !checkedInToday {
+		streak, err := f.Sports.GetCurrentStreak(userID)
+		if err != nil {
+			return nil, err
+		}
+		if streak.Days > 0 {
+			calendar.Events = append(calendar.Events, Event{
+				UID:         fmt.Sprintf("streak-%s-%d@gotohell", today.Format("20060102"), userID),
+				Day:         today,
+				Summary:     fmt.Sprintf("Check in to keep your %d day streak", streak.Days),
+				Description: "Your streak ends at midnight UTC, unless you do some exercises today.",
+				URL:         f.FrontendURL,
+			})
+		}
+	}
+	return calendar, nil
+}
+
+// returns an event on the last day of the current window of every weekly and monthly goal
+func (f *Feed) goalDeadlines(goals []models.PersonalGoal, totals []models.SportDayTotal) []Event {
+	events := []Event{}
+	for _, goal := range goals {
+		if goal.Frequency != models.Weekly && goal.Frequency != models.Monthly {
+			continue
+		}
+		start, end := goal.Window(f.Now())
+		done := 0
+		for _, total := range totals {
+			if total.Kind == goal.Sport && total.Date >= start.Format(time.DateOnly) {
+				done += total.Amount
+			}
+		}
+
+		summary := fmt.Sprintf("Deadline of your %s %s goal", goal.Frequency, goal.Sport)
+		description := fmt.Sprintf("%d of %d %s done, %d left.", done, goal.Amount, goal.Sport, goal.Amount-done)
+		if done >= goal.Amount {
+			summary = fmt.Sprintf("Reached your %s %s goal", goal.Frequency, goal.Sport)
+			description = fmt.Sprintf("%d of %d %s done.", done, goal.Amount, goal.Sport)
+		}
+		events = append(events, Event{
+			UID:         fmt.Sprintf("goal-%d-%s@gotohell", goal.ID, start.Format("20060102")),
+			Day:         end.AddDate(0, 0, -1),
+			Summary:     summary,
+			Description: description,
+			URL:         f.FrontendURL,
+		})
+	}
+	return events
+}
+
+// returns an event for every day since <from>, on which the user did exercises, with the
+// amounts of every sport
+func (f *Feed) checkIns(userID models.Snowflake, totals []models.SportDayTotal, from time.Time) []Event {
+	// totals are ordered by date and sport, but split by game
+	days := []string{}
+	amounts := map[string]map[string]int{}
+	for _, total := range totals {
+		if total.Date < from.Format(time.DateOnly) {
+			continue
+		}
+		if _, ok := amounts[total.Date]; !ok {
+			days = append(days, total.Date)
+			amounts[total.Date] = map[string]int{}
+		}
+		amounts[total.Date][total.Kind] += total.Amount
+	}
+
+	events := make([]Event, 0, len(days))
+	for _, date := range days {
+		day, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			continue
+		}
+		kinds := make([]string, 0, len(amounts[date]))
+		for kind := range amounts[date] {
+			kinds = append(kinds, kind)
+		}
+		slices.Sort(kinds)
+		lines := make([]string, 0, len(kinds))
+		for _, kind := range kinds {
+			lines = append(lines, fmt.Sprintf("%d %s", amounts[date][kind], kind))
+		}
+		events = append(events, Event{
+			UID:     fmt.Sprintf("checkin-%s-%d@gotohell", day.Format("20060102"), userID),
+			Day:     day,
+			Summary: "Checked in: " + strings.Join(lines, ", "),
+		})
+	}
+	return events
+}
@@ -0,0 +1,164 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// unfold joins folded lines and returns the content lines of <feed>
+func unfold(feed string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(feed, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+// events returns the properties of every event of <feed> by UID
+func events(feed string) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	var current map[string]string
+	for _, line := range unfold(feed) {
+		switch {
+		case line == "BEGIN:VEVENT":
+			current = map[string]string{}
+		case line == "END:VEVENT":
+			result[current["UID"]] = current
+			current = nil
+		case current != nil:
+			name, value, _ := strings.Cut(line, ":")
+			current[name] = value
+		}
+	}
+	return result
+}
+
+func TestFeedBuild(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.User{}, &models.Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// wednesday, the week started on the 1st of may
+	now := time.Date(2023, 5, 3, 10, 0, 0, 0, time.UTC)
+	Now := func() time.Time { return now }
+	sports := &db.OrmSportRepository{DB: database, StreakService: db.NewStreakService(Now)}
+	goals := db.NewPersonalGoalsRepository(database)
+
+	for _, goal := range []models.PersonalGoal{
+		{ID: 1, UserID: 1, Amount: 10, Frequency: models.Daily, Sport: "pushup"},
+		{ID: 2, UserID: 1, Amount: 50, Frequency: models.Weekly, Sport: "squat"},
+		{ID: 3, UserID: 1, Amount: 100, Frequency: models.Monthly, Sport: "pushup"},
+		{ID: 4, UserID: 2, Amount: 10, Frequency: models.Weekly, Sport: "pushup"},
+	} {
+		if _, err := goals.Insert(&goal); err != nil {
+			t.Fatalf("failed to insert goal: %v", err)
+		}
+	}
+	for _, sport := range []models.Sport{
+		// a check-in, but before the month of the monthly goal
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 40, Timedate: time.Date(2023, 4, 30, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "squat", Game: "league", Amount: 60, Timedate: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: time.Date(2023, 5, 2, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "pushup", Game: "overwatch", Amount: 5, Timedate: time.Date(2023, 5, 2, 18, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "squat", Game: "overwatch", Amount: 20, Timedate: time.Date(2023, 5, 2, 19, 0, 0, 0, time.UTC)},
+		{UserID: 2, Kind: "pushup", Game: "league", Amount: 10, Timedate: time.Date(2023, 5, 2, 12, 0, 0, 0, time.UTC)},
+	} {
+		if err := sports.InsertSport(sport); err != nil {
+			t.Fatalf("failed to insert sport: %v", err)
+		}
+	}
+
+	feed := NewFeed(goals, sports, "https://example.com/", Now)
+	calendar, err := feed.Build(1)
+	if err != nil {
+		t.Fatalf("failed to build feed: %v", err)
+	}
+	encoded := string(calendar.Bytes(now))
+	got := events(encoded)
+
+	var tests = []struct {
+		name    string
+		uid     string
+		day     string
+		summary string
+	}{
+		{"Weekly goal ends on sunday", "goal-2-20230501@gotohell", "20230507", "Reached your weekly squat goal"},
+		{"Monthly goal ends on the last day of the month", "goal-3-20230501@gotohell", "20230531", "Deadline of your monthly pushup goal"},
+		{"Check-in before the month", "checkin-20230430-1@gotohell", "20230430", `Checked in: 40 pushup`},
+		{"Check-in of the weekly goal", "checkin-20230501-1@gotohell", "20230501", "Checked in: 60 squat"},
+		{"Check-in sums the games", "checkin-20230502-1@gotohell", "20230502", `Checked in: 15 pushup\, 20 squat`},
+		{"Streak reminder without check-in today", "streak-20230503-1@gotohell", "20230503", "Check in to keep your 3 day streak"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := got[tt.uid]
+			if !ok {
+				t.Fatalf("got no event %s in feed\n%s", tt.uid, encoded)
+			}
+			if event["DTSTART;VALUE=DATE"] != tt.day || event["SUMMARY"] != tt.summary {
+				t.Errorf("got event on %s with summary %q, want %s and %q", event["DTSTART;VALUE=DATE"], event["SUMMARY"], tt.day, tt.summary)
+			}
+		})
+	}
+	if len(got) != len(tests) {
+		t.Errorf("got %d events, want %d without the daily goal and the data of other users", len(got), len(tests))
+	}
+	if description := got["goal-3-20230501@gotohell"]["DESCRIPTION"]; description != `15 of 100 pushup done\, 85 left.` {
+		t.Errorf("got description %q of the monthly goal", description)
+	}
+
+	// no reminder is needed after checking in today
+	if err := sports.InsertSport(models.Sport{UserID: 1, Kind: "pushup", Game: "league", Amount: 5, Timedate: now}); err != nil {
+		t.Fatalf("failed to insert sport: %v", err)
+	}
+	calendar, err = feed.Build(1)
+	if err != nil {
+		t.Fatalf("failed to build feed: %v", err)
+	}
+	got = events(string(calendar.Bytes(now)))
+	if _, ok := got["streak-20230503-1@gotohell"]; ok {
+		t.Errorf("got a streak reminder after checking in today")
+	}
+	if _, ok := got["checkin-20230503-1@gotohell"]; !ok {
+		t.Errorf("got no check-in for today")
+	}
+}
+
+// TestCalendarBytes verifies that text is escaped and long lines are folded without splitting characters.
+func TestCalendarBytes(t *testing.T) {
+	calendar := Calendar{Name: "GoToHell", Events: []Event{{
+		UID:         "1@gotohell",
+		Day:         time.Date(2023, 5, 3, 23, 0, 0, 0, time.UTC),
+		Summary:     "Liegestütze; Kniebeugen, Klimmzüge",
+		Description: strings.Repeat("Übung ", 30) + "\nback\\slash",
+	}}}
+	encoded := string(calendar.Bytes(time.Date(2023, 5, 3, 8, 0, 0, 0, time.UTC)))
+
+	if !strings.HasSuffix(encoded, "END:VCALENDAR\r\n") {
+		t.Errorf("got feed without CRLF line endings: %q", encoded)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(encoded, "\r\n"), "\r\n") {
+		if len(line) > maxLineLength || !utf8.ValidString(line) {
+			t.Errorf("got line with %d octets or split characters: %q", len(line), line)
+		}
+	}
+	event := events(encoded)["1@gotohell"]
+	want := map[string]string{
+		"DTSTAMP":            "20230503T080000Z",
+		"DTSTART;VALUE=DATE": "20230503",
+		"DTEND;VALUE=DATE":   "20230504",
+		"SUMMARY":            `Liegestütze\; Kniebeugen\, Klimmzüge`,
+		"DESCRIPTION":        strings.Repeat("Übung ", 30) + `\nback\\slash`,
+	}
+	for name, value := range want {
+		if event[name] != value {
+			t.Errorf("got %s %q, want %q", name, event[name], value)
+		}
+	}
+}
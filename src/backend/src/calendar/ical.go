@@ -0,0 +1,97 @@
+// Package calendar builds the private iCalendar feeds of users, which calendar apps subscribe to
+package calendar
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	productID = "-//GoToHell//Calendar Feed//EN"
+	// lines longer than 75 octets are folded (RFC 5545 section 3.1)
+	maxLineLength = 75
+	// how often calendar apps should fetch the feed
+	refreshInterval = "PT1H"
+)
+
+// Event is an all-day event of a calendar
+type Event struct {
+	// stays the same when the feed is fetched again, so that apps update the event
+	UID string
+	// the day of the event, only the UTC date is used
+	Day         time.Time
+	Summary     string
+	Description string
+	URL         string
+}
+
+// Calendar is an iCalendar feed (RFC 5545)
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Bytes encodes the calendar. <now> is the timestamp of the events
+func (c *Calendar) Bytes(now time.Time) []byte {
+	var b strings.Builder
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+productID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	writeLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:"+refreshInterval)
+	writeLine(&b, "X-PUBLISHED-TTL:"+refreshInterval)
+	for _, event := range c.Events {
+		day := event.Day.UTC()
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+stamp)
+		writeLine(&b, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+		writeLine(&b, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.URL != "" {
+			writeLine(&b, "URL:"+event.URL)
+		}
+		// all-day events shouldn't block the time of the user
+		writeLine(&b, "TRANSP:TRANSPARENT")
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// escapes the characters, which have a meaning in TEXT values
+func escapeText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// writes <line> terminated with CRLF and folds it, without splitting UTF-8 characters
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of continuation lines counts to their length
+		limit = maxLineLength - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
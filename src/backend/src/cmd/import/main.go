@@ -0,0 +1,107 @@
+// Command import imports historical workouts from a CSV or JSON file into the database.
+//
+//	go run ./cmd/import -user 348922315062044675 -file sheet.csv -dry-run
+//
+// The file needs the columns date, sport, amount and game. Sport names, which are not known,
+// are mapped through the aliases of config/sport_aliases.csv and the optional -aliases file.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/importer"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dbPath := flag.String("db", "./db/go-to-hell.db", "path of the SQLite database")
+	userID := flag.Uint64("user", 0, "ID of the user, the workouts belong to")
+	filePath := flag.String("file", "", "CSV or JSON file to import")
+	formatName := flag.String("format", "", "csv or json, defaults to the file extension")
+	aliasesPath := flag.String("aliases", "", "optional CSV file with additional aliases (alias,sport)")
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	flag.Parse()
+
+	if *userID == 0 || *filePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *formatName == "" {
+		*formatName = filepath.Ext(*filePath)
+	}
+	format, err := importer.ParseFormat(*formatName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	database, err := gorm.Open(sqlite.Open(*dbPath), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	if err := database.AutoMigrate(&models.Sport{}); err != nil {
+		log.Fatalf("failed to migrate: %v", err)
+	}
+	sportImporter, err := importer.New(&db.OrmSportRepository{DB: database, StreakService: db.NewStreakService(time.Now)})
+	if err != nil {
+		log.Fatalf("failed to create importer: %v", err)
+	}
+
+	if *aliasesPath != "" {
+		if err := addAliases(sportImporter, *aliasesPath); err != nil {
+			log.Fatalf("failed to read aliases: %v", err)
+		}
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	report, err := sportImporter.Import(models.Snowflake(*userID), file, format, *dryRun)
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+	printReport(report)
+}
+
+// adds the aliases of the CSV file <path> to <sportImporter>
+func addAliases(sportImporter *importer.Importer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return err
+	}
+	return sportImporter.AddAliases(records)
+}
+
+func printReport(report *importer.Report) {
+	for _, rowErr := range report.Errors {
+		fmt.Println(rowErr.Error())
+	}
+	for alias, sport := range report.Aliases {
+		fmt.Printf("mapped %q to %s\n", alias, sport)
+	}
+	if report.DryRun {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report.Sports)
+		fmt.Print("dry run, nothing was imported. ")
+	}
+	fmt.Printf("%d rows: %d imported, %d duplicates, %d invalid\n",
+		report.Total, report.Imported, report.Duplicates, report.Invalid)
+}
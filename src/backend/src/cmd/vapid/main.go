@@ -0,0 +1,23 @@
+// Command vapid generates the VAPID key pair, which signs push notifications.
+//
+//	go run ./cmd/vapid
+//
+// The private key is configured as VAPID_PRIVATE_KEY. Changing it invalidates all push
+// subscriptions, since browsers only accept messages signed with the key they subscribed with.
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/webpush"
+)
+
+func main() {
+	keys, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatalf("failed to generate VAPID keys: %v", err)
+	}
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", keys.PrivateKey())
+	fmt.Printf("# public key, served at /api/push/public-key: %s\n", keys.PublicKey)
+}
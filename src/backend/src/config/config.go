@@ -1,18 +1,117 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
-	"golang.org/x/oauth2"
 )
 
 // Config holds application configuration
 type Config struct {
-	DiscordOAuthConfig *oauth2.Config
-	SessionSecret      string
-	FrontendURL        string
+	Discord       DiscordConfig
+	Riot          RiotConfig
+	Email         EmailConfig
+	WebPush       WebPushConfig
+	OIDCProviders []OIDCProviderConfig
+	SessionSecret string
+	// encrypts the OAuth tokens of the login providers in the database, defaults to a key
+	// derived from the session secret
+	TokenEncryptionKey string
+	// sessions expire, when they were not used for this duration
+	SessionIdleTimeout time.Duration
+	// sessions expire after this duration, even when they are used
+	SessionMaxAge time.Duration
+	// accounts are deleted after this duration, in which the deletion can be cancelled
+	AccountDeletionGracePeriod time.Duration
+	// how often the live death counter is saved, so that it survives restarts
+	DeathCounterPersistInterval time.Duration
+	// how often the live death counter is added to the overdue deaths
+	DeathCounterFlushInterval time.Duration
+	FrontendURL               string
+	// URL under which users reach the backend, e.g. for unsubscribe links in emails
+	BackendURL string
+	// IPs or CIDRs of the reverse proxies, whose X-Forwarded-For header is trusted.
+	// Without them, the client IP is the remote address of the connection
+	TrustedProxies []string
+}
+
+// DiscordConfig holds the OAuth credentials of the Discord application
+type DiscordConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// base URL of the Discord API, empty for the default
+	APIURL string
+	// how often the profiles of active users are fetched from Discord
+	ProfileRefreshInterval time.Duration
+	// hex encoded public key of the application, which verifies the interactions of the bot.
+	// The slash commands are disabled without it
+	PublicKey string
+	// token of the bot user, which registers the commands and sends reminders
+	BotToken string
+	// UTC hour, after which users are reminded of streaks, which end at midnight
+	ReminderHour int
+}
+
+// RiotConfig holds the API key of the Riot connector. The connector is disabled without a key
+type RiotConfig struct {
+	APIKey string
+	// regional routing URL of the Riot API, empty for europe
+	APIURL string
+	// how often the matches of linked accounts are fetched
+	PollInterval time.Duration
+}
+
+// EmailConfig holds the SMTP server, which sends notification emails. Without an SMTP host,
+// emails are written as files into <Directory>. Emails are disabled, when neither is set
+type EmailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// sender address, e.g. GoToHell <noreply@example.com>
+	From      string
+	Directory string
+	// signs the unsubscribe links, defaults to a key derived from the session secret
+	UnsubscribeSecret string
+}
+
+// Enabled returns whether emails are sent or written to files
+func (c EmailConfig) Enabled() bool {
+	return c.SMTPHost != "" || c.Directory != ""
+}
+
+// WebPushConfig holds the VAPID key pair, which signs push messages to browsers.
+// Push notifications are disabled without a private key
+type WebPushConfig struct {
+	// base64url encoded P-256 private key, generated with `go run ./cmd/vapid`
+	VAPIDPrivateKey string
+	// contact for operators of push services, e.g. mailto:admin@example.com
+	Subject string
+}
+
+// Enabled returns whether push messages are sent
+func (c WebPushConfig) Enabled() bool {
+	return c.VAPIDPrivateKey != ""
+}
+
+// OIDCProviderConfig holds the credentials of an additional OpenID Connect login provider.
+// Configured with OIDC_PROVIDERS=<name>,... and OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and optionally OIDC_<NAME>_REDIRECT_URI
+type OIDCProviderConfig struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
 }
 
 var AppConfig *Config
@@ -46,32 +145,190 @@ func Load() *Config {
 		frontendURL = "http://localhost:5173"
 	}
 
-	discordOAuthConfig := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  redirectURL,
-		Scopes:       []string{"identify", "email"},
-		Endpoint: oauth2.Endpoint{
-			AuthURL:  "https://discord.com/api/oauth2/authorize",
-			TokenURL: "https://discord.com/api/oauth2/token",
-		},
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:8080"
+	}
+
+	sessionIdleTimeout := durationFromEnv("SESSION_IDLE_TIMEOUT", 14*24*time.Hour)
+	sessionMaxAge := durationFromEnv("SESSION_MAX_AGE", 90*24*time.Hour)
+	accountDeletionGracePeriod := durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", 7*24*time.Hour)
+	profileRefreshInterval := durationFromEnv("DISCORD_PROFILE_REFRESH_INTERVAL", time.Hour)
+	deathCounterPersistInterval := durationFromEnv("DEATH_COUNTER_PERSIST_INTERVAL", 5*time.Second)
+	deathCounterFlushInterval := durationFromEnv("DEATH_COUNTER_FLUSH_INTERVAL", 5*time.Minute)
+	riotPollInterval := durationFromEnv("RIOT_POLL_INTERVAL", 10*time.Minute)
+	reminderHour := 20
+	if value := os.Getenv("DISCORD_REMINDER_HOUR"); value != "" {
+		hour, err := strconv.Atoi(value)
+		if err != nil || hour < 0 || hour > 23 {
+			log.Fatalf("DISCORD_REMINDER_HOUR is not an hour between 0 and 23: %s", value)
+		}
+		reminderHour = hour
+	}
+	smtpPort := 587
+	if value := os.Getenv("SMTP_PORT"); value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			log.Fatalf("SMTP_PORT is not a valid port: %s", value)
+		}
+		smtpPort = port
+	}
+	emailFrom := os.Getenv("EMAIL_FROM")
+	if emailFrom == "" {
+		emailFrom = "GoToHell <noreply@localhost>"
+	}
+	vapidSubject := os.Getenv("VAPID_SUBJECT")
+	if vapidSubject == "" {
+		vapidSubject = frontendURL
+	}
+	unsubscribeSecret := os.Getenv("EMAIL_UNSUBSCRIBE_SECRET")
+	if unsubscribeSecret == "" {
+		unsubscribeSecret = deriveSecret(sessionSecret, "email unsubscribe links")
+	}
+
+	tokenEncryptionKey := os.Getenv("TOKEN_ENCRYPTION_KEY")
+	if tokenEncryptionKey == "" {
+		tokenEncryptionKey = deriveSecret(sessionSecret, "OAuth token encryption")
 	}
 
 	AppConfig = &Config{
-		DiscordOAuthConfig: discordOAuthConfig,
+		Discord: DiscordConfig{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			APIURL:       os.Getenv("DISCORD_API_URL"),
+
+			ProfileRefreshInterval: profileRefreshInterval,
+			PublicKey:              os.Getenv("DISCORD_PUBLIC_KEY"),
+			BotToken:               os.Getenv("DISCORD_BOT_TOKEN"),
+			ReminderHour:           reminderHour,
+		},
+		Riot: RiotConfig{
+			APIKey:       os.Getenv("RIOT_API_KEY"),
+			APIURL:       os.Getenv("RIOT_API_URL"),
+			PollInterval: riotPollInterval,
+		},
+		Email: EmailConfig{
+			SMTPHost:          os.Getenv("SMTP_HOST"),
+			SMTPPort:          smtpPort,
+			SMTPUsername:      os.Getenv("SMTP_USERNAME"),
+			SMTPPassword:      os.Getenv("SMTP_PASSWORD"),
+			From:              emailFrom,
+			Directory:         os.Getenv("EMAIL_DIRECTORY"),
+			UnsubscribeSecret: unsubscribeSecret,
+		},
+		WebPush: WebPushConfig{
+			VAPIDPrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+			Subject:         vapidSubject,
+		},
+		OIDCProviders:      loadOIDCProviders(),
 		SessionSecret:      sessionSecret,
-		FrontendURL:        frontendURL,
+		TokenEncryptionKey: tokenEncryptionKey,
+		SessionIdleTimeout: sessionIdleTimeout,
+		SessionMaxAge:      sessionMaxAge,
+
+		AccountDeletionGracePeriod:  accountDeletionGracePeriod,
+		DeathCounterPersistInterval: deathCounterPersistInterval,
+		DeathCounterFlushInterval:   deathCounterFlushInterval,
+		FrontendURL:                 frontendURL,
+		BackendURL:                  backendURL,
+		TrustedProxies:              loadTrustedProxies(),
 	}
 	PrintConfig(AppConfig)
 	return AppConfig
 }
 
+// reads a duration like 336h from <key> or returns <fallback>, when it's not set
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("%s is not a valid duration: %s", key, value)
+	}
+	return duration
+}
+
+// derives a key for <purpose> from <secret>, so that one configured secret can be used for
+// several purposes without reusing the same key
+func deriveSecret(secret string, purpose string) string {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, "GoToHell "+purpose, 32)
+	if err != nil {
+		log.Fatalf("failed to derive the key for %s: %v", purpose, err)
+	}
+	return hex.EncodeToString(key)
+}
+
+// reads the OpenID Connect providers listed in OIDC_PROVIDERS
+// reads the comma separated TRUSTED_PROXIES. Returns nil, when none are set, so that no proxy is trusted
+func loadTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func loadOIDCProviders() []OIDCProviderConfig {
+	providers := []OIDCProviderConfig{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URI"),
+		}
+		if provider.IssuerURL == "" || provider.ClientID == "" || provider.ClientSecret == "" {
+			log.Fatalf("%sISSUER, %sCLIENT_ID and %sCLIENT_SECRET are required for OIDC provider %s", prefix, prefix, prefix, name)
+		}
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = fmt.Sprintf("http://localhost:8080/api/auth/%s/callback", name)
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
 // PrintConfig logs some key configuration values.
 func PrintConfig(cfg *Config) {
 	log.Println("Discord OAuth Config:")
-	log.Println("  ClientID:      ", cfg.DiscordOAuthConfig.ClientID) // Consider masking in production
-	log.Println("  RedirectURL:   ", cfg.DiscordOAuthConfig.RedirectURL)
-	log.Println("  Scopes:        ", cfg.DiscordOAuthConfig.Scopes)
+	log.Println("  ClientID:      ", cfg.Discord.ClientID) // Consider masking in production
+	log.Println("  RedirectURL:   ", cfg.Discord.RedirectURL)
+	log.Println("  Profile refresh:", cfg.Discord.ProfileRefreshInterval)
+	log.Println("  Bot commands enabled:", cfg.Discord.PublicKey != "")
+	log.Println("  Bot reminders enabled:", cfg.Discord.BotToken != "")
+	log.Println("Riot connector enabled:", cfg.Riot.APIKey != "")
+	switch {
+	case cfg.Email.SMTPHost != "":
+		log.Printf("Emails are sent with %s:%d", cfg.Email.SMTPHost, cfg.Email.SMTPPort)
+	case cfg.Email.Directory != "":
+		log.Println("Emails are written to", cfg.Email.Directory)
+	default:
+		log.Println("Emails are disabled")
+	}
+	log.Println("Push notifications enabled:", cfg.WebPush.Enabled())
+	for _, provider := range cfg.OIDCProviders {
+		log.Printf("OIDC Provider %s:", provider.Name)
+		log.Println("  Issuer:        ", provider.IssuerURL)
+		log.Println("  RedirectURL:   ", provider.RedirectURL)
+	}
 	// Avoid printing sensitive values: clientSecret and sessionSecret.
 	log.Println("Frontend URL:     ", cfg.FrontendURL)
+	log.Println("Backend URL:      ", cfg.BackendURL)
+	log.Println("Trusted proxies:  ", cfg.TrustedProxies)
+	log.Println("Session idle timeout:", cfg.SessionIdleTimeout)
+	log.Println("Session max age:     ", cfg.SessionMaxAge)
+	log.Println("Account deletion grace period:", cfg.AccountDeletionGracePeriod)
+	log.Println("Death counter persist interval:", cfg.DeathCounterPersistInterval)
+	log.Println("Death counter flush interval:  ", cfg.DeathCounterFlushInterval)
 }
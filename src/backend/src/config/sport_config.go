@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"encoding/csv"
 	"log"
+	"strconv"
 	"strings"
 )
 
@@ -17,6 +18,39 @@ var defaultGamesBytes []byte
 
 var DefaultGamesCsv [][]string
 
+// names of sports used in old spreadsheets, mapped to the name of the sport
+//
+//go:embed sport_aliases.csv
+var sportAliasesBytes []byte
+
+var SportAliasesCsv [][]string
+
+// DefaultSportMultipliers returns the base multiplier of every default sport
+func DefaultSportMultipliers() map[string]float64 {
+	return multipliersFromCsv(DefaultSportsCsv)
+}
+
+// DefaultGameMultipliers returns the multiplier of every default game
+func DefaultGameMultipliers() map[string]float64 {
+	return multipliersFromCsv(DefaultGamesCsv)
+}
+
+// returns the multipliers of a CSV with a header, the name and the multiplier
+func multipliersFromCsv(records [][]string) map[string]float64 {
+	multipliers := make(map[string]float64, len(records))
+	for i, record := range records {
+		if i == 0 || len(record) < 2 {
+			continue
+		}
+		multiplier, err := strconv.ParseFloat(record[1], 64)
+		if err != nil || multiplier <= 0 {
+			multiplier = 1
+		}
+		multipliers[record[0]] = multiplier
+	}
+	return multipliers
+}
+
 func init() {
 	reader := csv.NewReader(strings.NewReader(string(defaultSportsBytes)))
 	reader.Comma = ','
@@ -33,4 +67,12 @@ func init() {
 		log.Fatalf("feiled to parse default_games.csv: %v", err)
 	}
 	DefaultGamesCsv = records
+
+	reader = csv.NewReader(strings.NewReader(string(sportAliasesBytes)))
+	reader.Comma = ','
+	records, err = reader.ReadAll()
+	if err != nil {
+		log.Fatalf("failed to parse sport_aliases.csv: %v", err)
+	}
+	SportAliasesCsv = records
 }
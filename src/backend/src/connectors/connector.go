@@ -0,0 +1,52 @@
+// Package connectors fetches the recent matches of linked game accounts from game-stats APIs
+// and turns their deaths into overdue deaths.
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+)
+
+// ErrAccountNotFound is returned, when the connector doesn't know the account entered by the user
+var ErrAccountNotFound = repositories.NotFound("game account not found")
+
+// RateLimitError is returned, when the API of <Connector> rejects requests for <RetryAfter>
+type RateLimitError struct {
+	Connector  string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by %s, retry after %s", e.Connector, e.RetryAfter)
+}
+
+// Account is an account at a game-stats API
+type Account struct {
+	// stable ID of the account, e.g. the Riot PUUID
+	ExternalID string
+	// name shown to the user, e.g. the Riot ID
+	DisplayName string
+}
+
+// Match is a match played with a linked account
+type Match struct {
+	ID string
+	// game of `config.DefaultGamesCsv`, e.g. league
+	Game    string
+	Deaths  int64
+	EndedAt time.Time
+}
+
+// Connector fetches the matches of accounts from a game-stats API
+type Connector interface {
+	// unique name of the connector, e.g. riot
+	Name() string
+	// resolves the account <name> entered by the user, e.g. a Riot ID like Faker#KR1
+	ResolveAccount(ctx context.Context, name string) (*Account, error)
+	// returns the matches of the account <externalID>, which started after <since>.
+	// Matches with an ID in <known> were processed before and are skipped without fetching them
+	RecentMatches(ctx context.Context, externalID string, since time.Time, known map[string]bool) ([]Match, error)
+}
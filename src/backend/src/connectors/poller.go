@@ -0,0 +1,112 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+const (
+	defaultPollInterval  = 10 * time.Minute
+	defaultPollBatchSize = 50
+	// matches are fetched again for this duration, since they appear with a delay in the APIs.
+	// Processed matches are skipped by their ID
+	defaultPollLookback = 24 * time.Hour
+)
+
+// Poller fetches the matches of linked game accounts and adds their deaths to the overdue deaths
+type Poller struct {
+	Repo       repositories.GameAccountRepository
+	Connectors map[string]Connector
+	// accounts are polled at most once within this duration
+	Interval time.Duration
+	// maximum number of accounts polled per connector and run
+	BatchSize int
+	Lookback  time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewPoller(repo repositories.GameAccountRepository, connectors []Connector, Now func() time.Time) *Poller {
+	poller := &Poller{
+		Repo:       repo,
+		Connectors: map[string]Connector{},
+		Interval:   defaultPollInterval,
+		BatchSize:  defaultPollBatchSize,
+		Lookback:   defaultPollLookback,
+		Now:        Now,
+	}
+	for _, connector := range connectors {
+		poller.Connectors[connector.Name()] = connector
+	}
+	return poller
+}
+
+// PollAll polls the accounts of every connector, which are due. Failing accounts are logged and
+// skipped. Returns the number of new matches
+func (p *Poller) PollAll(ctx context.Context) (int, error) {
+	processed := 0
+	for name, connector := range p.Connectors {
+		accounts, err := p.Repo.FetchDue(name, p.Now().UTC().Add(-p.Interval), p.BatchSize)
+		if err != nil {
+			return processed, fmt.Errorf("failed to fetch %s accounts to poll: %w", name, err)
+		}
+		for i := range accounts {
+			if err := ctx.Err(); err != nil {
+				return processed, err
+			}
+			matches, err := p.Poll(ctx, connector, &accounts[i])
+			var rateLimit *RateLimitError
+			if errors.As(err, &rateLimit) {
+				// the other accounts are polled in the next run
+				log.Printf("Stopped polling %s accounts: %v", name, err)
+				break
+			}
+			if err != nil {
+				log.Printf("Failed to poll %s account of user %d: %v", name, accounts[i].UserID, err)
+				continue
+			}
+			processed += len(matches)
+		}
+	}
+	return processed, nil
+}
+
+// Poll fetches the recent matches of <account> and adds the deaths of new matches to the overdue deaths.
+// Returns the new matches
+func (p *Poller) Poll(ctx context.Context, connector Connector, account *models.GameAccount) ([]models.ProcessedMatch, error) {
+	now := p.Now().UTC()
+	since := account.CreatedAt
+	if account.LastPolledAt != nil && account.LastPolledAt.Add(-p.Lookback).After(since) {
+		since = account.LastPolledAt.Add(-p.Lookback)
+	}
+
+	known, err := p.Repo.FetchProcessedMatchIDs(account, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch processed matches: %w", err)
+	}
+	matches, err := connector.RecentMatches(ctx, account.ExternalID, since, known)
+	if err != nil {
+		return nil, err
+	}
+	newMatches := []models.ProcessedMatch{}
+	for _, match := range matches {
+		// matches, which ended before linking the account, belong to the history
+		if match.EndedAt.Before(account.CreatedAt) {
+			continue
+		}
+		newMatches = append(newMatches, models.ProcessedMatch{
+			MatchID: match.ID,
+			Game:    match.Game,
+			Deaths:  match.Deaths,
+			EndedAt: match.EndedAt,
+		})
+	}
+	return p.Repo.RecordMatches(account, newMatches, now)
+}
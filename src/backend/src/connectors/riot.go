@@ -0,0 +1,231 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// regional routing value of the Riot API for accounts and matches of europe
+	DefaultRiotBaseURL = "https://europe.api.riotgames.com"
+	// maximum number of match IDs fetched per game and poll
+	defaultRiotMaxMatches = 20
+)
+
+// errRiotNotFound is returned, when the Riot API answers with 404 Not Found
+var errRiotNotFound = errors.New("not found by the Riot API")
+
+// RiotConnector fetches League of Legends and Teamfight Tactics matches from the Riot API.
+// Accounts are linked with their Riot ID <name>#<tag>
+type RiotConnector struct {
+	// regional routing URL like https://europe.api.riotgames.com
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+	// maximum number of match IDs fetched per game and poll
+	MaxMatches int
+}
+
+func NewRiotConnector(baseURL string, apiKey string) *RiotConnector {
+	if baseURL == "" {
+		baseURL = DefaultRiotBaseURL
+	}
+	return &RiotConnector{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		MaxMatches: defaultRiotMaxMatches,
+	}
+}
+
+func (r *RiotConnector) Name() string {
+	return "riot"
+}
+
+type riotAccount struct {
+	PUUID    string `json:"puuid"`
+	GameName string `json:"gameName"`
+	TagLine  string `json:"tagLine"`
+}
+
+// ResolveAccount looks up the Riot ID <name> in the format <game name>#<tag line>
+func (r *RiotConnector) ResolveAccount(ctx context.Context, name string) (*Account, error) {
+	gameName, tagLine, ok := strings.Cut(strings.TrimSpace(name), "#")
+	if !ok || gameName == "" || tagLine == "" {
+		return nil, fmt.Errorf("%w: %q is no Riot ID like name#tag", ErrAccountNotFound, name)
+	}
+
+	var account riotAccount
+	path := fmt.Sprintf("/riot/account/v1/accounts/by-riot-id/%s/%s", url.PathEscape(gameName), url.PathEscape(tagLine))
+	err := r.get(ctx, path, nil, &account)
+	if errors.Is(err, errRiotNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Account{ExternalID: account.PUUID, DisplayName: account.GameName + "#" + account.TagLine}, nil
+}
+
+// RecentMatches returns the League of Legends and Teamfight Tactics matches of the PUUID <externalID>,
+// which are not in <known>
+func (r *RiotConnector) RecentMatches(ctx context.Context, externalID string, since time.Time, known map[string]bool) ([]Match, error) {
+	leagueMatches, err := r.recentMatches(ctx, "/lol/match/v5/matches", externalID, since, known, r.leagueMatch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch league matches: %w", err)
+	}
+	tftMatches, err := r.recentMatches(ctx, "/tft/match/v1/matches", externalID, since, known, r.tftMatch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tft matches: %w", err)
+	}
+	return append(leagueMatches, tftMatches...), nil
+}
+
+// fetches the IDs of the matches of <puuid> from <basePath> and every match not in <known> with <fetchMatch>
+func (r *RiotConnector) recentMatches(
+	ctx context.Context,
+	basePath string,
+	puuid string,
+	since time.Time,
+	known map[string]bool,
+	fetchMatch func(ctx context.Context, path string, puuid string) (*Match, error),
+) ([]Match, error) {
+	query := url.Values{}
+	query.Set("startTime", strconv.FormatInt(since.Unix(), 10))
+	query.Set("count", strconv.Itoa(r.MaxMatches))
+
+	var ids []string
+	if err := r.get(ctx, basePath+"/by-puuid/"+url.PathEscape(puuid)+"/ids", query, &ids); err != nil {
+		return nil, err
+	}
+	matches := []Match{}
+	for _, id := range ids {
+		if known[id] {
+			// every fetched match counts against the rate limit
+			continue
+		}
+		match, err := fetchMatch(ctx, basePath+"/"+url.PathEscape(id), puuid)
+		if errors.Is(err, errRiotNotFound) {
+			// listed matches can be missing for a short time
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch match %s: %w", id, err)
+		}
+		if match != nil {
+			matches = append(matches, *match)
+		}
+	}
+	return matches, nil
+}
+
+type riotLeagueMatch struct {
+	Metadata struct {
+		MatchID string `json:"matchId"`
+	} `json:"metadata"`
+	Info struct {
+		GameStartTimestamp int64 `json:"gameStartTimestamp"`
+		GameEndTimestamp   int64 `json:"gameEndTimestamp"`
+		// seconds since the start of the match
+		GameDuration int64 `json:"gameDuration"`
+		Participants []struct {
+			PUUID  string `json:"puuid"`
+			Deaths int64  `json:"deaths"`
+		} `json:"participants"`
+	} `json:"info"`
+}
+
+// fetches the League of Legends match at <path> and returns the deaths of <puuid>
+func (r *RiotConnector) leagueMatch(ctx context.Context, path string, puuid string) (*Match, error) {
+	var match riotLeagueMatch
+	if err := r.get(ctx, path, nil, &match); err != nil {
+		return nil, err
+	}
+	endedAt := time.UnixMilli(match.Info.GameEndTimestamp)
+	if match.Info.GameEndTimestamp == 0 {
+		// matches before patch 11.20 have no end timestamp
+		endedAt = time.UnixMilli(match.Info.GameStartTimestamp).Add(time.Duration(match.Info.GameDuration) * time.Second)
+	}
+	for _, participant := range match.Info.Participants {
+		if participant.PUUID == puuid {
+			return &Match{ID: match.Metadata.MatchID, Game: "league", Deaths: participant.Deaths, EndedAt: endedAt.UTC()}, nil
+		}
+	}
+	return nil, nil
+}
+
+type riotTFTMatch struct {
+	Metadata struct {
+		MatchID string `json:"match_id"`
+	} `json:"metadata"`
+	Info struct {
+		// unix milliseconds of the end of the match
+		GameDatetime int64 `json:"game_datetime"`
+		Participants []struct {
+			PUUID     string `json:"puuid"`
+			Placement int    `json:"placement"`
+		} `json:"participants"`
+	} `json:"info"`
+}
+
+// fetches the Teamfight Tactics match at <path>. Every player except the winner is eliminated
+// exactly once, which counts as one death
+func (r *RiotConnector) tftMatch(ctx context.Context, path string, puuid string) (*Match, error) {
+	var match riotTFTMatch
+	if err := r.get(ctx, path, nil, &match); err != nil {
+		return nil, err
+	}
+	for _, participant := range match.Info.Participants {
+		if participant.PUUID != puuid {
+			continue
+		}
+		var deaths int64
+		if participant.Placement > 1 {
+			deaths = 1
+		}
+		return &Match{
+			ID:      match.Metadata.MatchID,
+			Game:    "tft",
+			Deaths:  deaths,
+			EndedAt: time.UnixMilli(match.Info.GameDatetime).UTC(),
+		}, nil
+	}
+	return nil, nil
+}
+
+// sends a GET request to <path> with <query> and decodes the JSON reply into <target>
+func (r *RiotConnector) get(ctx context.Context, path string, query url.Values, target any) error {
+	requestURL := r.BaseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("X-Riot-Token", r.APIKey)
+
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", errRiotNotFound, path)
+	case response.StatusCode == http.StatusTooManyRequests:
+		retryAfter, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+		return &RateLimitError{Connector: r.Name(), RetryAfter: time.Duration(retryAfter) * time.Second}
+	case response.StatusCode != http.StatusOK:
+		return fmt.Errorf("riot API answered %s with %s", path, response.Status)
+	}
+	return json.NewDecoder(response.Body).Decode(target)
+}
@@ -0,0 +1,247 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	testAPIKey = "RGAPI-test"
+	testPUUID  = "puuid-1"
+)
+
+// fakeRiot serves the account and match endpoints of the Riot API from memory
+type fakeRiot struct {
+	// league and tft matches by ID
+	league map[string]map[string]any
+	tft    map[string]map[string]any
+	// start time in unix seconds by match ID, used for the startTime filter
+	startTimes map[string]int64
+	// answer every request with 429
+	rateLimited bool
+	// number of match requests
+	matchRequests int
+}
+
+func newFakeRiot() *fakeRiot {
+	return &fakeRiot{
+		league:     map[string]map[string]any{},
+		tft:        map[string]map[string]any{},
+		startTimes: map[string]int64{},
+	}
+}
+
+func (f *fakeRiot) addLeagueMatch(id string, end time.Time, deaths int) {
+	f.startTimes[id] = end.Add(-30 * time.Minute).Unix()
+	f.league[id] = map[string]any{
+		"metadata": map[string]any{"matchId": id},
+		"info": map[string]any{
+			"gameStartTimestamp": end.Add(-30 * time.Minute).UnixMilli(),
+			"gameEndTimestamp":   end.UnixMilli(),
+			"participants": []map[string]any{
+				{"puuid": "someone-else", "deaths": 1},
+				{"puuid": testPUUID, "deaths": deaths},
+			},
+		},
+	}
+}
+
+func (f *fakeRiot) addTFTMatch(id string, end time.Time, placement int) {
+	f.startTimes[id] = end.Add(-30 * time.Minute).Unix()
+	f.tft[id] = map[string]any{
+		"metadata": map[string]any{"match_id": id},
+		"info": map[string]any{
+			"game_datetime": end.UnixMilli(),
+			"participants":  []map[string]any{{"puuid": testPUUID, "placement": placement}},
+		},
+	}
+}
+
+func (f *fakeRiot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Riot-Token") != testAPIKey {
+		http.Error(w, `{"status":{"status_code":401}}`, http.StatusUnauthorized)
+		return
+	}
+	if f.rateLimited {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/riot/account/v1/accounts/by-riot-id/Faker/KR1":
+		writeJSON(w, map[string]string{"puuid": testPUUID, "gameName": "Faker", "tagLine": "KR1"})
+	case strings.HasPrefix(path, "/lol/match/v5/matches/"):
+		f.serveMatches(w, r, strings.TrimPrefix(path, "/lol/match/v5/matches/"), f.league)
+	case strings.HasPrefix(path, "/tft/match/v1/matches/"):
+		f.serveMatches(w, r, strings.TrimPrefix(path, "/tft/match/v1/matches/"), f.tft)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serves the match IDs of the test PUUID or a single match
+func (f *fakeRiot) serveMatches(w http.ResponseWriter, r *http.Request, path string, matches map[string]map[string]any) {
+	if path == "by-puuid/"+testPUUID+"/ids" {
+		startTime, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		ids := []string{}
+		for id := range matches {
+			if f.startTimes[id] >= startTime {
+				ids = append(ids, id)
+			}
+		}
+		writeJSON(w, ids)
+		return
+	}
+	match, ok := matches[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	f.matchRequests++
+	writeJSON(w, match)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func newTestRiotConnector(t *testing.T, fake *fakeRiot) *RiotConnector {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewRiotConnector(server.URL, testAPIKey)
+}
+
+func TestRiotResolveAccount(t *testing.T) {
+	connector := newTestRiotConnector(t, newFakeRiot())
+
+	account, err := connector.ResolveAccount(context.Background(), " Faker#KR1 ")
+	if err != nil {
+		t.Fatalf("failed to resolve account: %v", err)
+	}
+	if account.ExternalID != testPUUID || account.DisplayName != "Faker#KR1" {
+		t.Errorf("got account %+v", account)
+	}
+
+	for _, name := range []string{"Faker", "Faker#", "Nobody#EUW"} {
+		if _, err := connector.ResolveAccount(context.Background(), name); !errors.Is(err, repositories.ErrNotFound) {
+			t.Errorf("got %v for %q, want ErrNotFound", err, name)
+		}
+	}
+}
+
+func TestRiotRecentMatches(t *testing.T) {
+	fake := newFakeRiot()
+	connector := newTestRiotConnector(t, fake)
+	now := time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC)
+	fake.addLeagueMatch("EUW1_1", now.Add(-3*time.Hour), 7)
+	fake.addLeagueMatch("EUW1_2", now.Add(-time.Hour), 2)
+	fake.addTFTMatch("EUW1_3", now, 4)
+	fake.addTFTMatch("EUW1_4", now, 1)
+
+	matches, err := connector.RecentMatches(context.Background(), testPUUID, now.Add(-2*time.Hour), map[string]bool{"EUW1_4": true})
+	if err != nil {
+		t.Fatalf("failed to fetch matches: %v", err)
+	}
+	deaths := map[string]int64{}
+	for _, match := range matches {
+		deaths[match.ID] = match.Deaths
+	}
+	want := map[string]int64{"EUW1_2": 2, "EUW1_3": 1}
+	if len(deaths) != len(want) {
+		t.Fatalf("got matches %+v, want %v", matches, want)
+	}
+	for id, count := range want {
+		if deaths[id] != count {
+			t.Errorf("got %d deaths in %s, want %d", deaths[id], id, count)
+		}
+	}
+	// the known match is skipped without fetching it
+	if fake.matchRequests != len(want) {
+		t.Errorf("got %d match requests, want %d", fake.matchRequests, len(want))
+	}
+
+	fake.rateLimited = true
+	_, err = connector.RecentMatches(context.Background(), testPUUID, now, nil)
+	var rateLimit *RateLimitError
+	if !errors.As(err, &rateLimit) || rateLimit.RetryAfter != 7*time.Second {
+		t.Errorf("got %v, want a RateLimitError with 7s", err)
+	}
+}
+
+// TestPoller verifies that new matches become overdue deaths exactly once
+func TestPoller(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.OverdueDeaths{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := db.NewGormGameAccountRepository(database)
+
+	fake := newFakeRiot()
+	linkedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := linkedAt
+	poller := NewPoller(repo, []Connector{newTestRiotConnector(t, fake)}, func() time.Time { return now })
+
+	account, err := repo.Create(&models.GameAccount{UserID: 1, Connector: "riot", ExternalID: testPUUID, CreatedAt: linkedAt})
+	if err != nil {
+		t.Fatalf("failed to link account: %v", err)
+	}
+	if _, err := repo.Create(&models.GameAccount{UserID: 1, Connector: "riot", ExternalID: "puuid-2"}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("got %v when linking a second riot account, want ErrConflict", err)
+	}
+
+	// played before linking the account
+	fake.addLeagueMatch("EUW1_1", linkedAt.Add(-time.Hour), 9)
+	fake.addLeagueMatch("EUW1_2", linkedAt.Add(time.Hour), 3)
+	fake.addTFTMatch("EUW1_3", linkedAt.Add(2*time.Hour), 5)
+
+	now = linkedAt.Add(3 * time.Hour)
+	processed, err := poller.PollAll(context.Background())
+	if err != nil || processed != 2 {
+		t.Fatalf("got %d processed matches, %v; want 2", processed, err)
+	}
+	// polled within the interval
+	if processed, _ := poller.PollAll(context.Background()); processed != 0 {
+		t.Errorf("got %d processed matches directly after polling, want 0", processed)
+	}
+
+	// the lookback lists the old matches again, but only the new one is fetched and processed
+	fake.addLeagueMatch("EUW1_4", now.Add(time.Hour), 4)
+	now = now.Add(2 * time.Hour)
+	fake.matchRequests = 0
+	matches, err := poller.Poll(context.Background(), poller.Connectors["riot"], account)
+	if err != nil {
+		t.Fatalf("failed to poll: %v", err)
+	}
+	if len(matches) != 1 || matches[0].MatchID != "EUW1_4" {
+		t.Errorf("got new matches %+v, want only EUW1_4", matches)
+	}
+	if fake.matchRequests != 1 {
+		t.Errorf("got %d match requests, want 1", fake.matchRequests)
+	}
+
+	var overdue []models.OverdueDeaths
+	database.Order("game").Find(&overdue)
+	if len(overdue) != 2 || overdue[0].Game != "league" || overdue[0].Count != 7 || overdue[1].Count != 1 {
+		t.Errorf("got overdue deaths %+v, want 7 in league and 1 in tft", overdue)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountDeletionReply is the reply sent when doing [delete] /me
+// swagger:model AccountDeletionReply
+type AccountDeletionReply struct {
+	Data AccountDeletion `json:"data"`
+}
+
+// AccountController deletes and exports the account of the logged in user
+type AccountController struct {
+	repo AccountRepository
+	// time between the request and the deletion, in which the user can cancel it
+	gracePeriod time.Duration
+	Now         func() time.Time
+}
+
+func NewAccountController(repo AccountRepository, gracePeriod time.Duration, Now func() time.Time) *AccountController {
+	return &AccountController{repo: repo, gracePeriod: gracePeriod, Now: Now}
+}
+
+// @Summary Requests the deletion of the logged in user and all of his data.
+// @Description The account is deleted after a grace period, in which the deletion can be cancelled.
+// @Tags account
+// @Produce json
+// @Security CookieAuth
+// @Success 202 {object} AccountDeletionReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/me [delete]
+func (ac *AccountController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	now := ac.Now().UTC()
+	deletion, err := ac.repo.ScheduleDeletion(user.ID, now, now.Add(ac.gracePeriod))
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, AccountDeletionReply{Data: *deletion})
+}
+
+// @Summary Cancels the requested deletion of the logged in user
+// @Tags account
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/me/deletion [delete]
+func (ac *AccountController) CancelDeletion(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if err := ac.repo.CancelDeletion(user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Account deletion cancelled"})
+}
+
+// @Summary Exports everything stored about the logged in user
+// @Description ZIP archive with account.json containing all data and a CSV file for sports,
+// @Description game sessions, overdue deaths, personal goals and friendships
+// @Tags account
+// @Produce application/zip
+// @Security CookieAuth
+// @Success 200 {file} file
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/me/export [get]
+func (ac *AccountController) Export(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	export, err := ac.repo.Export(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("gotohell-export-%d-%s.zip", user.ID, ac.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	// the status is already sent, errors can only be logged
+	if err := writeAccountExport(c.Writer, export); err != nil {
+		log.Printf("Failed to write export of user %d: %v", user.ID, err)
+	}
+}
+
+// writes <export> as ZIP archive to <w>
+func writeAccountExport(w io.Writer, export *AccountExport) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("account.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	tables := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"sports.csv", []string{"id", "kind", "amount", "game", "timedate"}, sportRows(export.Sports)},
+		{"game_sessions.csv", []string{"id", "game", "started_at", "ended_at", "deaths", "overdue_deaths", "outcome"}, gameSessionRows(export.GameSessions)},
+		{"overdue_deaths.csv", []string{"game", "count"}, overdueDeathRows(export.OverdueDeaths)},
+		{"personal_goals.csv", []string{"id", "sport", "amount", "frequency"}, personalGoalRows(export.PersonalGoals)},
+		{"friendships.csv", []string{"id", "requester_id", "recipient_id", "status", "created_at"}, friendshipRows(export.Friendships)},
+	}
+	for _, table := range tables {
+		file, err := archive.Create(table.name)
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(file)
+		if err := writer.Write(table.header); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(table.rows); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func sportRows(sports []Sport) [][]string {
+	rows := make([][]string, 0, len(sports))
+	for _, sport := range sports {
+		rows = append(rows, []string{
+			formatSnowflake(sport.ID),
+			sport.Kind,
+			strconv.Itoa(sport.Amount),
+			sport.Game,
+			sport.Timedate.UTC().Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func gameSessionRows(sessions []GameSession) [][]string {
+	rows := make([][]string, 0, len(sessions))
+	for _, session := range sessions {
+		endedAt := ""
+		if session.EndedAt != nil {
+			endedAt = session.EndedAt.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			formatSnowflake(session.ID),
+			session.Game,
+			session.StartedAt.UTC().Format(time.RFC3339),
+			endedAt,
+			strconv.FormatInt(session.Deaths, 10),
+			strconv.FormatInt(session.OverdueDeaths, 10),
+			string(session.Outcome),
+		})
+	}
+	return rows
+}
+
+func overdueDeathRows(overdueDeaths []OverdueDeaths) [][]string {
+	rows := make([][]string, 0, len(overdueDeaths))
+	for _, deaths := range overdueDeaths {
+		rows = append(rows, []string{deaths.Game, strconv.FormatInt(deaths.Count, 10)})
+	}
+	return rows
+}
+
+func personalGoalRows(goals []PersonalGoal) [][]string {
+	rows := make([][]string, 0, len(goals))
+	for _, goal := range goals {
+		rows = append(rows, []string{
+			formatSnowflake(goal.ID),
+			goal.Sport,
+			strconv.Itoa(goal.Amount),
+			string(goal.Frequency),
+		})
+	}
+	return rows
+}
+
+func friendshipRows(friendships []Friendships) [][]string {
+	rows := make([][]string, 0, len(friendships))
+	for _, friendship := range friendships {
+		rows = append(rows, []string{
+			formatSnowflake(friendship.ID),
+			formatSnowflake(friendship.RequesterID),
+			formatSnowflake(friendship.RecipientID),
+			string(friendship.Status),
+			friendship.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func formatSnowflake(id Snowflake) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// random bytes of a token, encoded to 43 characters
+	apiTokenBytes = 32
+	// characters of the token stored in plain text to tell tokens apart
+	apiTokenPrefixLength = len(TokenPrefix) + 4
+	maxAPITokenLifetime  = 365
+)
+
+// PostAPITokenRequest is the request sent when doing [post] /tokens
+// swagger:model PostAPITokenRequest
+type PostAPITokenRequest struct {
+	Name string `json:"name" binding:"required,max=64" example:"Stream Deck"`
+	// at least one of read, sport:write and goals:write
+	Scopes []TokenScope `json:"scopes" binding:"required,min=1" example:"read,sport:write"`
+	// how long the token is valid. 0 or missing means it never expires, maximum 365 days
+	ExpiresInDays int `json:"expires_in_days,omitempty" binding:"gte=0" example:"90"`
+}
+
+// PostAPITokenReply contains the created token. The token itself is only sent once
+// swagger:model PostAPITokenReply
+type PostAPITokenReply struct {
+	Token string   `json:"token" example:"gth_3Kd9..."`
+	Data  APIToken `json:"data"`
+}
+
+// GetAPITokensReply is the reply sent when doing [get] /tokens
+// swagger:model GetAPITokensReply
+type GetAPITokensReply struct {
+	Data []APIToken `json:"data"`
+}
+
+// APITokensController manages the personal access tokens of the logged in user
+type APITokensController struct {
+	repo APITokenRepository
+	Now  func() time.Time
+}
+
+func NewAPITokensController(repo APITokenRepository, Now func() time.Time) *APITokensController {
+	return &APITokensController{repo: repo, Now: Now}
+}
+
+// generates a random token with the `gth_` prefix
+func generateAPIToken() (string, error) {
+	b := make([]byte, apiTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// @Summary Creates a personal access token, which is sent as `Authorization: Bearer <token>`
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PostAPITokenRequest true "Name, scopes and lifetime of the token"
+// @Success 200 {object} PostAPITokenReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/tokens [post]
+func (tc *APITokensController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var req PostAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !scope.IsValid() {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid scope: %s", scope))
+			return
+		}
+	}
+	if req.ExpiresInDays > maxAPITokenLifetime {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("tokens can be valid for at most %d days", maxAPITokenLifetime))
+		return
+	}
+
+	raw, err := generateAPIToken()
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	now := tc.Now().UTC()
+	token := &APIToken{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    raw[:apiTokenPrefixLength],
+		Hash:      middleware.HashToken(raw),
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	token, err = tc.repo.Create(token)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PostAPITokenReply{Token: raw, Data: *token})
+}
+
+// @Summary Lists all personal access tokens of the logged in user
+// @Tags tokens
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetAPITokensReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/tokens [get]
+func (tc *APITokensController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	tokens, err := tc.repo.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetAPITokensReply{Data: tokens})
+}
+
+// @Summary Revokes a personal access token of the logged in user
+// @Tags tokens
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/tokens/{id} [delete]
+func (tc *APITokensController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid token ID: %s", c.Param("id")))
+		return
+	}
+
+	err = tc.repo.Revoke(Snowflake(id), user.ID, tc.Now().UTC())
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Token revoked successfully"})
+}
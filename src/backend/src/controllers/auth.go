@@ -3,48 +3,56 @@ package controllers
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/auth"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/config"
-	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/oauth2"
 )
 
 // AuthController handles authentication logic
 type AuthController struct {
-	OAuthConfig *oauth2.Config
-	userRepo    db.UserRepository
+	authService  *auth.Service
+	settingsRepo repositories.UserSettingsRepository
 }
 
-// NewAuthController creates a new auth controller
-func NewAuthController(oauthConfig *oauth2.Config, userRepo db.UserRepository) *AuthController {
-	return &AuthController{
-		OAuthConfig: oauthConfig,
-		userRepo:    userRepo,
-	}
+// GetUserReply is the logged in user together with his settings
+// swagger:model GetUserReply
+type GetUserReply struct {
+	models.JsUser
+	Settings models.UserSettings `json:"settings"`
 }
 
-// retrieves the user  by Context and session
-func UserFromSession(c *gin.Context) (*models.User, int, error) {
-	session := sessions.Default(c)
-	userData := session.Get("user")
-	if userData == nil {
-		return nil, http.StatusUnauthorized, fmt.Errorf("not logged in")
-	}
+// GetProvidersReply is the reply sent when doing [get] /auth/providers
+// swagger:model GetProvidersReply
+type GetProvidersReply struct {
+	Data []string `json:"data"`
+}
 
-	user_go, ok := userData.(models.User)
-	if !ok {
-		return nil, http.StatusInternalServerError, fmt.Errorf("wrong user format: %v %v", userData, ok)
-	}
+// GetIdentitiesReply is the reply sent when doing [get] /auth/identities
+// swagger:model GetIdentitiesReply
+type GetIdentitiesReply struct {
+	Data []models.UserIdentity `json:"data"`
+}
 
-	return &user_go, http.StatusOK, nil
+// NewAuthController creates a new auth controller
+func NewAuthController(
+	authService *auth.Service,
+	settingsRepo repositories.UserSettingsRepository,
+) *AuthController {
+	return &AuthController{
+		authService:  authService,
+		settingsRepo: settingsRepo,
+	}
 }
 
 // GenerateState creates a random state string for OAuth
@@ -56,99 +64,178 @@ func (ac *AuthController) GenerateState() (string, error) {
 	return base64.URLEncoding.EncodeToString(b), nil
 }
 
-// Login initiates Discord OAuth flow
+// returns the provider of the :provider route parameter or sets a 404
+func (ac *AuthController) providerFromParam(c *gin.Context) (auth.Provider, bool) {
+	name := c.Param("provider")
+	provider, ok := ac.authService.Provider(name)
+	if !ok {
+		SetGinError(c, http.StatusNotFound, fmt.Errorf("unknown login provider: %s", name))
+	}
+	return provider, ok
+}
+
+// Login initiates the OAuth flow of the provider. If the user is already logged in,
+// the account of the provider is linked to him after the callback.
 func (ac *AuthController) Login(c *gin.Context) {
+	provider, ok := ac.providerFromParam(c)
+	if !ok {
+		return
+	}
+
 	state, err := ac.GenerateState()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to generate state"))
 		return
 	}
 
 	session := sessions.Default(c)
 	session.Set("state", state)
+	session.Set("provider", provider.Name())
 	if err := session.Save(); err != nil {
 		log.Printf("Save session failed: %v", err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to save session"))
 		return
 	}
 
-	url := ac.OAuthConfig.AuthCodeURL(state)
+	url := provider.AuthCodeURL(state)
 	c.Redirect(http.StatusTemporaryRedirect, url)
 }
 
-// Callback handles OAuth callback from Discord
+// Callback handles the OAuth callback of the provider
 func (ac *AuthController) Callback(c *gin.Context) {
+	provider, ok := ac.providerFromParam(c)
+	if !ok {
+		return
+	}
+
 	session := sessions.Default(c)
 	savedState := session.Get("state")
+	savedProvider := session.Get("provider")
 	queryState := c.Query("state")
 
-	if savedState == nil || savedState != queryState {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid state parameter"})
+	if savedState == nil || savedState != queryState || savedProvider != provider.Name() {
+		SetGinError(c, http.StatusBadRequest, errors.New("Invalid state parameter"))
 		return
 	}
 
 	session.Delete("state")
+	session.Delete("provider")
 	session.Save()
 
 	code := c.Query("code")
 	if code == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Code not found"})
+		SetGinError(c, http.StatusBadRequest, errors.New("Code not found"))
 		return
 	}
 
-	token, err := ac.OAuthConfig.Exchange(c, code)
+	identity, err := provider.Exchange(c, code)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange code for token"})
+		log.Printf("%s login failed: %v", provider.Name(), err)
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to get user info"))
 		return
 	}
 
-	client := ac.OAuthConfig.Client(c, token)
-	resp, err := client.Get("https://discord.com/api/users/@me")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
-		return
+	// a logged in user links an additional account
+	var currentUser *models.User
+	if sessionUser, ok := session.Get("user").(models.User); ok {
+		currentUser = &sessionUser
 	}
-	defer resp.Body.Close()
 
-	var d_user models.JsUser
-	if err := json.NewDecoder(resp.Body).Decode(&d_user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user info"})
+	user, err := ac.authService.Login(identity, currentUser)
+	if errors.Is(err, auth.ErrIdentityLinkedToOtherUser) {
+		SetGinError(c, http.StatusConflict, err)
 		return
 	}
-	user, err := d_user.Parse()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "User ID was not parsable to int"})
+		log.Printf("%s login failed: %v", provider.Name(), err)
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to log in"))
+		return
 	}
 
 	session.Set("user", *user)
-	ac.userRepo.CreateUser(user)
 	if err := session.Save(); err != nil {
 		log.Printf("user: %v; Error: %v", user, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to save session"))
 		return
 	}
 	redirect_url := fmt.Sprintf("%v", config.AppConfig.FrontendURL)
 	c.Redirect(http.StatusTemporaryRedirect, redirect_url)
 }
 
+// @Summary Lists the names of all configured login providers
+// @Tags auth
+// @Produce json
+// @Success 200 {object} GetProvidersReply
+// @Router /api/auth/providers [get]
+func (ac *AuthController) GetProviders(c *gin.Context) {
+	names := make([]string, 0, len(ac.authService.Providers))
+	for name := range ac.authService.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, GetProvidersReply{Data: names})
+}
+
+// @Summary Lists the accounts linked to the logged in user
+// @Tags auth
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetIdentitiesReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/auth/identities [get]
+func (ac *AuthController) GetIdentities(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	identities, err := ac.authService.Identities.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetIdentitiesReply{Data: identities})
+}
+
+// @Summary Unlinks the account of a provider from the logged in user. The last account can't be unlinked
+// @Tags auth
+// @Produce json
+// @Security CookieAuth
+// @Param provider path string true "Name of the provider"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/auth/identities/{provider} [delete]
+func (ac *AuthController) DeleteIdentity(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	err := ac.authService.Identities.Delete(user.ID, c.Param("provider"))
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Account unlinked successfully"})
+}
+
 // GetUser returns the current authenticated user
 func (ac *AuthController) GetUser(c *gin.Context) {
-	session := sessions.Default(c)
-	user := session.Get("user")
-	if user == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
+	user_go := middleware.CurrentUser(c)
+	settings, err := ac.settingsRepo.Get(user_go.ID)
+	if err != nil {
+		SetError(c, err)
 		return
 	}
-	user_go := user.(models.User)
-	c.JSON(http.StatusOK, user_go.ParseJS())
+	c.JSON(http.StatusOK, GetUserReply{JsUser: user_go.ParseJS(), Settings: *settings})
 }
 
 // Logout clears the user session
 func (ac *AuthController) Logout(c *gin.Context) {
 	session := sessions.Default(c)
 	session.Clear()
+	// deletes the server-side session, so that the cookie can't be used anymore
+	session.Options(sessions.Options{Path: "/", MaxAge: -1})
 	if err := session.Save(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear session"})
+		SetGinError(c, http.StatusInternalServerError, errors.New("Failed to clear session"))
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
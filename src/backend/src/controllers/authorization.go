@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// FilterRequestedUsers reduces <requestedIDs> to the users whose <resource> the viewer is allowed to see.
+// Forbidden IDs are left out. If none of the requested users is visible, a 403 is set
+// and ok is false, so that the caller can return.
+func FilterRequestedUsers(
+	c *gin.Context,
+	visibility db.IVisibilityService,
+	viewerID Snowflake,
+	requestedIDs []Snowflake,
+	resource Resource,
+) (visible []Snowflake, forbidden []Snowflake, ok bool) {
+	visible, forbidden, err := visibility.FilterVisible(viewerID, requestedIDs, resource)
+	if err != nil {
+		SetError(c, err)
+		return nil, nil, false
+	}
+	if len(visible) == 0 && len(forbidden) > 0 {
+		SetGinError(c, http.StatusForbidden, fmt.Errorf("not allowed to view %s of users %v", resource, forbidden))
+		return nil, nil, false
+	}
+	return visible, forbidden, true
+}
+
+// RequireVisible sets a 403 and returns false, if the viewer is not allowed to see <resource> of <ownerID>
+func RequireVisible(
+	c *gin.Context,
+	visibility db.IVisibilityService,
+	viewerID Snowflake,
+	ownerID Snowflake,
+	resource Resource,
+) bool {
+	allowed, err := visibility.CanView(viewerID, ownerID, resource)
+	if err != nil {
+		SetError(c, err)
+		return false
+	}
+	if !allowed {
+		SetGinError(c, http.StatusForbidden, fmt.Errorf("not allowed to view %s of user %d", resource, ownerID))
+		return false
+	}
+	return true
+}
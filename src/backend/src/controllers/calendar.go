@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/calendar"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// random bytes of a calendar feed token, encoded to 43 characters
+	calendarTokenBytes = 32
+	// characters of the token stored in plain text to tell feed URLs apart
+	calendarTokenPrefixLength = len(CalendarTokenPrefix) + 4
+)
+
+// GetCalendarFeedReply is the reply sent when doing [get] /settings/calendar.
+// Data is null, when no feed was created
+// swagger:model GetCalendarFeedReply
+type GetCalendarFeedReply struct {
+	Data *CalendarFeed `json:"data"`
+}
+
+// PostCalendarFeedReply contains the URL of the created feed. The URL is only sent once
+// swagger:model PostCalendarFeedReply
+type PostCalendarFeedReply struct {
+	URL  string       `json:"url" example:"https://api.example.com/api/calendar/gthc_3Kd9....ics"`
+	Data CalendarFeed `json:"data"`
+}
+
+// CalendarController serves the private iCalendar feeds of users and manages the feed of the
+// logged in user
+type CalendarController struct {
+	repo CalendarFeedRepository
+	feed *calendar.Feed
+	// URL under which calendar apps reach the backend
+	backendURL string
+	Now        func() time.Time
+}
+
+func NewCalendarController(
+	repo CalendarFeedRepository,
+	feed *calendar.Feed,
+	backendURL string,
+	Now func() time.Time,
+) *CalendarController {
+	return &CalendarController{repo: repo, feed: feed, backendURL: strings.TrimRight(backendURL, "/"), Now: Now}
+}
+
+// generates a random feed token with the `gthc_` prefix
+func generateCalendarToken() (string, error) {
+	b := make([]byte, calendarTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return CalendarTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// @Summary Get the metadata of the calendar feed of the logged in user
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetCalendarFeedReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/calendar [get]
+func (cc *CalendarController) GetFeed(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	feed, err := cc.repo.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetCalendarFeedReply{Data: feed})
+}
+
+// @Summary Creates or rotates the private calendar feed URL of the logged in user
+// @Description The previous URL stops working immediately.
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} PostCalendarFeedReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/calendar [post]
+func (cc *CalendarController) PostFeed(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	token, err := generateCalendarToken()
+	if err != nil {
+		SetGinError(c, http.StatusInternalServerError, err)
+		return
+	}
+	feed, err := cc.repo.Save(&CalendarFeed{
+		UserID:    user.ID,
+		Hash:      middleware.HashToken(token),
+		Prefix:    token[:calendarTokenPrefixLength],
+		CreatedAt: cc.Now().UTC(),
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	url := fmt.Sprintf("%s/api/calendar/%s.ics", cc.backendURL, token)
+	c.JSON(http.StatusOK, PostCalendarFeedReply{URL: url, Data: *feed})
+}
+
+// @Summary Revokes the calendar feed URL of the logged in user
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/calendar [delete]
+func (cc *CalendarController) DeleteFeed(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if err := cc.repo.Delete(user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Calendar feed revoked successfully"})
+}
+
+// @Summary Returns the iCalendar feed with goal deadlines, check-ins and streak reminders
+// @Description Works without login, the token of the feed URL authenticates the user
+// @Tags UserSettings
+// @Produce text/calendar
+// @Param token path string true "Token of the feed URL, optionally followed by .ics"
+// @Success 200 {string} string "iCalendar feed"
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/calendar/{token} [get]
+func (cc *CalendarController) GetICS(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	feed, err := cc.repo.FetchByHash(middleware.HashToken(token))
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if feed == nil {
+		SetGinError(c, http.StatusNotFound, errors.New("calendar feed not found"))
+		return
+	}
+
+	now := cc.Now().UTC()
+	ics, err := cc.feed.Build(feed.UserID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if err := cc.repo.Touch(feed.UserID, now); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ics.Bytes(now))
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// IncrementDeathsRequest is the optional request sent when doing [post] /overdue-deaths/increment
+// swagger:model IncrementDeathsRequest
+type IncrementDeathsRequest struct {
+	// the game, defaults to the game of the live game session
+	Game string `json:"game,omitempty" binding:"max=64" example:"overwatch"`
+	// number of deaths, defaults to 1
+	Count int64 `json:"count,omitempty" binding:"omitempty,gte=1,lte=100" example:"1"`
+}
+
+// LiveDeathsReply is the reply sent when doing [post] /overdue-deaths/increment
+// swagger:model LiveDeathsReply
+type LiveDeathsReply struct {
+	// deaths of the game, which were not added to the overdue deaths yet
+	Data LiveDeathCount `json:"data"`
+}
+
+// GetLiveDeathsReply is the reply sent when doing [get] /overdue-deaths/live
+// swagger:model GetLiveDeathsReply
+type GetLiveDeathsReply struct {
+	Data []LiveDeathCount `json:"data"`
+}
+
+// DeathCounterController counts deaths in memory, e.g. with a hotkey of an in-game overlay
+type DeathCounterController struct {
+	counter      db.IDeathCounter
+	gameSessions GameSessionRepository
+}
+
+func NewDeathCounterController(counter db.IDeathCounter, gameSessions GameSessionRepository) *DeathCounterController {
+	return &DeathCounterController{counter: counter, gameSessions: gameSessions}
+}
+
+// @Summary Counts deaths of the logged in user
+// @Description The deaths are added to the overdue deaths and the live game session of the game
+// @Description periodically and when the session is closed. The body can be omitted to count one
+// @Description death in the game of the live game session.
+// @Tags OverdueDeaths
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param request body IncrementDeathsRequest false "Game and number of deaths"
+// @Success 200 {object} LiveDeathsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 429 {object} ErrorReply
+// @Router /api/overdue-deaths/increment [post]
+func (dc *DeathCounterController) Increment(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	req := IncrementDeathsRequest{Count: 1}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+			return
+		}
+		if req.Count == 0 {
+			req.Count = 1
+		}
+	}
+	req.Game = NormalizeGame(req.Game)
+
+	if req.Game == "" {
+		session, err := dc.gameSessions.FetchLive(user.ID)
+		if err != nil {
+			SetError(c, err)
+			return
+		}
+		if session == nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("game is required without a live game session"))
+			return
+		}
+		req.Game = NormalizeGame(session.Game)
+	}
+
+	c.JSON(http.StatusOK, LiveDeathsReply{Data: dc.counter.Increment(user.ID, req.Game, req.Count)})
+}
+
+// @Summary Get the counted deaths of the logged in user, which were not added to the overdue deaths yet
+// @Tags OverdueDeaths
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Success 200 {object} GetLiveDeathsReply
+// @Router /api/overdue-deaths/live [get]
+func (dc *DeathCounterController) GetLive(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+	c.JSON(http.StatusOK, GetLiveDeathsReply{Data: dc.counter.Pending(user.ID)})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/discordbot"
+	"github.com/gin-gonic/gin"
+)
+
+// maximum size of an interaction sent by Discord
+const maxInteractionBytes = 64 << 10
+
+// DiscordInteractionsController receives the slash commands of the Discord bot
+type DiscordInteractionsController struct {
+	bot *discordbot.Bot
+	// public key of the Discord application. Without it, every interaction is rejected
+	publicKey ed25519.PublicKey
+}
+
+func NewDiscordInteractionsController(bot *discordbot.Bot, publicKey ed25519.PublicKey) *DiscordInteractionsController {
+	return &DiscordInteractionsController{bot: bot, publicKey: publicKey}
+}
+
+// @Summary Interactions endpoint of the Discord application, which answers slash commands
+// @Description Discord signs every interaction with the key of the application in the headers
+// @Description X-Signature-Ed25519 and X-Signature-Timestamp. Unsigned interactions are rejected.
+// @Tags discord
+// @Accept json
+// @Produce json
+// @Param X-Signature-Ed25519 header string true "hex encoded Ed25519 signature"
+// @Param X-Signature-Timestamp header string true "timestamp, which is signed together with the body"
+// @Success 200 {object} discordbot.InteractionResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Router /api/discord/interactions [post]
+func (dc *DiscordInteractionsController) Post(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxInteractionBytes))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	signature := c.GetHeader("X-Signature-Ed25519")
+	timestamp := c.GetHeader("X-Signature-Timestamp")
+	if !discordbot.VerifySignature(dc.publicKey, signature, timestamp, body) {
+		apierror.Abort(c, apierror.Unauthorized("invalid request signature"))
+		return
+	}
+
+	var interaction discordbot.Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	c.JSON(http.StatusOK, dc.bot.Handle(&interaction))
+}
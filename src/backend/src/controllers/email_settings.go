@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/mail"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailSettings contains the notification types, which the user receives by email
+// swagger:model EmailSettings
+type EmailSettings struct {
+	// the address emails are sent to. Emails can't be sent, when it's empty
+	Email         string             `json:"email" example:"inu@example.com"`
+	Subscriptions []NotificationType `json:"subscriptions" example:"weekly_digest,streak_warning"`
+	// every notification type, which can be sent by email
+	Available []NotificationType `json:"available" example:"friend_request,weekly_digest"`
+	// whether the server is configured to send emails
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// GetEmailSettingsReply is the reply sent when doing [get] /settings/email
+// swagger:model GetEmailSettingsReply
+type GetEmailSettingsReply struct {
+	Data EmailSettings `json:"data"`
+}
+
+// PutEmailSettingsRequest is the request sent when doing [put] /settings/email
+// swagger:model PutEmailSettingsRequest
+type PutEmailSettingsRequest struct {
+	// replaces all subscriptions, an empty list unsubscribes from everything
+	Subscriptions []NotificationType `json:"subscriptions" binding:"required" example:"weekly_digest"`
+}
+
+// GetEmailDeliveriesReply is the reply sent when doing [get] /settings/email/deliveries
+// swagger:model GetEmailDeliveriesReply
+type GetEmailDeliveriesReply struct {
+	Data []EmailDelivery `json:"data"`
+}
+
+// EmailSettingsController manages the email subscriptions of users
+type EmailSettingsController struct {
+	repo    EmailRepository
+	tokens  *mail.UnsubscribeTokens
+	enabled bool
+	Now     func() time.Time
+}
+
+func NewEmailSettingsController(
+	emailRepo EmailRepository,
+	tokens *mail.UnsubscribeTokens,
+	enabled bool,
+	Now func() time.Time,
+) *EmailSettingsController {
+	return &EmailSettingsController{repo: emailRepo, tokens: tokens, enabled: enabled, Now: Now}
+}
+
+// returns the email settings of <user>
+func (ec *EmailSettingsController) settings(user *User) (*EmailSettings, error) {
+	subscriptions, err := ec.repo.FetchSubscriptions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailSettings{
+		Email:         user.Email,
+		Subscriptions: subscriptions,
+		Available:     NotificationTypes,
+		Enabled:       ec.enabled,
+	}, nil
+}
+
+// @Summary Get the notification types the logged in user receives by email
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetEmailSettingsReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/email [get]
+func (ec *EmailSettingsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	settings, err := ec.settings(user)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetEmailSettingsReply{Data: *settings})
+}
+
+// @Summary Replace the notification types the logged in user receives by email
+// @Tags UserSettings
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PutEmailSettingsRequest true "Notification types to receive by email"
+// @Success 200 {object} GetEmailSettingsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/email [put]
+func (ec *EmailSettingsController) Put(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var req PutEmailSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	types := []NotificationType{}
+	for _, notificationType := range req.Subscriptions {
+		if !notificationType.IsValid() {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid notification type %q", notificationType))
+			return
+		}
+		if !slices.Contains(types, notificationType) {
+			types = append(types, notificationType)
+		}
+	}
+
+	if err := ec.repo.SaveSubscriptions(user.ID, types, ec.Now().UTC()); err != nil {
+		SetError(c, err)
+		return
+	}
+	settings, err := ec.settings(user)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetEmailSettingsReply{Data: *settings})
+}
+
+// @Summary Lists the emails sent to the logged in user, newest first
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Param limit query int false "Maximum number of emails, default is 50"
+// @Success 200 {object} GetEmailDeliveriesReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings/email/deliveries [get]
+func (ec *EmailSettingsController) GetDeliveries(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid limit value: %s", c.Query("limit")))
+		return
+	}
+
+	deliveries, err := ec.repo.FetchDeliveries(user.ID, limit)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetEmailDeliveriesReply{Data: deliveries})
+}
+
+// page of the unsubscribe links. Opening the link only asks for confirmation, since mail
+// scanners prefetch links
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe - GoToHell</title></head>
+<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem">
+{{if .Error}}<p>{{.Error}}</p>
+{{else if .Done}}<p>You won't receive {{.Type}} emails anymore.</p>
+{{else}}<p>Do you want to stop receiving {{.Type}} emails?</p>
+<form method="post" action="?token={{.Token}}"><button type="submit">Unsubscribe</button></form>
+{{end}}</body>
+</html>
+`))
+
+// data of the unsubscribe page
+type unsubscribePageData struct {
+	Token string
+	Type  NotificationType
+	Done  bool
+	Error string
+}
+
+// renders the unsubscribe page with <status>
+func renderUnsubscribePage(c *gin.Context, status int, data unsubscribePageData) {
+	var page bytes.Buffer
+	if err := unsubscribePage.Execute(&page, data); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// @Summary Shows the page of the unsubscribe link of an email, which asks for confirmation
+// @Description Works without login, the token is signed. Opening the link doesn't unsubscribe,
+// @Description since mail scanners prefetch links
+// @Tags UserSettings
+// @Produce html
+// @Param token query string true "Token of the unsubscribe link"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Page with the error"
+// @Router /api/email/unsubscribe [get]
+func (ec *EmailSettingsController) GetUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	_, notificationType, err := ec.tokens.Verify(token)
+	if err != nil {
+		renderUnsubscribePage(c, http.StatusBadRequest, unsubscribePageData{Error: "This unsubscribe link is invalid."})
+		return
+	}
+	renderUnsubscribePage(c, http.StatusOK, unsubscribePageData{Token: token, Type: notificationType})
+}
+
+// @Summary Unsubscribes from the notification type of the unsubscribe link of an email
+// @Description Works without login, the token is signed. Used by the confirmation page and by
+// @Description mail clients for one-click unsubscribing (RFC 8058). Browsers get a page
+// @Tags UserSettings
+// @Produce json,html
+// @Param token query string true "Token of the unsubscribe link"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/email/unsubscribe [post]
+func (ec *EmailSettingsController) Unsubscribe(c *gin.Context) {
+	// mail clients send no Accept header and get JSON
+	wantsPage := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+
+	userID, notificationType, err := ec.tokens.Verify(c.Query("token"))
+	if err != nil {
+		if wantsPage {
+			renderUnsubscribePage(c, http.StatusBadRequest, unsubscribePageData{Error: "This unsubscribe link is invalid."})
+			return
+		}
+		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := ec.repo.Unsubscribe(userID, notificationType); err != nil {
+		SetError(c, err)
+		return
+	}
+	if wantsPage {
+		renderUnsubscribePage(c, http.StatusOK, unsubscribePageData{Type: notificationType, Done: true})
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: fmt.Sprintf("You won't receive %s emails anymore", notificationType)})
+}
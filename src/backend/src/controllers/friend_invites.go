@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// characters which can't be mixed up when typing a code from a screenshot
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+
+	defaultInviteLifetime = 7 * 24 * time.Hour
+	maxInviteLifetime     = 30 * 24 * time.Hour
+)
+
+// PostFriendInviteRequest is the request sent when doing [post] /friends/invites
+// swagger:model PostFriendInviteRequest
+type PostFriendInviteRequest struct {
+	// how long the invite is valid, default is 168 hours (one week), maximum 720 hours
+	ExpiresInHours int `json:"expires_in_hours,omitempty" binding:"gte=0" example:"24"`
+	// how often the invite can be redeemed. 0 or missing means unlimited
+	MaxUses int `json:"max_uses,omitempty" binding:"gte=0" example:"10"`
+}
+
+// FriendInviteReply is the reply containing a single invite
+// swagger:model FriendInviteReply
+type FriendInviteReply struct {
+	Data FriendInvite `json:"data"`
+}
+
+// GetFriendInvitesReply is the reply sent when doing [get] /friends/invites
+// swagger:model GetFriendInvitesReply
+type GetFriendInvitesReply struct {
+	Data []FriendInvite `json:"data"`
+}
+
+// RedeemFriendInviteReply is the reply sent when redeeming an invite
+// swagger:model RedeemFriendInviteReply
+type RedeemFriendInviteReply struct {
+	Message string     `json:"message"`
+	Friend  PublicUser `json:"friend"`
+}
+
+// FriendInvitesController manages shareable invite codes, which create accepted friendships
+type FriendInvitesController struct {
+	repo          FriendInviteRepository
+	userRepo      db.UserRepository
+	notifications db.INotificationService
+	Now           func() time.Time
+}
+
+func NewFriendInvitesController(
+	inviteRepo FriendInviteRepository,
+	userRepo db.UserRepository,
+	notifications db.INotificationService,
+	Now func() time.Time,
+) *FriendInvitesController {
+	return &FriendInvitesController{
+		repo:          inviteRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+		Now:           Now,
+	}
+}
+
+// generates a random invite code
+func generateInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = inviteCodeAlphabet[int(b[i])%len(inviteCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// @Summary Creates an invite code, which makes everyone redeeming it a friend of the logged in user
+// @Tags friends
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PostFriendInviteRequest false "Lifetime and maximum uses of the invite"
+// @Success 200 {object} FriendInviteReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/friends/invites [post]
+func (fc *FriendInvitesController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	// the body is optional
+	var req PostFriendInviteRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+			return
+		}
+	}
+
+	lifetime := defaultInviteLifetime
+	if req.ExpiresInHours > 0 {
+		lifetime = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+	if lifetime > maxInviteLifetime {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invites can be valid for at most %v hours", maxInviteLifetime.Hours()))
+		return
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	now := fc.Now().UTC()
+	invite, err := fc.repo.Create(&FriendInvite{
+		Code:      code,
+		CreatorID: user.ID,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, FriendInviteReply{Data: *invite})
+}
+
+// @Summary Lists all invites created by the logged in user
+// @Tags friends
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetFriendInvitesReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/friends/invites [get]
+func (fc *FriendInvitesController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	invites, err := fc.repo.FetchByCreator(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetFriendInvitesReply{Data: invites})
+}
+
+// @Summary Revokes an invite of the logged in user
+// @Tags friends
+// @Produce json
+// @Security CookieAuth
+// @Param code path string true "Invite code"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/friends/invites/{code} [delete]
+func (fc *FriendInvitesController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	code := strings.ToUpper(c.Param("code"))
+	if err := fc.repo.Revoke(code, user.ID, fc.Now().UTC()); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Invite revoked successfully"})
+}
+
+// @Summary Redeems an invite, which makes the logged in user an accepted friend of the invite creator
+// @Tags friends
+// @Produce json
+// @Security CookieAuth
+// @Param code path string true "Invite code"
+// @Success 200 {object} RedeemFriendInviteReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 410 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/friends/invites/{code}/redeem [post]
+func (fc *FriendInvitesController) Redeem(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	code := strings.ToUpper(c.Param("code"))
+	invite, err := fc.repo.FetchByCode(code)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if invite.CreatorID == user.ID {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("you can't redeem your own invite"))
+		return
+	}
+
+	// blocked users and existing friends don't use up the invite
+	invite, created, err := fc.repo.Redeem(code, user.ID, fc.Now().UTC())
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if created {
+		notify(fc.notifications, invite.CreatorID, NotificationInviteRedeemed, user.ID, invite.ID)
+	}
+
+	creator, err := fc.userRepo.GetUserByID(invite.CreatorID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RedeemFriendInviteReply{
+		Message: "Friendship created successfully",
+		Friend:  creator.Public(),
+	})
+}
@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -17,8 +22,11 @@ type MessageResponse struct {
 
 // FriendsController manages friendship endpoints.
 type FriendsController struct {
-	repo     db.FriendshipRepository
-	userRepo db.UserRepository
+	repo          db.FriendshipRepository
+	userRepo      db.UserRepository
+	sportRepo     db.SportRepository
+	visibility    db.IVisibilityService
+	notifications db.INotificationService
 }
 
 // Reply for GET /api/friends
@@ -29,8 +37,20 @@ type GetFriendshipReply struct {
 }
 
 // NewFriendsController initializes a new FriendsController.
-func NewFriendsController(userRepo db.UserRepository, friendshipRepo db.FriendshipRepository) *FriendsController {
-	return &FriendsController{repo: friendshipRepo, userRepo: userRepo}
+func NewFriendsController(
+	userRepo db.UserRepository,
+	friendshipRepo db.FriendshipRepository,
+	sportRepo db.SportRepository,
+	visibility db.IVisibilityService,
+	notifications db.INotificationService,
+) *FriendsController {
+	return &FriendsController{
+		repo:          friendshipRepo,
+		userRepo:      userRepo,
+		sportRepo:     sportRepo,
+		visibility:    visibility,
+		notifications: notifications,
+	}
 }
 
 // FriendRequest is the expected payload when creating a friendship.
@@ -46,7 +66,17 @@ type UpdateFriendshipRequest struct {
 	Status       FriendshipStatus `json:"status" binding:"required"`
 }
 
+// Reply for GET /api/friends/suggestions
+//
+//swagger:model GetFriendSuggestionsReply
+type GetFriendSuggestionsReply struct {
+	Data []FriendSuggestion `json:"data"`
+}
+
 type FriendshipReply struct {
+	// every friendship with the other user embedded
+	Friends []FriendEntry `json:"friends"`
+	// Friendships and Users contain the same data as Friends, in the same order
 	Friendships []Friendships `json:"friendships"`
 	Users       []User        `json:"users"`
 }
@@ -58,47 +88,106 @@ type FriendshipReply struct {
 // @Accept	json
 // @Producte json
 // @Security CookieAuth
+// @Param status query string false "Only friendships with this status (pending, accepted, blocked)"
+// @Param direction query string false "Only incoming or outgoing friendships"
 // @Success 200 {object} GetFriendshipReply
 // @Failure 400 {object} ErrorReply
 // @Failure 502 {object} ErrorReply
 // @Router /api/friends [get]
 func (fc *FriendsController) GetFriends(c *gin.Context) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
+	user := middleware.CurrentUser(c)
+
+	filter := FriendshipFilter{
+		Status:    FriendshipStatus(c.Query("status")),
+		Direction: FriendshipDirection(c.Query("direction")),
+	}
+	switch filter.Status {
+	case "", Pending, Accepted, Blocked:
+	default:
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid status %q", filter.Status))
+		return
+	}
+	switch filter.Direction {
+	case "", Incoming, Outgoing:
+	default:
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid direction %q", filter.Direction))
 		return
 	}
 
-	friendships, err := fc.repo.GetFriendships(user.ID)
+	entries, err := fc.repo.GetFriendEntries(user.ID, filter)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
+		return
+	}
+	if err := fc.visibility.HideInvisible(user.ID, entries); err != nil {
+		SetError(c, err)
 		return
 	}
 
-	var reply FriendshipReply
-	reply.Friendships = friendships
-	reply.Users = make([]User, 0)
-	for _, friendship := range friendships {
-		var friendID Snowflake
-		// If the logged-in user is the requester, then the other user is the friend.
-		if friendship.RequesterID == user.ID {
-			friendID = friendship.RecipientID
-		} else {
-			friendID = friendship.RequesterID
+	// streaks of all friends, which allow it, are calculated at once
+	streakUserIDs := make([]Snowflake, 0, len(entries))
+	for _, entry := range entries {
+		if entry.StreakVisible {
+			streakUserIDs = append(streakUserIDs, entry.Friend.ID)
 		}
+	}
+	streaks, err := fc.sportRepo.GetCurrentStreaks(streakUserIDs)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
 
-		friendData, err := fc.userRepo.GetUserByID(friendID)
-		if err != nil {
-			// Skip friendship if unable to fetch friend details.
-			continue
+	reply := FriendshipReply{
+		Friends:     entries,
+		Friendships: make([]Friendships, 0, len(entries)),
+		Users:       make([]User, 0, len(entries)),
+	}
+	for i := range reply.Friends {
+		entry := &reply.Friends[i]
+		if streak, ok := streaks[entry.Friend.ID]; ok {
+			entry.CurrentStreak = &streak
 		}
-
-		reply.Users = append(reply.Users, *friendData)
+		reply.Friendships = append(reply.Friendships, entry.Friendship)
+		reply.Users = append(reply.Users, User{
+			ID:            entry.Friend.ID,
+			Username:      entry.Friend.Username,
+			Discriminator: entry.Discriminator,
+			Avatar:        entry.Friend.Avatar,
+		})
 	}
 
 	c.JSON(http.StatusOK, GetFriendshipReply{Data: reply})
 }
 
+// GetSuggestions
+// @Summary returns users the logged-in user might know, ranked by mutual friends and shared games.
+// @Tags 	friends
+// @Produce json
+// @Security CookieAuth
+// @Param limit query int false "Maximum number of suggestions, default is 10, maximum 50"
+// @Success 200 {object} GetFriendSuggestionsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/friends/suggestions [get]
+func (fc *FriendsController) GetSuggestions(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid limit value: %v", c.Query("limit")))
+		return
+	}
+	limit = min(limit, 50)
+
+	suggestions, err := fc.repo.GetSuggestions(user.ID, limit)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetFriendSuggestionsReply{Data: suggestions})
+}
+
 // Format:
 // @Param  <name>  body  <Type>  <required?>  "<description>"
 
@@ -110,15 +199,11 @@ func (fc *FriendsController) GetFriends(c *gin.Context) {
 // @Success 200 {object} MessageResponse
 // @Router /friends [post]
 func (fc *FriendsController) PostFriendship(c *gin.Context) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	var req FriendRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 		return
 	}
 
@@ -129,57 +214,62 @@ func (fc *FriendsController) PostFriendship(c *gin.Context) {
 
 	// Decline Accepted as status
 	if req.Status == Accepted {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You can't establish a friendship by just saying, you accept it"})
+		SetGinError(c, http.StatusForbidden, errors.New("You can't establish a friendship by just saying, you accept it"))
 		return
 	}
 
 	// The logged-in user's id is used as UserId1.
 	// the recipient is always the second param
-	if err := fc.repo.CreateFriendship(user.ID, req.FriendID, req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	friendship, err := fc.repo.CreateFriendship(user.ID, req.FriendID, req.Status)
+	if err != nil {
+		SetError(c, err)
 		return
 	}
+	// existing and blocked friendships are rejected by the repository, hence only new requests notify
+	switch friendship.Status {
+	case Pending:
+		notify(fc.notifications, req.FriendID, NotificationFriendRequest, user.ID, friendship.ID)
+	case Accepted:
+		// the friend had sent a request to the user before
+		notify(fc.notifications, req.FriendID, NotificationFriendAccepted, user.ID, friendship.ID)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Friendship created successfully"})
 }
 
 // UpdateFriendship updates an existing friendship's status.
 func (fc *FriendsController) UpdateFriendship(c *gin.Context) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	var req UpdateFriendshipRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 		return
 	}
 
-	if err := fc.repo.UpdateFriendship(req.FriendshipID, user.ID, req.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	friendship, changed, err := fc.repo.UpdateFriendship(req.FriendshipID, user.ID, req.Status)
+	if err != nil {
+		SetError(c, err)
 		return
 	}
+	// accepting a friendship twice doesn't notify the requester again
+	if changed && friendship.Status == Accepted {
+		notify(fc.notifications, friendship.RequesterID, NotificationFriendAccepted, user.ID, friendship.ID)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Friendship updated successfully"})
 }
 
 // DeleteFriendship removes a friendship record.
 func (fc *FriendsController) DeleteFriendship(c *gin.Context) {
-	_, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
 
 	idStr := c.Param("id")
 	friendshipID, err := NewSnowflakeFromString(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid friendship ID"})
+		SetGinError(c, http.StatusBadRequest, errors.New("Invalid friendship ID"))
 		return
 	}
 
 	if err := fc.repo.DeleteFriendship(Snowflake(friendshipID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Friendship deleted successfully"})
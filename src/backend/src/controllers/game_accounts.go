@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/connectors"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGameAccountsReply is the reply sent when doing [get] /game-accounts
+// swagger:model GetGameAccountsReply
+type GetGameAccountsReply struct {
+	Data []GameAccount `json:"data"`
+	// names of the connectors, which can be linked
+	Connectors []string `json:"connectors" example:"riot"`
+}
+
+// PostGameAccountRequest is the request sent when doing [post] /game-accounts
+// swagger:model PostGameAccountRequest
+type PostGameAccountRequest struct {
+	Connector string `json:"connector" binding:"required" example:"riot"`
+	// name of the account at the connector, e.g. the Riot ID
+	Account string `json:"account" binding:"required" example:"Faker#KR1"`
+}
+
+// PostGameAccountReply is the reply sent when doing [post] /game-accounts
+// swagger:model PostGameAccountReply
+type PostGameAccountReply struct {
+	Data GameAccount `json:"data"`
+}
+
+// GameAccountsController links accounts of game-stats connectors, whose matches are turned into overdue deaths
+type GameAccountsController struct {
+	repo       GameAccountRepository
+	connectors map[string]connectors.Connector
+	Now        func() time.Time
+}
+
+func NewGameAccountsController(
+	gameAccountRepo GameAccountRepository,
+	gameConnectors []connectors.Connector,
+	Now func() time.Time,
+) *GameAccountsController {
+	controller := &GameAccountsController{
+		repo:       gameAccountRepo,
+		connectors: map[string]connectors.Connector{},
+		Now:        Now,
+	}
+	for _, connector := range gameConnectors {
+		controller.connectors[connector.Name()] = connector
+	}
+	return controller
+}
+
+// @Summary Get the game accounts linked by the logged in user
+// @Tags game-accounts
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetGameAccountsReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/game-accounts [get]
+func (gc *GameAccountsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	accounts, err := gc.repo.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	names := []string{}
+	for name := range gc.connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.JSON(http.StatusOK, GetGameAccountsReply{Data: accounts, Connectors: names})
+}
+
+// @Summary Links an account of a game-stats connector to the logged in user
+// @Description The deaths of matches played after linking are added to the overdue deaths.
+// @Tags game-accounts
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PostGameAccountRequest true "Connector and account name"
+// @Success 201 {object} PostGameAccountReply
+// @Failure 400 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 409 {object} ErrorReply
+// @Failure 503 {object} ErrorReply
+// @Router /api/game-accounts [post]
+func (gc *GameAccountsController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var req PostGameAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	connector, ok := gc.connectors[req.Connector]
+	if !ok {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("unknown connector: %s", req.Connector))
+		return
+	}
+
+	account, err := connector.ResolveAccount(c.Request.Context(), req.Account)
+	var rateLimit *connectors.RateLimitError
+	if errors.As(err, &rateLimit) {
+		SetGinError(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	linked, err := gc.repo.Create(&GameAccount{
+		UserID:      user.ID,
+		Connector:   connector.Name(),
+		ExternalID:  account.ExternalID,
+		DisplayName: account.DisplayName,
+		CreatedAt:   gc.Now().UTC(),
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, PostGameAccountReply{Data: *linked})
+}
+
+// @Summary Unlinks a game account of the logged in user
+// @Tags game-accounts
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "ID of the game account"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Router /api/game-accounts/{id} [delete]
+func (gc *GameAccountsController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := NewSnowflakeFromString(c.Param("id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid game account ID: %s", c.Param("id")))
+		return
+	}
+	if err := gc.repo.Delete(id, user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Game account unlinked successfully"})
+}
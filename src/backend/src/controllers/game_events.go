@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/events"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// random bytes of a signing secret, encoded to 43 characters
+	eventSecretBytes = 32
+	// characters of the secret sent with its metadata to tell secrets apart
+	eventSecretPrefixLength = len(EventSecretPrefix) + 4
+	// maximum size of an event body
+	maxEventBodyBytes = 16 << 10
+	defaultEventLimit = 50
+	maxEventLimit     = 200
+
+	// header containing sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+	SignatureHeader = "X-Signature-256"
+	// header containing the unix timestamp in seconds, when the event was signed
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+)
+
+// GetEventSecretReply is the reply sent when doing [get] /events/secret
+// swagger:model GetEventSecretReply
+type GetEventSecretReply struct {
+	// null, if no secret was created
+	Data *EventSigningSecret `json:"data"`
+}
+
+// PostEventSecretReply contains the created signing secret. The secret itself is only sent once
+// swagger:model PostEventSecretReply
+type PostEventSecretReply struct {
+	Secret string             `json:"secret" example:"gths_3Kd9..."`
+	Data   EventSigningSecret `json:"data"`
+}
+
+// GetGameEventsReply is the reply sent when doing [get] /events
+// swagger:model GetGameEventsReply
+type GetGameEventsReply struct {
+	Data []GameEvent `json:"data"`
+}
+
+// PostGameEventReply is the reply sent when doing [post] /user/{user_id}/events
+// swagger:model PostGameEventReply
+type PostGameEventReply struct {
+	Data GameEvent `json:"data"`
+	// true, if an event with the same ID was received before. The event was not applied again
+	Duplicate bool `json:"duplicate"`
+}
+
+// GameEventsController receives signed events of games and tools like OBS plugins and manages
+// the signing secret of the logged in user
+type GameEventsController struct {
+	repo      GameEventRepository
+	processor *events.Processor
+	Now       func() time.Time
+}
+
+func NewGameEventsController(repo GameEventRepository, processor *events.Processor, Now func() time.Time) *GameEventsController {
+	return &GameEventsController{repo: repo, processor: processor, Now: Now}
+}
+
+// generates a random signing secret with the `gths_` prefix
+func generateEventSecret() (string, error) {
+	b := make([]byte, eventSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return EventSecretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// @Summary Get the metadata of the event signing secret of the logged in user
+// @Tags events
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetEventSecretReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/events/secret [get]
+func (ec *GameEventsController) GetSecret(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	secret, err := ec.repo.FetchSecret(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetEventSecretReply{Data: secret})
+}
+
+// @Summary Creates or rotates the secret, which signs the game events of the logged in user
+// @Description The previous secret stops working immediately.
+// @Tags events
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} PostEventSecretReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/events/secret [post]
+func (ec *GameEventsController) PostSecret(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	value, err := generateEventSecret()
+	if err != nil {
+		SetGinError(c, http.StatusInternalServerError, err)
+		return
+	}
+	secret, err := ec.repo.SaveSecret(&EventSigningSecret{
+		UserID:    user.ID,
+		Secret:    value,
+		Prefix:    value[:eventSecretPrefixLength],
+		CreatedAt: ec.Now().UTC(),
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PostEventSecretReply{Secret: value, Data: *secret})
+}
+
+// @Summary Deletes the event signing secret of the logged in user
+// @Tags events
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} MessageResponse
+// @Failure 404 {object} ErrorReply
+// @Router /api/events/secret [delete]
+func (ec *GameEventsController) DeleteSecret(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if err := ec.repo.DeleteSecret(user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Event signing secret deleted successfully"})
+}
+
+// @Summary Get the received game events of the logged in user, newest first
+// @Tags events
+// @Produce json
+// @Security CookieAuth
+// @Param limit query int false "Maximum number of events, default 50, maximum 200"
+// @Success 200 {object} GetGameEventsReply
+// @Failure 400 {object} ErrorReply
+// @Router /api/events [get]
+func (ec *GameEventsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	limit := defaultEventLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxEventLimit {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("limit has to be between 1 and %d", maxEventLimit))
+			return
+		}
+		limit = parsed
+	}
+	gameEvents, err := ec.repo.FetchByUserID(user.ID, limit)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetGameEventsReply{Data: gameEvents})
+}
+
+// @Summary Receives a game event like a death, signed with the event secret of the user
+// @Description The body is signed with HMAC-SHA256 over "<timestamp>.<body>" and sent as
+// @Description `X-Signature-256: sha256=<hex>` with the unix timestamp in `X-Signature-Timestamp`.
+// @Description Signatures older than 5 minutes are rejected. Events with an ID received before are ignored.
+// @Description Invalid events and events, which can't be applied, are stored with the status rejected.
+// @Tags events
+// @Accept json
+// @Produce json
+// @Param user_id path string true "ID of the user"
+// @Param X-Signature-256 header string true "sha256=<hex HMAC>"
+// @Param X-Signature-Timestamp header string true "unix timestamp in seconds"
+// @Param request body events.Event true "The event"
+// @Success 200 {object} PostGameEventReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 413 {object} ErrorReply
+// @Router /api/user/{user_id}/events [post]
+func (ec *GameEventsController) Post(c *gin.Context) {
+	userID, err := NewSnowflakeFromString(c.Param("user_id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid user ID: %s", c.Param("user_id")))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxEventBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		SetGinError(c, http.StatusRequestEntityTooLarge, fmt.Errorf("events can have at most %d bytes", maxEventBodyBytes))
+		return
+	}
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+
+	err = ec.processor.Verify(userID, c.GetHeader(SignatureTimestampHeader), c.GetHeader(SignatureHeader), body)
+	if errors.Is(err, events.ErrInvalidSignature) {
+		apierror.Abort(c, apierror.Unauthorized(err.Error()))
+		return
+	}
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	event, duplicate, err := ec.processor.Ingest(userID, body)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PostGameEventReply{Data: *event, Duplicate: duplicate})
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GameSessionDetails is a game session with its linked sports and statistics
+// swagger:model GameSessionDetails
+type GameSessionDetails struct {
+	GameSession
+	Sports []Sport          `json:"sports"`
+	Stats  GameSessionStats `json:"stats"`
+}
+
+// GetGameSessionsReply is the reply sent when doing [get] /game-sessions
+// swagger:model GetGameSessionsReply
+type GetGameSessionsReply struct {
+	Data []GameSession `json:"data"`
+}
+
+// GameSessionReply is the reply sent for a single game session
+// swagger:model GameSessionReply
+type GameSessionReply struct {
+	Data GameSessionDetails `json:"data"`
+}
+
+// PostGameSessionRequest is the request sent when doing [post] /game-sessions
+// swagger:model PostGameSessionRequest
+type PostGameSessionRequest struct {
+	Game string `json:"game" binding:"required" example:"overwatch"`
+	// when the session started, defaults to now
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// PatchGameSessionRequest is the request sent when doing [patch] /game-sessions/{id}.
+// Omitted fields are not changed
+// swagger:model PatchGameSessionRequest
+type PatchGameSessionRequest struct {
+	Game    *string       `json:"game,omitempty" binding:"omitempty,min=1" example:"overwatch"`
+	Deaths  *int64        `json:"deaths,omitempty" binding:"omitempty,gte=0" example:"12"`
+	Outcome *MatchOutcome `json:"outcome,omitempty" example:"win"`
+}
+
+// CloseGameSessionRequest is the request sent when doing [post] /game-sessions/{id}/close
+// swagger:model CloseGameSessionRequest
+type CloseGameSessionRequest struct {
+	Deaths  *int64        `json:"deaths,omitempty" binding:"omitempty,gte=0" example:"37"`
+	Outcome *MatchOutcome `json:"outcome,omitempty" example:"loss"`
+	// deaths of the session, which were not worked off yet. They are added to the overdue deaths of the game.
+	// Deaths counted with the live death counter are overdue already
+	OverdueDeaths int64 `json:"overdue_deaths" binding:"gte=0" example:"5"`
+}
+
+// GameSessionsController manages the game sessions of users
+type GameSessionsController struct {
+	repo       GameSessionRepository
+	deaths     db.IDeathCounter
+	visibility db.IVisibilityService
+	Now        func() time.Time
+}
+
+func NewGameSessionsController(
+	gameSessionRepo GameSessionRepository,
+	deathCounter db.IDeathCounter,
+	visibility db.IVisibilityService,
+	Now func() time.Time,
+) *GameSessionsController {
+	return &GameSessionsController{repo: gameSessionRepo, deaths: deathCounter, visibility: visibility, Now: Now}
+}
+
+// @Summary Get the game sessions of the logged in user or the user given with <user_id>, newest first
+// @Tags game-sessions
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param user_id query string false "ID of the user, defaults to the logged in user"
+// @Param limit query int false "Maximum number of sessions, default is 50"
+// @Param offset query int false "Number of sessions to skip"
+// @Success 200 {object} GetGameSessionsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/game-sessions [get]
+func (gc *GameSessionsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	requestedUserID := user.ID
+	if idStr := c.Query("user_id"); idStr != "" {
+		var err error
+		requestedUserID, err = NewSnowflakeFromString(idStr)
+		if err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid user_id: %w", err))
+			return
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid limit value: %s", c.Query("limit")))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid offset value: %s", c.Query("offset")))
+		return
+	}
+	if !RequireVisible(c, gc.visibility, user.ID, requestedUserID, ResourceActivities) {
+		return
+	}
+
+	sessions, err := gc.repo.FetchByUserID(requestedUserID, limit, offset)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetGameSessionsReply{Data: sessions})
+}
+
+// @Summary Get a game session with its linked sports and statistics
+// @Tags game-sessions
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param id path string true "ID of the game session"
+// @Success 200 {object} GameSessionReply
+// @Failure 400 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Router /api/game-sessions/{id} [get]
+func (gc *GameSessionsController) GetByID(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := NewSnowflakeFromString(c.Param("id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid game session ID: %s", c.Param("id")))
+		return
+	}
+	session, err := gc.repo.Fetch(id)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if !RequireVisible(c, gc.visibility, user.ID, session.UserID, ResourceActivities) {
+		return
+	}
+	gc.reply(c, http.StatusOK, session)
+}
+
+// @Summary Get the live game session of the logged in user
+// @Tags game-sessions
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Success 200 {object} GameSessionReply
+// @Failure 404 {object} ErrorReply
+// @Router /api/game-sessions/live [get]
+func (gc *GameSessionsController) GetLive(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	session, err := gc.repo.FetchLive(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if session == nil {
+		SetError(c, NotFound("there is no live game session"))
+		return
+	}
+	gc.reply(c, http.StatusOK, session)
+}
+
+// @Summary Starts a live game session for the logged in user
+// @Tags game-sessions
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param request body PostGameSessionRequest true "Game and start of the session"
+// @Success 201 {object} GameSessionReply
+// @Failure 400 {object} ErrorReply
+// @Failure 409 {object} ErrorReply
+// @Router /api/game-sessions [post]
+func (gc *GameSessionsController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var req PostGameSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	now := gc.Now().UTC()
+	startedAt := now
+	if req.StartedAt != nil {
+		if req.StartedAt.After(now) {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("started_at can't be in the future"))
+			return
+		}
+		startedAt = req.StartedAt.UTC()
+	}
+
+	session, err := gc.repo.Create(&GameSession{UserID: user.ID, Game: req.Game, StartedAt: startedAt})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	gc.reply(c, http.StatusCreated, session)
+}
+
+// @Summary Updates game, deaths or outcome of a live game session of the logged in user
+// @Description Deaths can't be less than the deaths counted with the live death counter.
+// @Tags game-sessions
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param id path string true "ID of the game session"
+// @Param request body PatchGameSessionRequest true "Fields to change"
+// @Success 200 {object} GameSessionReply
+// @Failure 400 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 409 {object} ErrorReply
+// @Router /api/game-sessions/{id} [patch]
+func (gc *GameSessionsController) Patch(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := NewSnowflakeFromString(c.Param("id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid game session ID: %s", c.Param("id")))
+		return
+	}
+	var req PatchGameSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	if req.Outcome != nil && !req.Outcome.IsValid() {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid outcome %q, expected one of win, loss, draw", *req.Outcome))
+		return
+	}
+
+	if req.Deaths != nil {
+		// deaths of the live death counter belong to the session and can't be set away
+		if err := gc.deaths.FlushUser(user.ID); err != nil {
+			SetError(c, err)
+			return
+		}
+	}
+	session, ok := gc.fetchOwnLive(c, id, user.ID)
+	if !ok {
+		return
+	}
+	if req.Game != nil {
+		session.Game = *req.Game
+	}
+	if req.Deaths != nil {
+		session.Deaths = *req.Deaths
+	}
+	if req.Outcome != nil {
+		session.Outcome = *req.Outcome
+	}
+	session, err = gc.repo.Update(session)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	gc.reply(c, http.StatusOK, session)
+}
+
+// @Summary Closes a live game session of the logged in user
+// @Description Not worked off deaths given with overdue_deaths are added to the overdue deaths of the game.
+// @Tags game-sessions
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param id path string true "ID of the game session"
+// @Param request body CloseGameSessionRequest true "Final deaths and outcome of the session"
+// @Success 200 {object} GameSessionReply
+// @Failure 400 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 409 {object} ErrorReply
+// @Router /api/game-sessions/{id}/close [post]
+func (gc *GameSessionsController) Close(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := NewSnowflakeFromString(c.Param("id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid game session ID: %s", c.Param("id")))
+		return
+	}
+	var req CloseGameSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	if req.Outcome != nil && !req.Outcome.IsValid() {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid outcome %q, expected one of win, loss, draw", *req.Outcome))
+		return
+	}
+
+	// deaths of the live death counter belong to the session
+	if err := gc.deaths.FlushUser(user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	session, ok := gc.fetchOwnLive(c, id, user.ID)
+	if !ok {
+		return
+	}
+	if req.Deaths != nil {
+		session.Deaths = *req.Deaths
+	}
+	if req.Outcome != nil {
+		session.Outcome = *req.Outcome
+	}
+	if session.OverdueDeaths+req.OverdueDeaths > session.Deaths {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf(
+			"overdue_deaths can't exceed the %d deaths of the session, of which %d are overdue already",
+			session.Deaths,
+			session.OverdueDeaths,
+		))
+		return
+	}
+	if req.Deaths != nil || req.Outcome != nil {
+		if _, err := gc.repo.Update(session); err != nil {
+			SetError(c, err)
+			return
+		}
+	}
+
+	session, err = gc.repo.Close(id, user.ID, gc.Now().UTC(), req.OverdueDeaths)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	gc.reply(c, http.StatusOK, session)
+}
+
+// fetches the session <id> and sets an error, if it doesn't belong to <userID> or is closed
+func (gc *GameSessionsController) fetchOwnLive(c *gin.Context, id Snowflake, userID Snowflake) (*GameSession, bool) {
+	session, err := gc.repo.Fetch(id)
+	if err != nil {
+		SetError(c, err)
+		return nil, false
+	}
+	if session.UserID != userID {
+		// sessions of other users are treated as missing, so that their IDs are not revealed
+		SetError(c, fmt.Errorf("%w: %d", db.ErrGameSessionNotFound, id))
+		return nil, false
+	}
+	if !session.IsLive() {
+		SetError(c, db.ErrGameSessionClosed)
+		return nil, false
+	}
+	return session, true
+}
+
+// replies with <session>, its linked sports and statistics
+func (gc *GameSessionsController) reply(c *gin.Context, status int, session *GameSession) {
+	sports, err := gc.repo.FetchSports(session.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	if sports == nil {
+		sports = []Sport{}
+	}
+	c.JSON(status, GameSessionReply{Data: GameSessionDetails{
+		GameSession: *session,
+		Sports:      sports,
+		Stats:       NewGameSessionStats(session, sports, gc.Now().UTC()),
+	}})
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotificationsReply is the reply sent when doing [get] /notifications
+// swagger:model GetNotificationsReply
+type GetNotificationsReply struct {
+	Data        []Notification `json:"data"`
+	UnreadCount int64          `json:"unread_count" example:"3"`
+	// cursor of the next page. Missing, if there are no more notifications
+	NextCursor *Snowflake `json:"next_cursor,omitempty"`
+}
+
+// UnreadCountReply is the reply sent when doing [get] /notifications/unread-count
+// swagger:model UnreadCountReply
+type UnreadCountReply struct {
+	UnreadCount int64 `json:"unread_count" example:"3"`
+}
+
+// MarkAllReadReply is the reply sent when doing [post] /notifications/read-all
+// swagger:model MarkAllReadReply
+type MarkAllReadReply struct {
+	Message string `json:"message"`
+	Marked  int64  `json:"marked" example:"3"`
+}
+
+// NotificationsController manages the notification inbox of the logged in user
+type NotificationsController struct {
+	repo NotificationRepository
+	Now  func() time.Time
+}
+
+func NewNotificationsController(repo NotificationRepository, Now func() time.Time) *NotificationsController {
+	return &NotificationsController{repo: repo, Now: Now}
+}
+
+// notify informs <userID> about something the logged in user did. A failed notification
+// doesn't fail the request, which already changed the state
+func notify(
+	notifications db.INotificationService,
+	userID Snowflake,
+	notificationType NotificationType,
+	actorID Snowflake,
+	subjectID Snowflake,
+) {
+	if _, err := notifications.Notify(userID, notificationType, actorID, subjectID); err != nil {
+		log.Printf("Failed to notify user %d about %s: %v", userID, notificationType, err)
+	}
+}
+
+// @Summary Lists the notifications of the logged in user, newest first
+// @Tags notifications
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param unread query bool false "Only notifications, which were not read yet"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of notifications, default is 20, maximum 100"
+// @Success 200 {object} GetNotificationsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/notifications [get]
+func (nc *NotificationsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid unread value: %s", c.Query("unread")))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid limit value: %s", c.Query("limit")))
+		return
+	}
+	limit = min(limit, 100)
+	var cursor Snowflake
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err = NewSnowflakeFromString(cursorStr)
+		if err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid cursor: %w", err))
+			return
+		}
+	}
+
+	// one more notification is fetched to know whether there is a next page
+	notifications, err := nc.repo.FetchByUserID(user.ID, NotificationFilter{
+		UnreadOnly: unreadOnly,
+		Before:     cursor,
+		Limit:      limit + 1,
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	unread, err := nc.repo.CountUnread(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	reply := GetNotificationsReply{Data: notifications, UnreadCount: unread}
+	if len(notifications) > limit {
+		reply.Data = notifications[:limit]
+		reply.NextCursor = &reply.Data[limit-1].ID
+	}
+	c.JSON(http.StatusOK, reply)
+}
+
+// @Summary Returns the number of unread notifications of the logged in user, e.g. for a badge
+// @Tags notifications
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Success 200 {object} UnreadCountReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/notifications/unread-count [get]
+func (nc *NotificationsController) GetUnreadCount(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	unread, err := nc.repo.CountUnread(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, UnreadCountReply{UnreadCount: unread})
+}
+
+// @Summary Marks a notification of the logged in user as read
+// @Tags notifications
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "ID of the notification"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/notifications/{id}/read [post]
+func (nc *NotificationsController) MarkRead(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := NewSnowflakeFromString(c.Param("id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid notification ID: %w", err))
+		return
+	}
+	if err := nc.repo.MarkRead(user.ID, id, nc.Now().UTC()); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Notification marked as read"})
+}
+
+// @Summary Marks all notifications of the logged in user as read
+// @Tags notifications
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} MarkAllReadReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/notifications/read-all [post]
+func (nc *NotificationsController) MarkAllRead(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	marked, err := nc.repo.MarkAllRead(user.ID, nc.Now().UTC())
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MarkAllReadReply{Message: "Notifications marked as read", Marked: marked})
+}
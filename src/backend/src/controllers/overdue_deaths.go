@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -36,32 +39,44 @@ type DeleteOverdueDeathsRequest struct {
 
 // FriendsController manages friendship endpoints.
 type OverdueDeathsController struct {
-	repo OverdueDeathRepository
+	repo       OverdueDeathRepository
+	visibility db.IVisibilityService
 }
 
-func NewOverdueDeathsController(overdueDeathRepo OverdueDeathRepository) *OverdueDeathsController {
-	return &OverdueDeathsController{repo: overdueDeathRepo}
+func NewOverdueDeathsController(overdueDeathRepo OverdueDeathRepository, visibility db.IVisibilityService) *OverdueDeathsController {
+	return &OverdueDeathsController{repo: overdueDeathRepo, visibility: visibility}
 }
 
 // returns all OverdueDeaths records for the user
-// @Summary Get all OverdueDeaths records for the logged in user
+// @Summary Get all OverdueDeaths records for the logged in user or the user given with <user_id>
 // @Tags OverdueDeaths
 // @Produce json
 // @Accept json
 // @Security CoockieAuth
+// @Param user_id query string false "ID of the user, defaults to the logged in user"
 // @Success 200 {object} GetOverdueDeathsReply
 // @Failure 400 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
 // @Router /api/overdue-deaths [get]
 func (oc *OverdueDeathsController) Get(c *gin.Context) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
+	user := middleware.CurrentUser(c)
+
+	requestedUserID := user.ID
+	if idStr := c.Query("user_id"); idStr != "" {
+		var err error
+		requestedUserID, err = NewSnowflakeFromString(idStr)
+		if err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid user_id: %w", err))
+			return
+		}
+	}
+	if !RequireVisible(c, oc.visibility, user.ID, requestedUserID, ResourceOverdueDeaths) {
 		return
 	}
 
-	overdueDeaths, err := oc.repo.FetchAll(user.ID)
+	overdueDeaths, err := oc.repo.FetchAll(requestedUserID)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	reply := GetOverdueDeathsReply{
@@ -118,21 +133,17 @@ func HandleCreation(
 	c *gin.Context,
 	method OverdueDeathCountFunc,
 ) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	var req PostOverdueDeathsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid JSON format: %w", err))
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 		return
 	}
 
 	data, err := method(user.ID, req.Game, req.Count)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, PostOverdueDeathsReply{Data: *data})
@@ -149,27 +160,29 @@ func HandleCreation(
 // @Failure 500 {object} ErrorReply
 // @Router /api/overdue-deaths [delete]
 func (oc *OverdueDeathsController) Delete(c *gin.Context) {
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	var req DeleteOverdueDeathsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SetGinError(c, http.StatusBadRequest, err)
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 		return
 	}
 
-	err = oc.repo.Delete(user.ID, req.Game)
+	err := oc.repo.Delete(user.ID, req.Game)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, req)
 }
 
+// SetGinError aborts the request with <err> and <status>. The response is rendered by `middleware.ErrorHandler`
 func SetGinError(c *gin.Context, status int, err error) {
-	c.JSON(status, gin.H{"error": err.Error()})
+	apierror.Abort(c, apierror.WithStatus(status, err))
+}
 
+// SetError aborts the request with <err>. The status is derived from the error, e.g. 404
+// for repository errors wrapping `repositories.ErrNotFound`, and falls back to 500
+func SetError(c *gin.Context, err error) {
+	apierror.Abort(c, apierror.From(err))
 }
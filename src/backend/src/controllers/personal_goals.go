@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -64,15 +67,17 @@ type DeletePersonalGoalsRequest struct {
 	ID Snowflake `json:"id" binding:"required"`
 }
 
-func NewPersonalGoalsController(personalGoalsRepo PersonalGoalsRepository) *PersonalGoalsController {
+func NewPersonalGoalsController(personalGoalsRepo PersonalGoalsRepository, visibility db.IVisibilityService) *PersonalGoalsController {
 	return &PersonalGoalsController{
-		repo: personalGoalsRepo,
+		repo:       personalGoalsRepo,
+		visibility: visibility,
 	}
 }
 
 // PersonalGoalsController manages personal goals endpoints.
 type PersonalGoalsController struct {
-	repo PersonalGoalsRepository
+	repo       PersonalGoalsRepository
+	visibility db.IVisibilityService
 }
 
 // returns all PersonalGoal records for the user
@@ -82,18 +87,23 @@ type PersonalGoalsController struct {
 // @Accept json
 // @Success 200 {object} GetPersonalGoalsReply
 // @Failure 400 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
 // @Router /{user_id}/goals [get]
 func (self *PersonalGoalsController) Get(c *gin.Context) {
 	requested_user_id, err := NewSnowflakeFromString(c.Param("user_id"))
 	if err != nil {
 		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+	requesting_user := middleware.CurrentUser(c)
+	if !RequireVisible(c, self.visibility, requesting_user.ID, requested_user_id, ResourceGoals) {
+		return
 	}
-	requesting_user, status, err := UserFromSession(c)
+	goals, err := self.repo.FetchByUserID(requested_user_id)
 	if err != nil {
-		SetGinError(c, status, err)
+		SetError(c, err)
 		return
 	}
-	goals, err := self.repo.FetchByUserID(requested_user_id, requesting_user.ID)
 	reply := GetPersonalGoalsReply{
 		Data: PersonalGoalDataFromList(goals),
 	}
@@ -149,7 +159,7 @@ func (self *PersonalGoalsController) Delete(c *gin.Context) {
 	// checks will be done, if the user is allowed to delete the goal.
 	var req DeletePersonalGoalsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SetGinError(c, http.StatusBadRequest, err)
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 		return
 	}
 	// user id gets added later
@@ -175,11 +185,7 @@ func HandlePersonalGoalsModification(
 		return
 	}
 
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	if user.ID != requested_user_id {
 		SetGinError(c, http.StatusForbidden, fmt.Errorf("Cannot modify another user's personal goals"))
@@ -189,7 +195,7 @@ func HandlePersonalGoalsModification(
 	if goal == nil {
 		var req PostPersonalGoalsRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			SetGinError(c, http.StatusBadRequest, err)
+			SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
 			return
 		}
 		goal = &PersonalGoal{
@@ -205,7 +211,7 @@ func HandlePersonalGoalsModification(
 
 	createdGoal, err := method(goal)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	reply := GetPersonalGoalsReply{
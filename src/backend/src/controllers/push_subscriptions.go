@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/webpush"
+	"github.com/gin-gonic/gin"
+)
+
+// PushPublicKey contains the key, which the frontend passes to pushManager.subscribe()
+// swagger:model PushPublicKey
+type PushPublicKey struct {
+	// base64url encoded VAPID public key, empty when push notifications are disabled
+	PublicKey string `json:"public_key" example:"BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"`
+	// whether the server is configured to send push notifications
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// GetPushPublicKeyReply is the reply sent when doing [get] /push/public-key
+// swagger:model GetPushPublicKeyReply
+type GetPushPublicKeyReply struct {
+	Data PushPublicKey `json:"data"`
+}
+
+// PushSubscriptionKeys are the keys of a PushSubscription of the browser
+// swagger:model PushSubscriptionKeys
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh" binding:"required" example:"BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"`
+	Auth   string `json:"auth" binding:"required" example:"BTBZMqHH6r4Tts7J_aSIgg"`
+}
+
+// PostPushSubscriptionRequest is the request sent when doing [post] /push/subscriptions.
+// It's the JSON of the PushSubscription of the browser with an optional device name
+// swagger:model PostPushSubscriptionRequest
+type PostPushSubscriptionRequest struct {
+	Endpoint string               `json:"endpoint" binding:"required,max=1024" example:"https://fcm.googleapis.com/fcm/send/dXp..."`
+	Keys     PushSubscriptionKeys `json:"keys" binding:"required"`
+	Device   string               `json:"device" binding:"max=64" example:"Firefox on Android"`
+}
+
+// PostPushSubscriptionReply is the reply sent when doing [post] /push/subscriptions
+// swagger:model PostPushSubscriptionReply
+type PostPushSubscriptionReply struct {
+	Data PushSubscription `json:"data"`
+}
+
+// GetPushSubscriptionsReply is the reply sent when doing [get] /push/subscriptions
+// swagger:model GetPushSubscriptionsReply
+type GetPushSubscriptionsReply struct {
+	Data []PushSubscription `json:"data"`
+}
+
+// PushSubscriptionsController manages the browsers, in which the logged in user receives
+// push notifications
+type PushSubscriptionsController struct {
+	repo PushSubscriptionRepository
+	// nil, when push notifications are disabled
+	keys *webpush.VAPIDKeys
+	Now  func() time.Time
+}
+
+func NewPushSubscriptionsController(
+	repo PushSubscriptionRepository,
+	keys *webpush.VAPIDKeys,
+	Now func() time.Time,
+) *PushSubscriptionsController {
+	return &PushSubscriptionsController{repo: repo, keys: keys, Now: Now}
+}
+
+// @Summary Returns the VAPID public key, which browsers subscribe with
+// @Tags push
+// @Produce json
+// @Success 200 {object} GetPushPublicKeyReply
+// @Router /api/push/public-key [get]
+func (pc *PushSubscriptionsController) GetPublicKey(c *gin.Context) {
+	key := PushPublicKey{}
+	if pc.keys != nil {
+		key = PushPublicKey{PublicKey: pc.keys.PublicKey, Enabled: true}
+	}
+	c.JSON(http.StatusOK, GetPushPublicKeyReply{Data: key})
+}
+
+// @Summary Lists the browsers, in which the logged in user receives push notifications
+// @Tags push
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetPushSubscriptionsReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/push/subscriptions [get]
+func (pc *PushSubscriptionsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	subscriptions, err := pc.repo.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetPushSubscriptionsReply{Data: subscriptions})
+}
+
+// @Summary Registers the push subscription of a browser for the logged in user
+// @Description Subscribing again with the same endpoint replaces the subscription
+// @Description Only endpoints of the push services of Chrome, Firefox, Safari and Edge are accepted
+// @Tags push
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PostPushSubscriptionRequest true "PushSubscription of the browser"
+// @Success 200 {object} PostPushSubscriptionReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Failure 503 {object} ErrorReply
+// @Router /api/push/subscriptions [post]
+func (pc *PushSubscriptionsController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	if pc.keys == nil {
+		SetGinError(c, http.StatusServiceUnavailable, errors.New("push notifications are not configured"))
+		return
+	}
+	var req PostPushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+	if err := webpush.ValidateEndpoint(req.Endpoint); err != nil {
+		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := webpush.ValidateKeys(req.Keys.P256dh, req.Keys.Auth); err != nil {
+		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription, err := pc.repo.Save(&PushSubscription{
+		UserID:    user.ID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		Device:    req.Device,
+		CreatedAt: pc.Now().UTC(),
+	})
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, PostPushSubscriptionReply{Data: *subscription})
+}
+
+// @Summary Unregisters a browser of the logged in user from push notifications
+// @Tags push
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/push/subscriptions/{id} [delete]
+func (pc *PushSubscriptionsController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid subscription ID: %s", c.Param("id")))
+		return
+	}
+
+	if err := pc.repo.Delete(Snowflake(id), user.ID); err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Push subscription deleted successfully"})
+}
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionReply is a login session of the logged in user
+// swagger:model SessionReply
+type SessionReply struct {
+	Session
+	// browser and operating system derived from the user agent
+	Device string `json:"device" example:"Firefox on Windows"`
+	// whether or not this is the session of the request
+	Current bool `json:"current"`
+}
+
+// GetSessionsReply is the reply sent when doing [get] /sessions
+// swagger:model GetSessionsReply
+type GetSessionsReply struct {
+	Data []SessionReply `json:"data"`
+}
+
+// SessionsController lists and revokes the login sessions of the logged in user
+type SessionsController struct {
+	repo SessionRepository
+}
+
+func NewSessionsController(repo SessionRepository) *SessionsController {
+	return &SessionsController{repo: repo}
+}
+
+// @Summary Lists the active login sessions of the logged in user
+// @Tags sessions
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetSessionsReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/sessions [get]
+func (sc *SessionsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	userSessions, err := sc.repo.FetchByUserID(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	currentID := sessions.Default(c).ID()
+	reply := GetSessionsReply{Data: make([]SessionReply, 0, len(userSessions))}
+	for _, session := range userSessions {
+		reply.Data = append(reply.Data, SessionReply{
+			Session: session,
+			Device:  describeUserAgent(session.UserAgent),
+			Current: session.ID == currentID,
+		})
+	}
+	c.JSON(http.StatusOK, reply)
+}
+
+// @Summary Revokes a login session of the logged in user, which logs out the device
+// @Tags sessions
+// @Produce json
+// @Security CookieAuth
+// @Param id path string true "Public ID of the session"
+// @Success 200 {object} MessageResponse
+// @Failure 401 {object} ErrorReply
+// @Failure 404 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/sessions/{id} [delete]
+func (sc *SessionsController) Delete(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	err := sc.repo.DeleteByPublicID(c.Param("id"), user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, MessageResponse{Message: "Session revoked successfully"})
+}
+
+// returns a short description like "Firefox on Windows" of a user agent
+func describeUserAgent(userAgent string) string {
+	browser := "Unknown browser"
+	// the order matters, since e.g. Edge also contains Chrome and Safari
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	os := "unknown OS"
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			os = candidate.name
+			break
+		}
+	}
+	return browser + " on " + os
+}
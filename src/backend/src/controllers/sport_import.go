@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/importer"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// maximum size of an import file
+const maxImportSize = 10 << 20
+
+// ImportSportsReply is the reply sent when doing [post] /sports/import
+// swagger:model ImportSportsReply
+type ImportSportsReply struct {
+	Data importer.Report `json:"data"`
+}
+
+// SportImportController imports historical workouts from CSV and JSON files
+type SportImportController struct {
+	importer *importer.Importer
+}
+
+func NewSportImportController(sportImporter *importer.Importer) *SportImportController {
+	return &SportImportController{importer: sportImporter}
+}
+
+// @Summary Imports workouts from a CSV or JSON file with the columns date, sport, amount and game
+// @Description The file is sent as body or as multipart form field `file`. Sport names are mapped
+// @Description through an alias table and exact duplicates are skipped.
+// @Tags sport
+// @Accept text/csv,application/json,multipart/form-data
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param format query string false "csv or json. Defaults to the content type or the file extension"
+// @Param dry_run query bool false "Only report what would be imported"
+// @Success 200 {object} ImportSportsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/sports/import [post]
+func (ic *SportImportController) Post(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid dry_run value: %s", c.Query("dry_run")))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var body io.Reader = c.Request.Body
+	formatName := c.Query("format")
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("missing file: %w", err))
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			SetError(c, err)
+			return
+		}
+		defer file.Close()
+		body = file
+		if formatName == "" {
+			formatName = fileHeader.Filename
+		}
+	}
+	if formatName == "" {
+		formatName = c.ContentType()
+	}
+	format, err := importer.ParseFormat(formatName)
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := ic.importer.Import(user.ID, body, format, dryRun)
+	var fileErr *importer.FileError
+	if errors.As(err, &fileErr) {
+		SetGinError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ImportSportsReply{Data: *report})
+}
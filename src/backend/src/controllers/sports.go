@@ -2,14 +2,18 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/config"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -41,6 +45,8 @@ type DeleteSportsReply struct {
 // swagger:parameters GetSport
 type GetSportReply struct {
 	Data []models.Sport `json:"data"`
+	// IDs of requested users, whose activities are not visible for the logged in user
+	ForbiddenUserIDs []models.Snowflake `json:"forbidden_user_ids,omitempty"`
 }
 
 // swagger:response GetSportTotal
@@ -59,19 +65,30 @@ type GetSportsRequestQuery struct {
 }
 
 // swagger:response ErrorReply
-// ErrorReply is the response structure for errors.
+// ErrorReply is the response structure for errors. It's rendered from `apierror.Error`
 type ErrorReply struct {
-	Error string `json:"error"`
+	Error string `json:"error" example:"sport not found"`
+	// stable, machine-readable error code like not_found or invalid_body
+	Code apierror.Code `json:"code" example:"not_found"`
+	// additional information like the invalid fields of the request body
+	Details any `json:"details,omitempty"`
 }
 
 type SportsController struct {
-	repo db.SportRepository
+	repo         db.SportRepository
+	gameSessions repositories.GameSessionRepository
+	visibility   db.IVisibilityService
 }
 
 // NewSportsController creates a new auth controller
 // and initializes the gorm repository.
-func NewSportsController(SportsRepo db.SportRepository, Now func() time.Time) *SportsController {
-	return &SportsController{repo: SportsRepo}
+func NewSportsController(
+	SportsRepo db.SportRepository,
+	gameSessions repositories.GameSessionRepository,
+	visibility db.IVisibilityService,
+	Now func() time.Time,
+) *SportsController {
+	return &SportsController{repo: SportsRepo, gameSessions: gameSessions, visibility: visibility}
 }
 
 // Default returns a list of Sports structs based on the default CSV.
@@ -113,15 +130,12 @@ func csvToMap(csv [][]string) map[string]float64 {
 // @Param limit query int false "Limit the number of results returned, default is 50"
 // @Success 200 {object} GetSportReply
 // @Failure 400 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
 // @Failure 500 {object} ErrorReply
 // @Router /api/sports [get]
 func (sc *SportsController) GetSports(c *gin.Context) {
 	// Read user_id from query, defaulting to 0 if not provided.
-	_, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	var req GetSportsRequestQuery
 
@@ -153,13 +167,19 @@ func (sc *SportsController) GetSports(c *gin.Context) {
 	}
 	req.Offset = offset
 
-	sports, err := sc.repo.GetSports(req.UserIDs.IDs, req.Limit, req.Offset)
+	// only users, which allow the logged in user to see their activities
+	userIDs, forbidden, ok := FilterRequestedUsers(c, sc.visibility, user.ID, req.UserIDs.IDs, models.ResourceActivities)
+	if !ok {
+		return
+	}
+
+	sports, err := sc.repo.GetSports(userIDs, req.Limit, req.Offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		SetError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, GetSportReply{Data: sports})
+	c.JSON(http.StatusOK, GetSportReply{Data: sports, ForbiddenUserIDs: forbidden})
 }
 
 // PostSport godoc
@@ -174,15 +194,11 @@ func (sc *SportsController) GetSports(c *gin.Context) {
 // @Router /api/sports/total [get]
 func (sc *SportsController) GetTotalResults(c *gin.Context) {
 	// Check if user is logged in via Discord
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	amount, err := sc.repo.GetTotalAmounts(user.ID)
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, GetSportTotalReply{Results: amount})
@@ -201,19 +217,16 @@ func (sc *SportsController) GetTotalResults(c *gin.Context) {
 // @Router /api/sports [patch]
 func (sc *SportsController) Patch(c *gin.Context) {
 	// Check if user is logged in via Discord
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// read body
 	var req PatchSportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid JSON format: %w", err))
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
 	}
 
-	err = sc.repo.PatchSport(models.Sport{
+	err := sc.repo.PatchSport(models.Sport{
 		ID:     req.ID,
 		Kind:   req.Kind,
 		Game:   req.Game,
@@ -222,11 +235,10 @@ func (sc *SportsController) Patch(c *gin.Context) {
 	})
 
 	if err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, fmt.Sprintf("Sport entry %d updated successfully", req.ID))
-
+	c.JSON(http.StatusOK, PatchSportReply{Message: fmt.Sprintf("Sport entry %d updated successfully", req.ID)})
 }
 
 // PostSport godoc
@@ -242,16 +254,12 @@ func (sc *SportsController) Patch(c *gin.Context) {
 // @Router /api/sports [post]
 func (sc *SportsController) PostSport(c *gin.Context) {
 	// Check if user is logged in via Discord
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// Read raw request body
 	body, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read request body"})
+		SetGinError(c, http.StatusBadRequest, errors.New("Unable to read request body"))
 		return
 	}
 
@@ -261,19 +269,26 @@ func (sc *SportsController) PostSport(c *gin.Context) {
 	if strings.HasPrefix(trimmed, "[") {
 		// Payload is an array of SportInput
 		if err := json.Unmarshal(body, &inputs); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			SetGinError(c, http.StatusBadRequest, err)
 			return
 		}
 	} else {
 		// Payload is a single SportInput
 		var input models.PostSportRequest
 		if err := json.Unmarshal(body, &input); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			SetGinError(c, http.StatusBadRequest, err)
 			return
 		}
 		inputs = append(inputs, input)
 	}
 
+	// sports of the game played in the live session are linked to it
+	liveSession, err := sc.gameSessions.FetchLive(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
 	// Override UserID from the session for each sport
 	// TODO: check, if timedate is allowed in overdue request table
 	for _, input := range inputs {
@@ -284,9 +299,25 @@ func (sc *SportsController) PostSport(c *gin.Context) {
 			UserID:   user.ID,          // use the id from the session
 			Timedate: time.Now().UTC(), // set to the current UTC time
 		}
+		if input.SessionID != nil {
+			session, err := sc.gameSessions.Fetch(*input.SessionID)
+			if err == nil && session.UserID != user.ID {
+				err = fmt.Errorf("%w: %d", db.ErrGameSessionNotFound, session.ID)
+			}
+			if err != nil {
+				SetError(c, err)
+				return
+			}
+			sport.SessionID = &session.ID
+			if sport.Game == "" {
+				sport.Game = session.Game
+			}
+		} else if liveSession != nil && liveSession.Game == sport.Game {
+			sport.SessionID = &liveSession.ID
+		}
 
 		if err := sc.repo.InsertSport(sport); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			SetError(c, err)
 			return
 		}
 	}
@@ -294,7 +325,7 @@ func (sc *SportsController) PostSport(c *gin.Context) {
 	// fetch amount
 	amount, err := sc.repo.GetTotalAmounts(user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Sport(s) added successfully", "results": amount})
@@ -313,11 +344,7 @@ func (sc *SportsController) PostSport(c *gin.Context) {
 // @Router /api/sports [delete]
 func (sc *SportsController) DeleteSport(c *gin.Context) {
 	// Check if user is logged in via Discord
-	user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// Read sport ID from URL
 	idStr := c.Param("id")
@@ -329,7 +356,7 @@ func (sc *SportsController) DeleteSport(c *gin.Context) {
 
 	// Delete sport
 	if err := sc.repo.DeleteSport(id, user.ID); err != nil {
-		SetGinError(c, http.StatusInternalServerError, err)
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Sport deleted successfully"})
@@ -338,23 +365,18 @@ func (sc *SportsController) DeleteSport(c *gin.Context) {
 // GetDayStreak retrieves the number of days a user has been active back to back.
 func (sc *SportsController) GetDayStreak(c *gin.Context) {
 	// Check if user is logged in via Discord
-	_, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
 
 	// Read user ID from URL
 	idStr := c.Param("id")
 	id, err := models.NewSnowflakeFromString(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		SetGinError(c, http.StatusBadRequest, errors.New("Invalid user ID"))
 		return
 	}
 
 	streak, err := sc.repo.GetCurrentStreak(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"data": streak})
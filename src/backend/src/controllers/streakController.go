@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -20,17 +22,20 @@ type GetStreakQuery struct {
 type GetStreakReply struct {
 	// Data contains the streak information for the user
 	Data []models.DayStreak `json:"data"`
+	// IDs of requested users, whose streak is not visible for the logged in user
+	ForbiddenUserIDs []models.Snowflake `json:"forbidden_user_ids,omitempty"`
 }
 
 type StreakController struct {
-	repo db.SportRepository
+	repo       db.SportRepository
+	visibility db.IVisibilityService
 }
 
 // NewStreakController creates a new StreakController instance
 // TODO: use separate repo
-func NewStreakController(sportRepo db.SportRepository, Now func() time.Time) *StreakController {
+func NewStreakController(sportRepo db.SportRepository, visibility db.IVisibilityService, Now func() time.Time) *StreakController {
 	// repo := &db.OrmSportRepository{DB: DB, StreakService: db.NewStreakService(Now)}
-	return &StreakController{repo: sportRepo}
+	return &StreakController{repo: sportRepo, visibility: visibility}
 }
 
 // @Summary retrieves the number of days a user has been active back to back
@@ -41,15 +46,12 @@ func NewStreakController(sportRepo db.SportRepository, Now func() time.Time) *St
 // @Success 200 {object} GetStreakReply
 // @Failure 400 {object} ErrorReply
 // @Failure 401 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
 // @Failure 500 {object} ErrorReply
 // @Router /api/streak [get]
 func (sc *StreakController) Get(c *gin.Context) {
 	// Check if user is logged in via Discord
-	_, status, err := UserFromSession(c)
-	if err != nil {
-		c.JSON(status, gin.H{"error": err.Error()})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	req := GetStreakQuery{}
 
@@ -57,8 +59,8 @@ func (sc *StreakController) Get(c *gin.Context) {
 	idStr := c.Query("user_ids")
 
 	// bind IDs to the request
-	if err = req.UserIDs.UnmarshalText([]byte(idStr)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user IDs"})
+	if err := req.UserIDs.UnmarshalText([]byte(idStr)); err != nil {
+		SetGinError(c, http.StatusBadRequest, errors.New("Invalid user IDs"))
 		return
 	}
 
@@ -67,14 +69,20 @@ func (sc *StreakController) Get(c *gin.Context) {
 		return
 	}
 
-	streaks := make([]models.DayStreak, len(req.UserIDs.IDs))
-	for i, id := range req.UserIDs.IDs {
-		streak, err := sc.repo.GetCurrentStreak(id)
-		if err != nil {
-			SetGinError(c, http.StatusInternalServerError, fmt.Errorf("failed to get streak for user %d: %w", id, err))
-			return
-		}
-		streaks[i] = streak
+	// only users, which allow the logged in user to see their streak
+	userIDs, forbidden, ok := FilterRequestedUsers(c, sc.visibility, user.ID, req.UserIDs.IDs, models.ResourceStreak)
+	if !ok {
+		return
+	}
+
+	streaksByUser, err := sc.repo.GetCurrentStreaks(userIDs)
+	if err != nil {
+		SetError(c, fmt.Errorf("failed to get streaks for users %v: %w", userIDs, err))
+		return
+	}
+	streaks := make([]models.DayStreak, len(userIDs))
+	for i, id := range userIDs {
+		streaks[i] = streaksByUser[id]
 	}
-	c.JSON(http.StatusOK, GetStreakReply{Data: streaks})
+	c.JSON(http.StatusOK, GetStreakReply{Data: streaks, ForbiddenUserIDs: forbidden})
 }
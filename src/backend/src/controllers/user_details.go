@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 )
@@ -43,11 +44,7 @@ func (self *PersonalDetailsController) Get(c *gin.Context) {
 	// if err := c.ShouldBindQuery(&query); err != nil {
 	// 	SetGinError(c, http.StatusBadRequest, err)
 	// }
-	requesting_user, status, err := UserFromSession(c)
-	if err != nil {
-		SetGinError(c, status, err)
-		return
-	}
+	requesting_user := middleware.CurrentUser(c)
 	requested_user_id, err := NewSnowflakeFromString(c.Param("user_id"))
 	if err != nil {
 		SetGinError(c, http.StatusBadRequest, err)
@@ -56,7 +53,7 @@ func (self *PersonalDetailsController) Get(c *gin.Context) {
 
 	reply, err := self.repo.GetDetails(requested_user_id, requesting_user.ID)
 	if err != nil {
-		SetGinError(c, http.StatusForbidden, err)
+		SetError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, reply)
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserSettingsReply is the reply sent when doing [get] /settings
+// swagger:model GetUserSettingsReply
+type GetUserSettingsReply struct {
+	Data UserSettings `json:"data"`
+}
+
+// PatchUserSettingsRequest is the request sent when doing [patch] /settings.
+// Only provided fields are changed.
+// swagger:model PatchUserSettingsRequest
+type PatchUserSettingsRequest struct {
+	ActivitiesVisibility    *Visibility `json:"activities_visibility,omitempty" example:"friends"`
+	StreakVisibility        *Visibility `json:"streak_visibility,omitempty" example:"public"`
+	GoalsVisibility         *Visibility `json:"goals_visibility,omitempty" example:"friends"`
+	OverdueDeathsVisibility *Visibility `json:"overdue_deaths_visibility,omitempty" example:"private"`
+	ProfileVisibility       *Visibility `json:"profile_visibility,omitempty" example:"friends"`
+	Discoverable            *bool       `json:"discoverable,omitempty" example:"true"`
+}
+
+// UserSettingsController manages the privacy settings of the logged in user
+type UserSettingsController struct {
+	repo UserSettingsRepository
+}
+
+func NewUserSettingsController(settingsRepo UserSettingsRepository) *UserSettingsController {
+	return &UserSettingsController{repo: settingsRepo}
+}
+
+// @Summary Get the privacy settings of the logged in user
+// @Tags UserSettings
+// @Produce json
+// @Security CookieAuth
+// @Success 200 {object} GetUserSettingsReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings [get]
+func (self *UserSettingsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	settings, err := self.repo.Get(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetUserSettingsReply{Data: *settings})
+}
+
+// @Summary Change the privacy settings of the logged in user
+// @Tags UserSettings
+// @Accept json
+// @Produce json
+// @Security CookieAuth
+// @Param request body PatchUserSettingsRequest true "Settings to change"
+// @Success 200 {object} GetUserSettingsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/settings [patch]
+func (self *UserSettingsController) Patch(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	var req PatchUserSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		SetGinError(c, http.StatusBadRequest, apierror.InvalidBody(err))
+		return
+	}
+
+	settings, err := self.repo.Get(user.ID)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	// apply all visibilities, which were sent
+	visibilities := []struct {
+		value  *Visibility
+		target *Visibility
+	}{
+		{req.ActivitiesVisibility, &settings.ActivitiesVisibility},
+		{req.StreakVisibility, &settings.StreakVisibility},
+		{req.GoalsVisibility, &settings.GoalsVisibility},
+		{req.OverdueDeathsVisibility, &settings.OverdueDeathsVisibility},
+		{req.ProfileVisibility, &settings.ProfileVisibility},
+	}
+	for _, v := range visibilities {
+		if v.value == nil {
+			continue
+		}
+		if !v.value.IsValid() {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid visibility %q, expected one of public, friends, private", *v.value))
+			return
+		}
+		*v.target = *v.value
+	}
+	if req.Discoverable != nil {
+		settings.Discoverable = *req.Discoverable
+	}
+
+	settings, err = self.repo.Save(settings)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetUserSettingsReply{Data: *settings})
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// days of the default range, which ends today
+	defaultStatsDays = 365
+	// maximum number of days in the range
+	maxStatsDays = 3 * 366
+)
+
+// GetUserStatsReply is the reply sent when doing [get] /user/{user_id}/stats
+// swagger:model GetUserStatsReply
+type GetUserStatsReply struct {
+	Data UserStats `json:"data"`
+}
+
+// UserStatsController serves aggregated statistics of the sports of a user
+type UserStatsController struct {
+	stats db.IStatsService
+	Now   func() time.Time
+}
+
+func NewUserStatsController(stats db.IStatsService, Now func() time.Time) *UserStatsController {
+	return &UserStatsController{stats: stats, Now: Now}
+}
+
+// @Summary Aggregated statistics of the sports of a user
+// @Description Per-day totals for a heatmap, totals per bucket, sport, game and weekday and the best day.
+// @Description Days are UTC days. Requires the activities of the user to be visible.
+// @Tags stats
+// @Produce json
+// @Security CookieAuth
+// @Security BearerAuth
+// @Param user_id path string true "ID of the user"
+// @Param from query string false "First day (YYYY-MM-DD), defaults to 364 days before to"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Param bucket query string false "day, week or month. Defaults to day"
+// @Success 200 {object} GetUserStatsReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 403 {object} ErrorReply
+// @Failure 500 {object} ErrorReply
+// @Router /api/user/{user_id}/stats [get]
+func (sc *UserStatsController) Get(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	requestedUserID, err := NewSnowflakeFromString(c.Param("user_id"))
+	if err != nil {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid user ID: %s", c.Param("user_id")))
+		return
+	}
+
+	query := StatsQuery{Bucket: StatsBucketSize(c.DefaultQuery("bucket", string(BucketDay)))}
+	if !query.Bucket.IsValid() {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid bucket %q, expected one of day, week, month", query.Bucket))
+		return
+	}
+
+	query.To = sc.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		if query.To, err = time.Parse(time.DateOnly, value); err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid to date %q, expected YYYY-MM-DD", value))
+			return
+		}
+	}
+	query.From = query.To.AddDate(0, 0, -(defaultStatsDays - 1))
+	if value := c.Query("from"); value != "" {
+		if query.From, err = time.Parse(time.DateOnly, value); err != nil {
+			SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid from date %q, expected YYYY-MM-DD", value))
+			return
+		}
+	}
+	if query.To.Before(query.From) {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("from needs to be before to"))
+		return
+	}
+	if query.To.Sub(query.From) > maxStatsDays*24*time.Hour {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("the range can be at most %d days", maxStatsDays))
+		return
+	}
+
+	stats, err := sc.stats.GetStats(requestedUserID, user.ID, query)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetUserStatsReply{Data: *stats})
+}
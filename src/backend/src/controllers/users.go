@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	minUserSearchLength   = 2
+	defaultUserSearchSize = 10
+	maxUserSearchSize     = 25
+)
+
+// SearchUsersReply is the reply sent when doing [get] /users/search
+// swagger:model SearchUsersReply
+type SearchUsersReply struct {
+	Data []PublicUser `json:"data"`
+}
+
+// UsersController manages endpoints to find other users
+type UsersController struct {
+	userRepo db.UserRepository
+}
+
+func NewUsersController(userRepo db.UserRepository) *UsersController {
+	return &UsersController{userRepo: userRepo}
+}
+
+// @Summary Search discoverable users by username to send them a friend request
+// @Tags users
+// @Produce json
+// @Security CookieAuth
+// @Param q query string true "Username or a part of it, at least 2 characters"
+// @Param limit query int false "Maximum number of users, default is 10, maximum 25"
+// @Success 200 {object} SearchUsersReply
+// @Failure 400 {object} ErrorReply
+// @Failure 401 {object} ErrorReply
+// @Failure 429 {object} ErrorReply
+// @Router /api/users/search [get]
+func (uc *UsersController) Search(c *gin.Context) {
+	user := middleware.CurrentUser(c)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(query) < minUserSearchLength {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("q needs to have at least %d characters", minUserSearchLength))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUserSearchSize)))
+	if err != nil || limit <= 0 {
+		SetGinError(c, http.StatusBadRequest, fmt.Errorf("invalid limit value: %v", c.Query("limit")))
+		return
+	}
+	limit = min(limit, maxUserSearchSize)
+
+	users, err := uc.userRepo.SearchDiscoverable(query, user.ID, limit)
+	if err != nil {
+		SetError(c, err)
+		return
+	}
+
+	// only public fields are sent, never the email
+	reply := SearchUsersReply{Data: make([]PublicUser, 0, len(users))}
+	for _, u := range users {
+		reply.Data = append(reply.Data, u.Public())
+	}
+	c.JSON(http.StatusOK, reply)
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrDeletionNotFound = repositories.NotFound("the deletion of this account was not requested")
+
+// AccountRepository defines the interface for deleting and exporting whole accounts.
+func NewGormAccountRepository(database *gorm.DB) repositories.AccountRepository {
+	repo := &GormAccountRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `AccountRepository` for GORM
+type GormAccountRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the AccountDeletion GORM table
+func (r *GormAccountRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&AccountDeletion{})
+}
+
+// Requests the deletion of <userID>. If it was already requested, the existing request is returned
+func (r *GormAccountRepository) ScheduleDeletion(
+	userID Snowflake,
+	requestedAt time.Time,
+	deleteAfter time.Time,
+) (*AccountDeletion, error) {
+	deletion := AccountDeletion{UserID: userID, RequestedAt: requestedAt, DeleteAfter: deleteAfter}
+	err := r.DB.Where(&AccountDeletion{UserID: userID}).FirstOrCreate(&deletion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// Returns the requested deletion of <userID> or nil, if there is none
+func (r *GormAccountRepository) FetchDeletion(userID Snowflake) (*AccountDeletion, error) {
+	var deletion AccountDeletion
+	err := r.DB.Where(&AccountDeletion{UserID: userID}).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+// Cancels the requested deletion of <userID>
+func (r *GormAccountRepository) CancelDeletion(userID Snowflake) error {
+	result := r.DB.Where(&AccountDeletion{UserID: userID}).Delete(&AccountDeletion{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeletionNotFound
+	}
+	return nil
+}
+
+// Returns all deletions, whose grace period is over at <now>
+func (r *GormAccountRepository) FetchDueDeletions(now time.Time) ([]AccountDeletion, error) {
+	var deletions []AccountDeletion
+	err := r.DB.Where("delete_after <= ?", now).Order("delete_after").Find(&deletions).Error
+	return deletions, err
+}
+
+// Deletes the user <userID> and all of his data in one transaction
+func (r *GormAccountRepository) Purge(userID Snowflake) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeUser(tx, userID)
+	})
+}
+
+// deletes every row belonging to <userID>. Only personal goals are deleted by a foreign key,
+// all other tables would keep orphaned rows otherwise
+func purgeUser(tx *gorm.DB, userID Snowflake) error {
+	deletions := []struct {
+		model any
+		query string
+		args  []any
+	}{
+		{&Sport{}, "user_id = ?", []any{userID}},
+		{&GameSession{}, "user_id = ?", []any{userID}},
+		{&OverdueDeaths{}, "user_id = ?", []any{userID}},
+		{&LiveDeathCount{}, "user_id = ?", []any{userID}},
+		{&GameAccount{}, "user_id = ?", []any{userID}},
+		{&ProcessedMatch{}, "user_id = ?", []any{userID}},
+		{&EventSigningSecret{}, "user_id = ?", []any{userID}},
+		{&GameEvent{}, "user_id = ?", []any{userID}},
+		{&PersonalGoal{}, "user_id = ?", []any{userID}},
+		{&StreakReminder{}, "user_id = ?", []any{userID}},
+		{&Friendships{}, "requester_id = ? OR recipient_id = ?", []any{userID, userID}},
+		{&FriendInvite{}, "creator_id = ?", []any{userID}},
+		{&Notification{}, "user_id = ? OR actor_id = ?", []any{userID, userID}},
+		{&EmailSubscription{}, "user_id = ?", []any{userID}},
+		{&EmailDelivery{}, "user_id = ?", []any{userID}},
+		{&PushSubscription{}, "user_id = ?", []any{userID}},
+		{&CalendarFeed{}, "user_id = ?", []any{userID}},
+		{&UserSettings{}, "user_id = ?", []any{userID}},
+		{&APIToken{}, "user_id = ?", []any{userID}},
+		{&Session{}, "user_id = ?", []any{userID}},
+		{&UserIdentity{}, "user_id = ?", []any{userID}},
+		{&AccountDeletion{}, "user_id = ?", []any{userID}},
+		{&User{}, "id = ?", []any{userID}},
+	}
+	for _, deletion := range deletions {
+		if err := tx.Where(deletion.query, deletion.args...).Delete(deletion.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns everything stored about the user <userID>
+func (r *GormAccountRepository) Export(userID Snowflake) (*AccountExport, error) {
+	export := AccountExport{}
+	if err := r.DB.First(&export.User, userID).Error; err != nil {
+		return nil, err
+	}
+
+	err := r.DB.Where(&UserSettings{UserID: userID}).First(&export.Settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		export.Settings = DefaultUserSettings(userID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	queries := []struct {
+		target any
+		query  string
+		args   []any
+		order  string
+	}{
+		{&export.Identities, "user_id = ?", []any{userID}, "created_at"},
+		{&export.Sessions, "user_id = ?", []any{userID}, "created_at"},
+		{&export.APITokens, "user_id = ?", []any{userID}, "created_at"},
+		{&export.Sports, "user_id = ?", []any{userID}, "timedate"},
+		{&export.GameSessions, "user_id = ?", []any{userID}, "started_at"},
+		{&export.GameAccounts, "user_id = ?", []any{userID}, "created_at"},
+		{&export.ProcessedMatches, "user_id = ?", []any{userID}, "ended_at"},
+		{&export.GameEvents, "user_id = ?", []any{userID}, "received_at"},
+		{&export.OverdueDeaths, "user_id = ?", []any{userID}, "game"},
+		{&export.PersonalGoals, "user_id = ?", []any{userID}, "id"},
+		{&export.StreakReminders, "user_id = ?", []any{userID}, "user_id"},
+		{&export.Friendships, "requester_id = ? OR recipient_id = ?", []any{userID, userID}, "created_at"},
+		{&export.FriendInvites, "creator_id = ?", []any{userID}, "created_at"},
+		{&export.Notifications, "user_id = ?", []any{userID}, "id"},
+		{&export.EmailSubscriptions, "user_id = ?", []any{userID}, "type"},
+		{&export.EmailDeliveries, "user_id = ?", []any{userID}, "sent_at"},
+		{&export.PushSubscriptions, "user_id = ?", []any{userID}, "created_at"},
+		{&export.CalendarFeeds, "user_id = ?", []any{userID}, "created_at"},
+	}
+	for _, q := range queries {
+		if err := r.DB.Where(q.query, q.args...).Order(q.order).Find(q.target).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	deletion, err := r.FetchDeletion(userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Deletion = deletion
+	return &export, nil
+}
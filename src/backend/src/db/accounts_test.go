@@ -0,0 +1,192 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestAccountDB migrates every table holding user data and stores some data for
+// the users 1 and 2, who are friends
+func newTestAccountDB(t *testing.T) (*GormAccountRepository, *gorm.DB) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	err = database.AutoMigrate(
+		&User{}, &Sport{}, &GameSession{}, &OverdueDeaths{}, &LiveDeathCount{},
+		&GameAccount{}, &ProcessedMatch{}, &EventSigningSecret{}, &GameEvent{}, &PersonalGoal{}, &StreakReminder{}, &Friendships{}, &FriendInvite{}, &Notification{}, &EmailSubscription{}, &EmailDelivery{}, &PushSubscription{}, &CalendarFeed{},
+		&UserSettings{}, &APIToken{}, &Session{}, &UserIdentity{},
+	)
+	if err != nil {
+		t.Fatalf("failed to migrate tables: %v", err)
+	}
+	repo := &GormAccountRepository{DB: database}
+	if err := repo.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate account deletions table: %v", err)
+	}
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, userID := range []Snowflake{1, 2} {
+		id := uint64(userID)
+		rows := []any{
+			&User{ID: userID, Username: "user"},
+			&Sport{ID: Snowflake(100 + id), UserID: userID, Kind: "push-up", Amount: 10, Game: "league", Timedate: now},
+			&OverdueDeaths{UserID: userID, Game: "league", Count: 3},
+			&PersonalGoal{ID: Snowflake(200 + id), UserID: userID, Sport: "push-up", Amount: 50, Frequency: Daily},
+			&FriendInvite{ID: Snowflake(300 + id), Code: fmt.Sprintf("CODE%d", id), CreatorID: userID, ExpiresAt: now},
+			&UserSettings{UserID: userID, ActivitiesVisibility: Public},
+			&APIToken{ID: Snowflake(400 + id), UserID: userID, Hash: fmt.Sprintf("hash%d", id), CreatedAt: now},
+			&Session{ID: fmt.Sprintf("session%d", id), PublicID: fmt.Sprintf("public%d", id), UserID: userID, ExpiresAt: now},
+			&UserIdentity{ID: Snowflake(500 + id), UserID: userID, Provider: "discord", Subject: fmt.Sprint(id)},
+			&Notification{ID: Snowflake(700 + id), UserID: userID, Type: NotificationFriendRequest, ActorID: Snowflake(3 - id), CreatedAt: now},
+			&EmailSubscription{UserID: userID, Type: NotificationWeeklyDigest, CreatedAt: now},
+			&CalendarFeed{UserID: userID, Hash: fmt.Sprintf("calendar%d", id), CreatedAt: now},
+			&PushSubscription{ID: Snowflake(800 + id), UserID: userID, Endpoint: fmt.Sprintf("https://push.example.com/%d", id), CreatedAt: now},
+		}
+		for _, row := range rows {
+			if err := database.Create(row).Error; err != nil {
+				t.Fatalf("failed to create %T: %v", row, err)
+			}
+		}
+	}
+	friendship := Friendships{ID: 600, RequesterID: 1, RecipientID: 2, Status: Accepted, CreatedAt: now}
+	if err := database.Create(&friendship).Error; err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	return repo, database
+}
+
+// TestAccountPurge verifies that every row of the user is deleted, but nothing of other users
+func TestAccountPurge(t *testing.T) {
+	repo, database := newTestAccountDB(t)
+
+	if err := repo.Purge(1); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+
+	var tests = []struct {
+		model any
+		query string
+		want  int64
+	}{
+		{&User{}, "id = 1", 0},
+		{&User{}, "id = 2", 1},
+		{&Sport{}, "user_id = 1", 0},
+		{&Sport{}, "user_id = 2", 1},
+		{&OverdueDeaths{}, "user_id = 1", 0},
+		{&PersonalGoal{}, "user_id = 1", 0},
+		{&PersonalGoal{}, "user_id = 2", 1},
+		// the friendship of both users is gone for user 2 as well
+		{&Friendships{}, "requester_id = 1 OR recipient_id = 1", 0},
+		{&FriendInvite{}, "creator_id = 1", 0},
+		{&FriendInvite{}, "creator_id = 2", 1},
+		{&UserSettings{}, "user_id = 1", 0},
+		{&APIToken{}, "user_id = 1", 0},
+		{&Session{}, "user_id = 1", 0},
+		{&Session{}, "user_id = 2", 1},
+		{&UserIdentity{}, "user_id = 1", 0},
+		// notifications caused by user 1 are gone for user 2 as well
+		{&Notification{}, "user_id = 1 OR actor_id = 1", 0},
+		{&EmailSubscription{}, "user_id = 1", 0},
+		{&EmailSubscription{}, "user_id = 2", 1},
+		{&PushSubscription{}, "user_id = 1", 0},
+		{&PushSubscription{}, "user_id = 2", 1},
+		{&CalendarFeed{}, "user_id = 1", 0},
+		{&CalendarFeed{}, "user_id = 2", 1},
+	}
+	for _, tt := range tests {
+		var count int64
+		if err := database.Model(tt.model).Where(tt.query).Count(&count).Error; err != nil {
+			t.Fatalf("failed to count %T: %v", tt.model, err)
+		}
+		if count != tt.want {
+			t.Errorf("%T where %s: got %d rows, want %d", tt.model, tt.query, count, tt.want)
+		}
+	}
+}
+
+// TestAccountDeletionSchedule verifies scheduling, cancelling and the grace period of deletions
+func TestAccountDeletionSchedule(t *testing.T) {
+	repo, _ := newTestAccountDB(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	deletion, err := repo.ScheduleDeletion(1, now, now.Add(week))
+	if err != nil {
+		t.Fatalf("failed to schedule deletion: %v", err)
+	}
+	// requesting it again must not extend the grace period
+	again, err := repo.ScheduleDeletion(1, now.Add(time.Hour), now.Add(time.Hour+week))
+	if err != nil {
+		t.Fatalf("failed to schedule deletion again: %v", err)
+	}
+	if !again.DeleteAfter.Equal(deletion.DeleteAfter) {
+		t.Errorf("got delete after %v, want %v", again.DeleteAfter, deletion.DeleteAfter)
+	}
+	if _, err := repo.ScheduleDeletion(2, now, now.Add(week)); err != nil {
+		t.Fatalf("failed to schedule deletion: %v", err)
+	}
+
+	due, err := repo.FetchDueDeletions(now.Add(week - time.Second))
+	if err != nil || len(due) != 0 {
+		t.Errorf("got %d due deletions (%v) during the grace period, want 0", len(due), err)
+	}
+
+	if err := repo.CancelDeletion(2); err != nil {
+		t.Fatalf("failed to cancel deletion: %v", err)
+	}
+	if err := repo.CancelDeletion(2); !errors.Is(err, ErrDeletionNotFound) {
+		t.Errorf("got %v, want ErrDeletionNotFound", err)
+	}
+
+	due, err = repo.FetchDueDeletions(now.Add(week))
+	if err != nil {
+		t.Fatalf("failed to fetch due deletions: %v", err)
+	}
+	if len(due) != 1 || due[0].UserID != 1 {
+		t.Errorf("got due deletions %+v, want only user 1", due)
+	}
+}
+
+// TestAccountExport verifies that the export contains the data of the user
+func TestAccountExport(t *testing.T) {
+	repo, _ := newTestAccountDB(t)
+
+	export, err := repo.Export(2)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if export.User.ID != 2 || export.Settings.UserID != 2 {
+		t.Errorf("got user %d with settings of %d, want 2", export.User.ID, export.Settings.UserID)
+	}
+	counts := map[string]int{
+		"identities":          len(export.Identities),
+		"sessions":            len(export.Sessions),
+		"api tokens":          len(export.APITokens),
+		"sports":              len(export.Sports),
+		"overdue deaths":      len(export.OverdueDeaths),
+		"personal goals":      len(export.PersonalGoals),
+		"friendships":         len(export.Friendships),
+		"friend invites":      len(export.FriendInvites),
+		"notifications":       len(export.Notifications),
+		"email subscriptions": len(export.EmailSubscriptions),
+		"push subscriptions":  len(export.PushSubscriptions),
+		"calendar feeds":      len(export.CalendarFeeds),
+	}
+	for name, count := range counts {
+		if count != 1 {
+			t.Errorf("got %d %s, want 1", count, name)
+		}
+	}
+	if export.Deletion != nil {
+		t.Errorf("got deletion %+v, want none", export.Deletion)
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrTokenNotFound = repositories.NotFound("token not found")
+
+// APITokenRepository defines the interface for managing API tokens in the database.
+func NewGormAPITokenRepository(database *gorm.DB) repositories.APITokenRepository {
+	repo := &GormAPITokenRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `APITokenRepository` for GORM
+type GormAPITokenRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the APIToken GORM table
+func (r *GormAPITokenRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&APIToken{})
+}
+
+// Creates a new APIToken record in the DB.
+func (r *GormAPITokenRepository) Create(token *APIToken) (*APIToken, error) {
+	if err := r.DB.Create(token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Returns the token with the SHA-256 <hash> or nil, if there is none
+func (r *GormAPITokenRepository) FetchByHash(hash string) (*APIToken, error) {
+	var token APIToken
+	err := r.DB.Where(&APIToken{Hash: hash}).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Returns all tokens of <userID>, newest first
+func (r *GormAPITokenRepository) FetchByUserID(userID Snowflake) ([]APIToken, error) {
+	var tokens []APIToken
+	err := r.DB.Where(&APIToken{UserID: userID}).Order("created_at desc").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revokes the token with <id>. Only the owner can revoke a token
+func (r *GormAPITokenRepository) Revoke(id Snowflake, userID Snowflake, now time.Time) error {
+	result := r.DB.Model(&APIToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// Sets the last usage of the token with <id> to <now>
+func (r *GormAPITokenRepository) Touch(id Snowflake, now time.Time) error {
+	return r.DB.Model(&APIToken{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
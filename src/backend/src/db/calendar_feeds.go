@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrCalendarFeedNotFound = repositories.NotFound("no calendar feed was created")
+
+// CalendarFeedRepository defines the interface for managing calendar feed tokens in the database.
+func NewGormCalendarFeedRepository(database *gorm.DB) repositories.CalendarFeedRepository {
+	repo := &GormCalendarFeedRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `CalendarFeedRepository` for GORM
+type GormCalendarFeedRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the CalendarFeed GORM table
+func (r *GormCalendarFeedRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&CalendarFeed{})
+}
+
+// Returns the feed of <userID> or nil, if there is none
+func (r *GormCalendarFeedRepository) FetchByUserID(userID Snowflake) (*CalendarFeed, error) {
+	return r.first(&CalendarFeed{UserID: userID})
+}
+
+// Returns the feed with the token with the SHA-256 <hash> or nil, if there is none
+func (r *GormCalendarFeedRepository) FetchByHash(hash string) (*CalendarFeed, error) {
+	return r.first(&CalendarFeed{Hash: hash})
+}
+
+// returns the first feed matching <query> or nil
+func (r *GormCalendarFeedRepository) first(query *CalendarFeed) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	err := r.DB.Where(query).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// Creates or replaces the feed of its user
+func (r *GormCalendarFeedRepository) Save(feed *CalendarFeed) (*CalendarFeed, error) {
+	if err := r.DB.Save(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// Deletes the feed of <userID>, so that its URL stops working
+func (r *GormCalendarFeedRepository) Delete(userID Snowflake) error {
+	result := r.DB.Where(&CalendarFeed{UserID: userID}).Delete(&CalendarFeed{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+// Sets the last fetch of the feed of <userID> to <now>
+func (r *GormCalendarFeedRepository) Touch(userID Snowflake, now time.Time) error {
+	return r.DB.Model(&CalendarFeed{}).Where("user_id = ?", userID).Update("last_fetched_at", now).Error
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+type IDeathCounter interface {
+	// adds <count> deaths of <userID> in <game> and returns the deaths, which were not flushed yet
+	Increment(userID Snowflake, game string, count int64) LiveDeathCount
+	// returns the deaths of <userID>, which were not flushed yet
+	Pending(userID Snowflake) []LiveDeathCount
+	// flushes the deaths of <userID> to his overdue deaths
+	FlushUser(userID Snowflake) error
+}
+
+type liveDeathKey struct {
+	userID Snowflake
+	game   string
+}
+
+type liveDeathEntry struct {
+	count     int64
+	updatedAt time.Time
+	// whether or not the entry changed since the last snapshot
+	dirty bool
+}
+
+// DeathCounter counts deaths in memory, so that counting a death doesn't write to the database.
+// The counts are saved as snapshot with `Persist` and added to the overdue deaths with `Flush`
+type DeathCounter struct {
+	Repo repositories.LiveDeathRepository
+	Now  func() time.Time
+
+	mu     sync.Mutex
+	counts map[liveDeathKey]*liveDeathEntry
+	// serializes Persist and Flush, so that a snapshot never restores flushed deaths
+	storeMu sync.Mutex
+}
+
+// NewDeathCounter creates a counter, which continues with the snapshot stored in <repo>
+func NewDeathCounter(repo repositories.LiveDeathRepository, Now func() time.Time) (*DeathCounter, error) {
+	counter := &DeathCounter{Repo: repo, Now: Now, counts: map[liveDeathKey]*liveDeathEntry{}}
+	snapshot, err := repo.FetchAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range snapshot {
+		counter.counts[liveDeathKey{count.UserID, count.Game}] = &liveDeathEntry{
+			count:     count.Count,
+			updatedAt: count.UpdatedAt,
+		}
+	}
+	return counter, nil
+}
+
+// Increment adds <count> deaths of <userID> in <game> and returns the deaths, which were not flushed yet
+func (c *DeathCounter) Increment(userID Snowflake, game string, count int64) LiveDeathCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := liveDeathKey{userID, game}
+	entry, ok := c.counts[key]
+	if !ok {
+		entry = &liveDeathEntry{}
+		c.counts[key] = entry
+	}
+	entry.count += count
+	entry.updatedAt = c.Now().UTC()
+	entry.dirty = true
+	return LiveDeathCount{UserID: userID, Game: game, Count: entry.count, UpdatedAt: entry.updatedAt}
+}
+
+// Pending returns the deaths of <userID>, which were not flushed yet, ordered by game
+func (c *DeathCounter) Pending(userID Snowflake) []LiveDeathCount {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := []LiveDeathCount{}
+	for key, entry := range c.counts {
+		if key.userID == userID && entry.count > 0 {
+			pending = append(pending, LiveDeathCount{
+				UserID:    userID,
+				Game:      key.game,
+				Count:     entry.count,
+				UpdatedAt: entry.updatedAt,
+			})
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Game < pending[j].Game })
+	return pending
+}
+
+// Persist saves all counts, which changed since the last snapshot, and returns their number
+func (c *DeathCounter) Persist() (int, error) {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	c.mu.Lock()
+	changed := []LiveDeathCount{}
+	for key, entry := range c.counts {
+		if entry.dirty {
+			entry.dirty = false
+			changed = append(changed, LiveDeathCount{
+				UserID:    key.userID,
+				Game:      key.game,
+				Count:     entry.count,
+				UpdatedAt: entry.updatedAt,
+			})
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.Repo.Save(changed); err != nil {
+		c.markDirty(changed)
+		return 0, err
+	}
+	return len(changed), nil
+}
+
+// Flush adds all counted deaths to the overdue deaths and returns the number of flushed counts
+func (c *DeathCounter) Flush() (int, error) {
+	return c.flush(func(liveDeathKey) bool { return true })
+}
+
+// FlushUser adds the counted deaths of <userID> to his overdue deaths
+func (c *DeathCounter) FlushUser(userID Snowflake) error {
+	_, err := c.flush(func(key liveDeathKey) bool { return key.userID == userID })
+	return err
+}
+
+// flushes the counts with a key matching <filter>. Deaths counted while flushing are kept
+func (c *DeathCounter) flush(filter func(liveDeathKey) bool) (int, error) {
+	c.storeMu.Lock()
+	defer c.storeMu.Unlock()
+
+	c.mu.Lock()
+	flushed := []LiveDeathCount{}
+	for key, entry := range c.counts {
+		if entry.count > 0 && filter(key) {
+			flushed = append(flushed, LiveDeathCount{UserID: key.userID, Game: key.game, Count: entry.count})
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.Repo.Flush(flushed); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, count := range flushed {
+		key := liveDeathKey{count.UserID, count.Game}
+		entry := c.counts[key]
+		entry.count -= count.Count
+		if entry.count <= 0 {
+			delete(c.counts, key)
+			continue
+		}
+		// the snapshot of this entry was removed by the flush
+		entry.dirty = true
+	}
+	return len(flushed), nil
+}
+
+// marks the entries of <counts> as changed, so that they are part of the next snapshot
+func (c *DeathCounter) markDirty(counts []LiveDeathCount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, count := range counts {
+		if entry, ok := c.counts[liveDeathKey{count.UserID, count.Game}]; ok {
+			entry.dirty = true
+		}
+	}
+}
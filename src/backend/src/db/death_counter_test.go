@@ -0,0 +1,118 @@
+package db
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDeathCounterDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&OverdueDeaths{}, &GameSession{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return database
+}
+
+func overdueDeathCount(t *testing.T, database *gorm.DB, userID Snowflake, game string) int64 {
+	t.Helper()
+	var overdue OverdueDeaths
+	database.Where(&OverdueDeaths{UserID: userID, Game: game}).Limit(1).Find(&overdue)
+	return overdue.Count
+}
+
+// TestDeathCounterSurvivesRestart verifies that persisted counts are loaded by a new counter
+func TestDeathCounterSurvivesRestart(t *testing.T) {
+	database := newTestDeathCounterDB(t)
+	repo := NewGormLiveDeathRepository(database)
+	counter, err := NewDeathCounter(repo, time.Now)
+	if err != nil {
+		t.Fatalf("failed to create counter: %v", err)
+	}
+
+	counter.Increment(1, "overwatch", 1)
+	counter.Increment(1, "overwatch", 2)
+	counter.Increment(2, "league", 1)
+	if saved, err := counter.Persist(); err != nil || saved != 2 {
+		t.Fatalf("got %d saved counts, %v; want 2", saved, err)
+	}
+	if saved, _ := counter.Persist(); saved != 0 {
+		t.Errorf("got %d saved counts without changes, want 0", saved)
+	}
+	// not persisted, lost on restart
+	counter.Increment(1, "overwatch", 10)
+
+	restarted, err := NewDeathCounter(repo, time.Now)
+	if err != nil {
+		t.Fatalf("failed to restart counter: %v", err)
+	}
+	pending := restarted.Pending(1)
+	if len(pending) != 1 || pending[0].Game != "overwatch" || pending[0].Count != 3 {
+		t.Errorf("got pending %+v after restart, want 3 overwatch deaths", pending)
+	}
+	if overdueDeathCount(t, database, 1, "overwatch") != 0 {
+		t.Errorf("deaths were added to the overdue deaths before flushing")
+	}
+}
+
+func TestDeathCounterFlush(t *testing.T) {
+	database := newTestDeathCounterDB(t)
+	repo := NewGormLiveDeathRepository(database)
+	counter, err := NewDeathCounter(repo, time.Now)
+	if err != nil {
+		t.Fatalf("failed to create counter: %v", err)
+	}
+
+	database.Create(&OverdueDeaths{UserID: 1, Game: "overwatch", Count: 4})
+	database.Create(&GameSession{UserID: 1, Game: "overwatch", StartedAt: time.Now()})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Increment(1, "overwatch", 1)
+		}()
+	}
+	wg.Wait()
+	counter.Increment(2, "league", 7)
+	counter.Persist()
+
+	if err := counter.FlushUser(1); err != nil {
+		t.Fatalf("failed to flush user: %v", err)
+	}
+	if got := overdueDeathCount(t, database, 1, "overwatch"); got != 54 {
+		t.Errorf("got %d overdue deaths, want 54", got)
+	}
+	var session GameSession
+	database.First(&session)
+	if session.Deaths != 50 || session.OverdueDeaths != 50 {
+		t.Errorf("got session %+v, want 50 deaths, which are all overdue", session)
+	}
+	if pending := counter.Pending(1); len(pending) != 0 {
+		t.Errorf("got pending %+v after flushing", pending)
+	}
+	// the snapshot of the other user is kept
+	if snapshot, _ := repo.FetchAll(); len(snapshot) != 1 || snapshot[0].UserID != 2 {
+		t.Errorf("got snapshot %+v, want only user 2", snapshot)
+	}
+
+	if flushed, err := counter.Flush(); err != nil || flushed != 1 {
+		t.Fatalf("got %d flushed counts, %v; want 1", flushed, err)
+	}
+	if got := overdueDeathCount(t, database, 2, "league"); got != 7 {
+		t.Errorf("got %d overdue deaths, want 7", got)
+	}
+	if snapshot, _ := repo.FetchAll(); len(snapshot) != 0 {
+		t.Errorf("got snapshot %+v after flushing everything", snapshot)
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+// EmailRepository defines the interface for managing email subscriptions and deliveries in the database.
+func NewGormEmailRepository(database *gorm.DB) repositories.EmailRepository {
+	repo := &GormEmailRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `EmailRepository` for GORM
+type GormEmailRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the EmailSubscription and EmailDelivery GORM tables
+func (r *GormEmailRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&EmailSubscription{}, &EmailDelivery{})
+}
+
+// Returns the notification types, which <userID> receives by email
+func (r *GormEmailRepository) FetchSubscriptions(userID Snowflake) ([]NotificationType, error) {
+	types := []NotificationType{}
+	err := r.DB.Model(&EmailSubscription{}).
+		Where(&EmailSubscription{UserID: userID}).
+		Order("type").
+		Pluck("type", &types).Error
+	return types, err
+}
+
+// Returns whether <userID> opted in to <notificationType>
+func (r *GormEmailRepository) IsSubscribed(userID Snowflake, notificationType NotificationType) (bool, error) {
+	var count int64
+	err := r.DB.Model(&EmailSubscription{}).
+		Where(&EmailSubscription{UserID: userID, Type: notificationType}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Replaces the subscriptions of <userID> with <types> in one transaction
+func (r *GormEmailRepository) SaveSubscriptions(userID Snowflake, types []NotificationType, now time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&EmailSubscription{UserID: userID}).Delete(&EmailSubscription{}).Error; err != nil {
+			return err
+		}
+		if len(types) == 0 {
+			return nil
+		}
+		subscriptions := make([]EmailSubscription, 0, len(types))
+		for _, notificationType := range types {
+			subscriptions = append(subscriptions, EmailSubscription{UserID: userID, Type: notificationType, CreatedAt: now})
+		}
+		return tx.Create(&subscriptions).Error
+	})
+}
+
+// Removes the subscription of <userID> to <notificationType>. Unsubscribing twice is no error,
+// since unsubscribe links can be opened more than once
+func (r *GormEmailRepository) Unsubscribe(userID Snowflake, notificationType NotificationType) error {
+	return r.DB.Where(&EmailSubscription{UserID: userID, Type: notificationType}).Delete(&EmailSubscription{}).Error
+}
+
+// Stores <delivery> in the delivery log
+func (r *GormEmailRepository) LogDelivery(delivery *EmailDelivery) error {
+	delivery.ID = 0 // ensure that GORM creates a new record
+	return r.DB.Create(delivery).Error
+}
+
+// Returns the emails sent to <userID>, newest first
+func (r *GormEmailRepository) FetchDeliveries(userID Snowflake, limit int) ([]EmailDelivery, error) {
+	deliveries := []EmailDelivery{}
+	err := r.DB.Where(&EmailDelivery{UserID: userID}).Order("sent_at desc, id desc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInviteNotFound  = repositories.NotFound("invite not found")
+	ErrInviteNotUsable = repositories.Gone("invite is expired, revoked or used up")
+)
+
+// FriendInviteRepository defines the interface for managing friend invites in the database.
+func NewGormFriendInviteRepository(database *gorm.DB) repositories.FriendInviteRepository {
+	repo := &GormFriendInviteRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `FriendInviteRepository` for GORM
+type GormFriendInviteRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the FriendInvite GORM table
+func (r *GormFriendInviteRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&FriendInvite{})
+}
+
+// Creates a new FriendInvite record in the DB.
+func (r *GormFriendInviteRepository) Create(invite *FriendInvite) (*FriendInvite, error) {
+	invite.ID = 0 // ensure that GORM creates a new record
+	if err := r.DB.Create(invite).Error; err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// Returns the invite with <code>
+func (r *GormFriendInviteRepository) FetchByCode(code string) (*FriendInvite, error) {
+	var invite FriendInvite
+	if err := r.DB.Where(&FriendInvite{Code: code}).First(&invite).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrInviteNotFound, code)
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Returns all invites created by <creatorID>, newest first
+func (r *GormFriendInviteRepository) FetchByCreator(creatorID Snowflake) ([]FriendInvite, error) {
+	var invites []FriendInvite
+	err := r.DB.Where(&FriendInvite{CreatorID: creatorID}).Order("created_at desc").Find(&invites).Error
+	return invites, err
+}
+
+// Revokes the invite with <code>. Only the creator can revoke an invite
+func (r *GormFriendInviteRepository) Revoke(code string, creatorID Snowflake, now time.Time) error {
+	result := r.DB.Model(&FriendInvite{}).
+		Where("code = ? AND creator_id = ? AND revoked_at IS NULL", code, creatorID).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrInviteNotFound, code)
+	}
+	return nil
+}
+
+// Counts one use of the invite with <code>. The check and the increment happen in a single
+// UPDATE, so that concurrent redemptions can't exceed <MaxUses>
+func (r *GormFriendInviteRepository) Use(code string, now time.Time) (*FriendInvite, error) {
+	result := r.DB.Model(&FriendInvite{}).
+		Where("code = ? AND revoked_at IS NULL AND expires_at > ? AND (max_uses = 0 OR uses < max_uses)", code, now).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	invite, err := r.FetchByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInviteNotUsable, code)
+	}
+	return invite, nil
+}
+
+// Makes <redeemerID> an accepted friend of the creator of the invite with <code>. The use is
+// only counted for new friendships and in the same transaction, so that blocked users and
+// failed friendships don't use up the invite. Expired, revoked and used up invites fail, even when the
+// users are already friends
+func (r *GormFriendInviteRepository) Redeem(code string, redeemerID Snowflake, now time.Time) (*FriendInvite, bool, error) {
+	var invite *FriendInvite
+	created := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		invites := &GormFriendInviteRepository{DB: tx}
+		friendships := &GormFriendshipRepository{DB: tx}
+
+		var err error
+		if invite, err = invites.FetchByCode(code); err != nil {
+			return err
+		}
+		if !invite.IsUsable(now) {
+			return fmt.Errorf("%w: %s", ErrInviteNotUsable, code)
+		}
+		existing, err := friendships.findFriendship(invite.CreatorID, redeemerID)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.Status == Blocked {
+			return repositories.Forbidden("friendship between %d and %d is blocked", invite.CreatorID, redeemerID)
+		}
+		if existing != nil && existing.Status == Accepted {
+			// already being friends should not use up the invite
+			return nil
+		}
+
+		if invite, err = invites.Use(code, now); err != nil {
+			return err
+		}
+		created = true
+		return friendships.EstablishFriendship(invite.CreatorID, redeemerID)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return invite, created, nil
+}
+
+// Deletes all invites, which can't be redeemed anymore at <now>
+func (r *GormFriendInviteRepository) DeleteUnusable(now time.Time) (int64, error) {
+	result := r.DB.
+		Where("revoked_at IS NOT NULL OR expires_at <= ? OR (max_uses > 0 AND uses >= max_uses)", now).
+		Delete(&FriendInvite{})
+	return result.RowsAffected, result.Error
+}
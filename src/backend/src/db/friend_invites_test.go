@@ -0,0 +1,146 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestInviteRepo builds an isolated in-memory repository for each test.
+func newTestInviteRepo(t *testing.T) *GormFriendInviteRepository {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+
+	repo := &GormFriendInviteRepository{DB: database}
+	if err := repo.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate friend invites table: %v", err)
+	}
+	return repo
+}
+
+// TestFriendInviteUse verifies that invites can only be used while they are usable.
+func TestFriendInviteUse(t *testing.T) {
+	now := time.Date(2023, 1, 6, 12, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name    string
+		invite  FriendInvite
+		useAt   time.Time
+		uses    int
+		wantErr error
+	}{
+		{"Unlimited invite can be used many times", FriendInvite{Code: "A", ExpiresAt: now.Add(time.Hour)}, now, 5, nil},
+		{"Invite can be used up to max uses", FriendInvite{Code: "B", MaxUses: 2, ExpiresAt: now.Add(time.Hour)}, now, 2, nil},
+		{"Invite can't be used more than max uses", FriendInvite{Code: "C", MaxUses: 2, ExpiresAt: now.Add(time.Hour)}, now, 3, ErrInviteNotUsable},
+		{"Expired invite can't be used", FriendInvite{Code: "D", ExpiresAt: now.Add(time.Hour)}, now.Add(time.Hour), 1, ErrInviteNotUsable},
+		{"Revoked invite can't be used", FriendInvite{Code: "E", ExpiresAt: now.Add(time.Hour), RevokedAt: &now}, now, 1, ErrInviteNotUsable},
+	}
+
+	repo := newTestInviteRepo(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.invite.CreatorID = 1
+			if _, err := repo.Create(&tt.invite); err != nil {
+				t.Fatalf("failed to create invite: %v", err)
+			}
+
+			var err error
+			for i := 0; i < tt.uses; i++ {
+				_, err = repo.Use(tt.invite.Code, tt.useAt)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Unknown invite is not found", func(t *testing.T) {
+		if _, err := repo.Use("UNKNOWN", now); !errors.Is(err, ErrInviteNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrInviteNotFound)
+		}
+	})
+}
+
+// TestFriendInviteRevoke verifies that only the creator can revoke an invite.
+func TestFriendInviteRevoke(t *testing.T) {
+	now := time.Date(2023, 1, 6, 12, 0, 0, 0, time.UTC)
+	repo := newTestInviteRepo(t)
+
+	if _, err := repo.Create(&FriendInvite{Code: "CODE", CreatorID: 1, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+
+	if err := repo.Revoke("CODE", 2, now); !errors.Is(err, ErrInviteNotFound) {
+		t.Fatalf("expected other user to fail revoking, got %v", err)
+	}
+	if err := repo.Revoke("CODE", 1, now); err != nil {
+		t.Fatalf("expected creator to revoke invite, got %v", err)
+	}
+	if _, err := repo.Use("CODE", now); !errors.Is(err, ErrInviteNotUsable) {
+		t.Errorf("expected revoked invite to be unusable, got %v", err)
+	}
+}
+
+// TestFriendInviteRedeem verifies that only new friendships use up an invite.
+func TestFriendInviteRedeem(t *testing.T) {
+	now := time.Date(2023, 1, 6, 12, 0, 0, 0, time.UTC)
+	repo := newTestInviteRepo(t)
+	if err := repo.DB.AutoMigrate(&Friendships{}); err != nil {
+		t.Fatalf("failed to migrate friendships table: %v", err)
+	}
+	if _, err := repo.Create(&FriendInvite{Code: "CODE", CreatorID: 1, MaxUses: 1, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("failed to create invite: %v", err)
+	}
+	for _, friendship := range []Friendships{
+		{RequesterID: 2, RecipientID: 1, Status: Blocked, CreatedAt: now},
+		{RequesterID: 1, RecipientID: 3, Status: Accepted, CreatedAt: now},
+	} {
+		if err := repo.DB.Create(&friendship).Error; err != nil {
+			t.Fatalf("failed to create friendship: %v", err)
+		}
+	}
+
+	var tests = []struct {
+		name        string
+		redeemerID  Snowflake
+		wantCreated bool
+		wantUses    int
+		wantErr     bool
+	}{
+		{"Blocked user doesn't use up the invite", 2, false, 0, true},
+		{"Friend doesn't use up the invite", 3, false, 0, false},
+		{"New friend uses the invite", 4, true, 1, false},
+		{"Used up invite can't be redeemed", 5, false, 1, true},
+		{"Friend can't redeem a used up invite", 3, false, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, created, err := repo.Redeem("CODE", tt.redeemerID, now)
+			if (err != nil) != tt.wantErr || created != tt.wantCreated {
+				t.Errorf("got created %v (%v), want %v", created, err, tt.wantCreated)
+			}
+			invite, err := repo.FetchByCode("CODE")
+			if err != nil || invite.Uses != tt.wantUses {
+				t.Errorf("got %d uses (%v), want %d", invite.Uses, err, tt.wantUses)
+			}
+		})
+	}
+
+	var friendships int64
+	repo.DB.Model(&Friendships{}).Where("status = ? AND (requester_id = 5 OR recipient_id = 5)", Accepted).Count(&friendships)
+	if friendships != 0 {
+		t.Errorf("got a friendship for the failed redemption")
+	}
+	friends, err := (&GormFriendshipRepository{DB: repo.DB}).HavePositiveFriendshipStatus(1, 4)
+	if err != nil || !friends {
+		t.Errorf("got friends %v (%v) after redeeming, want true", friends, err)
+	}
+}
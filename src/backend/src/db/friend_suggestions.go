@@ -0,0 +1,108 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// one row of the suggestion query
+type friendSuggestionRow struct {
+	ID            Snowflake
+	Username      string
+	Avatar        string
+	MutualFriends int
+	// JSON array of the shared games, since game names can contain any separator
+	Games string
+}
+
+// the suggestion query is evaluated in a single statement:
+//   - my_friends: all accepted friends of the user
+//   - related: everyone with any friendship record (accepted, pending, blocked) with the user
+//   - mutual: friends of my_friends with the number of distinct friends in between
+//   - shared: users, who played at least one game, the user has played too
+//
+// Users only sharing games have to be discoverable to be suggested.
+const friendSuggestionsQuery = `
+WITH my_friends AS (
+	SELECT recipient_id AS id FROM friendships WHERE requester_id = @user AND status = @accepted
+	UNION
+	SELECT requester_id FROM friendships WHERE recipient_id = @user AND status = @accepted
+),
+related AS (
+	SELECT recipient_id AS id FROM friendships WHERE requester_id = @user
+	UNION
+	SELECT requester_id FROM friendships WHERE recipient_id = @user
+	UNION
+	SELECT @user
+),
+friends_of_friends AS (
+	SELECT recipient_id AS candidate_id, requester_id AS via FROM friendships
+	WHERE status = @accepted AND requester_id IN (SELECT id FROM my_friends)
+	UNION
+	SELECT requester_id, recipient_id FROM friendships
+	WHERE status = @accepted AND recipient_id IN (SELECT id FROM my_friends)
+),
+mutual AS (
+	SELECT candidate_id, COUNT(DISTINCT via) AS mutual_friends
+	FROM friends_of_friends GROUP BY candidate_id
+),
+shared AS (
+	SELECT user_id AS candidate_id, COUNT(DISTINCT game) AS shared_games, json_group_array(DISTINCT game) AS games
+	FROM sports
+	WHERE game IN (SELECT DISTINCT game FROM sports WHERE user_id = @user AND game <> '')
+	GROUP BY user_id
+),
+candidates AS (
+	SELECT candidate_id FROM mutual
+	UNION
+	SELECT candidate_id FROM shared
+)
+SELECT
+	users.id AS id,
+	users.username AS username,
+	users.avatar AS avatar,
+	COALESCE(mutual.mutual_friends, 0) AS mutual_friends,
+	COALESCE(shared.games, '[]') AS games
+FROM candidates
+JOIN users ON users.id = candidates.candidate_id
+LEFT JOIN mutual ON mutual.candidate_id = candidates.candidate_id
+LEFT JOIN shared ON shared.candidate_id = candidates.candidate_id
+LEFT JOIN user_settings ON user_settings.user_id = candidates.candidate_id
+WHERE candidates.candidate_id NOT IN (SELECT id FROM related)
+	AND (COALESCE(mutual.mutual_friends, 0) > 0 OR user_settings.discoverable = TRUE)
+ORDER BY mutual_friends DESC, COALESCE(shared.shared_games, 0) DESC, users.username
+LIMIT @limit
+`
+
+// GetSuggestions returns up to <limit> users, which are not related to <userID> in any way
+// (neither accepted, pending nor blocked), ranked by mutual accepted friends and shared games.
+func (r *GormFriendshipRepository) GetSuggestions(userID Snowflake, limit int) ([]FriendSuggestion, error) {
+	var rows []friendSuggestionRow
+	err := r.DB.Raw(
+		friendSuggestionsQuery,
+		sql.Named("user", userID),
+		sql.Named("accepted", Accepted),
+		sql.Named("limit", limit),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]FriendSuggestion, 0, len(rows))
+	for _, row := range rows {
+		games := []string{}
+		if err := json.Unmarshal([]byte(row.Games), &games); err != nil {
+			return nil, err
+		}
+		sort.Strings(games)
+		suggestions = append(suggestions, FriendSuggestion{
+			User:          PublicUser{ID: row.ID, Username: row.Username, Avatar: row.Avatar},
+			MutualFriends: row.MutualFriends,
+			SharedGames:   games,
+		})
+	}
+	return suggestions, nil
+}
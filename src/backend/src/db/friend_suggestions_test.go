@@ -0,0 +1,105 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestGetSuggestions verifies ranking and exclusion of related users.
+func TestGetSuggestions(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&User{}, &UserSettings{}, &Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := &GormFriendshipRepository{DB: database}
+	if err := repo.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate friendships: %v", err)
+	}
+
+	me := Snowflake(1)
+	friendA := Snowflake(2)
+	friendB := Snowflake(3)
+	twoMutual := Snowflake(4)       // friend of friendA and friendB
+	oneMutual := Snowflake(5)       // friend of friendA, plays overwatch too
+	gamer := Snowflake(6)           // no mutual friends, discoverable, plays overwatch
+	hiddenGamer := Snowflake(7)     // no mutual friends, not discoverable
+	pendingMutual := Snowflake(8)   // friend of friendA, but pending with me
+	blockedMutual := Snowflake(9)   // friend of friendA, but blocked by me
+	otherGameGamer := Snowflake(10) // discoverable, plays a different game
+
+	for id := Snowflake(1); id <= 10; id++ {
+		database.Create(&User{ID: id, Username: "user"})
+	}
+	database.Create(&UserSettings{UserID: gamer, Discoverable: true})
+	database.Create(&UserSettings{UserID: otherGameGamer, Discoverable: true})
+
+	friendships := []struct {
+		a, b   Snowflake
+		status FriendshipStatus
+	}{
+		{me, friendA, Accepted},
+		{friendB, me, Accepted},
+		{friendA, twoMutual, Accepted},
+		{twoMutual, friendB, Accepted},
+		{friendA, oneMutual, Accepted},
+		{friendA, pendingMutual, Accepted},
+		{pendingMutual, me, Pending},
+		{friendA, blockedMutual, Accepted},
+		{me, blockedMutual, Blocked},
+	}
+	for _, f := range friendships {
+		if err := database.Create(&Friendships{RequesterID: f.a, RecipientID: f.b, Status: f.status}).Error; err != nil {
+			t.Fatalf("failed to create friendship: %v", err)
+		}
+	}
+
+	now := time.Now()
+	for _, s := range []Sport{
+		{UserID: me, Game: "overwatch", Kind: "pushup", Amount: 1, Timedate: now},
+		{UserID: me, Game: "league", Kind: "pushup", Amount: 1, Timedate: now},
+		{UserID: oneMutual, Game: "overwatch", Kind: "pushup", Amount: 1, Timedate: now},
+		{UserID: gamer, Game: "overwatch", Kind: "squats", Amount: 1, Timedate: now},
+		{UserID: gamer, Game: "league", Kind: "squats", Amount: 1, Timedate: now},
+		{UserID: me, Game: "Warhammer 40,000: Space Marine 2", Kind: "pushup", Amount: 1, Timedate: now},
+		{UserID: gamer, Game: "Warhammer 40,000: Space Marine 2", Kind: "squats", Amount: 1, Timedate: now},
+		{UserID: hiddenGamer, Game: "overwatch", Kind: "squats", Amount: 1, Timedate: now},
+		{UserID: otherGameGamer, Game: "tft", Kind: "squats", Amount: 1, Timedate: now},
+	} {
+		if err := database.Create(&s).Error; err != nil {
+			t.Fatalf("failed to create sport: %v", err)
+		}
+	}
+
+	suggestions, err := repo.GetSuggestions(me, 10)
+	if err != nil {
+		t.Fatalf("GetSuggestions failed: %v", err)
+	}
+
+	got := make([]Snowflake, 0, len(suggestions))
+	for _, s := range suggestions {
+		got = append(got, s.User.ID)
+	}
+	if want := []Snowflake{twoMutual, oneMutual, gamer}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got suggestions %v, want %v", got, want)
+	}
+
+	if suggestions[0].MutualFriends != 2 {
+		t.Errorf("expected 2 mutual friends, got %d", suggestions[0].MutualFriends)
+	}
+	if !reflect.DeepEqual(suggestions[1].SharedGames, []string{"overwatch"}) {
+		t.Errorf("expected overwatch as shared game, got %v", suggestions[1].SharedGames)
+	}
+	// game names can contain commas
+	want := []string{"Warhammer 40,000: Space Marine 2", "league", "overwatch"}
+	if !reflect.DeepEqual(suggestions[2].SharedGames, want) || suggestions[2].MutualFriends != 0 {
+		t.Errorf("expected shared games %v and no mutual friends, got %+v", want, suggestions[2])
+	}
+}
@@ -1,9 +1,12 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"gorm.io/gorm"
 )
@@ -11,10 +14,15 @@ import (
 type FriendshipRepository interface {
 	InitRepo() error
 	GetFriendships(userID Snowflake) ([]Friendships, error)
-	CreateFriendship(requesterID Snowflake, recipientID Snowflake, status FriendshipStatus) error
-	UpdateFriendship(friendshipID Snowflake, userID Snowflake, status FriendshipStatus) error
+	// creates a friendship or accepts the pending reverse request. Returns the resulting friendship
+	CreateFriendship(requesterID Snowflake, recipientID Snowflake, status FriendshipStatus) (*Friendships, error)
+	// updates the status of a friendship. Returns the friendship and whether its status changed
+	UpdateFriendship(friendshipID Snowflake, userID Snowflake, status FriendshipStatus) (*Friendships, bool, error)
 	DeleteFriendship(friendshipID Snowflake) error
 	HavePositiveFriendshipStatus(userA Snowflake, userB Snowflake) (bool, error)
+	EstablishFriendship(userA Snowflake, userB Snowflake) error
+	GetSuggestions(userID Snowflake, limit int) ([]FriendSuggestion, error)
+	GetFriendEntries(userID Snowflake, filter FriendshipFilter) ([]FriendEntry, error)
 }
 
 type GormFriendshipRepository struct {
@@ -69,58 +77,206 @@ func (r *GormFriendshipRepository) GetFriendships(userID Snowflake) ([]Friendshi
 	return friendships, nil
 }
 
+// one row of the joined friend query
+type friendEntryRow struct {
+	ID            Snowflake
+	RequesterID   Snowflake
+	RecipientID   Snowflake
+	Status        FriendshipStatus
+	CreatedAt     time.Time
+	FriendID      Snowflake
+	Username      string
+	Discriminator string
+	Avatar        string
+	LastActivity  sql.NullString
+}
+
+// GetFriendEntries returns all friendships of <userID> matching <filter> together with the other
+// user of each friendship and the time of his last activity. Everything is loaded with one joined query,
+// so a friendship is never returned without its user. The visibility of the friends is not checked,
+// use `IVisibilityService.HideInvisible` before returning the entries.
+func (r *GormFriendshipRepository) GetFriendEntries(userID Snowflake, filter FriendshipFilter) ([]FriendEntry, error) {
+	query := r.DB.Table("friendships").
+		Select(`friendships.id, friendships.requester_id, friendships.recipient_id,
+			friendships.status, friendships.created_at,
+			users.id AS friend_id, users.username, users.discriminator, users.avatar,
+			(SELECT MAX(sports.timedate) FROM sports WHERE sports.user_id = users.id) AS last_activity`).
+		Joins(`JOIN users ON users.id = CASE WHEN friendships.requester_id = ?
+			THEN friendships.recipient_id ELSE friendships.requester_id END`, userID)
+
+	switch filter.Direction {
+	case Incoming:
+		query = query.Where("friendships.recipient_id = ?", userID)
+	case Outgoing:
+		query = query.Where("friendships.requester_id = ?", userID)
+	default:
+		query = query.Where("friendships.requester_id = ? OR friendships.recipient_id = ?", userID, userID)
+	}
+	if filter.Status != "" {
+		query = query.Where("friendships.status = ?", filter.Status)
+	}
+
+	var rows []friendEntryRow
+	if err := query.Order("friendships.created_at DESC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]FriendEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := FriendEntry{
+			Friendship: Friendships{
+				ID:          row.ID,
+				RequesterID: row.RequesterID,
+				RecipientID: row.RecipientID,
+				Status:      row.Status,
+				CreatedAt:   row.CreatedAt,
+			},
+			Direction:     Outgoing,
+			Friend:        PublicUser{ID: row.FriendID, Username: row.Username, Avatar: row.Avatar},
+			Discriminator: row.Discriminator,
+		}
+		if row.RecipientID == userID {
+			entry.Direction = Incoming
+		}
+		if row.LastActivity.Valid {
+			lastActivity, err := parseSqliteTime(row.LastActivity.String)
+			if err != nil {
+				return nil, err
+			}
+			entry.LastActivity = &lastActivity
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// aggregates like MAX lose the column type, hence SQLite returns the stored text
+func parseSqliteTime(value string) (time.Time, error) {
+	layouts := []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02T15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+		time.RFC3339Nano,
+	}
+	var err error
+	for _, layout := range layouts {
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time %q: %w", value, err)
+}
+
 // CreateFriendship creates a new friendship entry.
-func (r *GormFriendshipRepository) CreateFriendship(requesterID Snowflake, recipientID Snowflake, status FriendshipStatus) error {
+// A pending request of the recipient is accepted instead. A blocked friendship in either direction
+// is forbidden and any other existing friendship is a conflict.
+func (r *GormFriendshipRepository) CreateFriendship(requesterID Snowflake, recipientID Snowflake, status FriendshipStatus) (*Friendships, error) {
+	existing, err := r.findFriendship(requesterID, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		switch {
+		case existing.Status == Blocked:
+			return nil, repositories.Forbidden("friendship between %d and %d is blocked", requesterID, recipientID)
+		case existing.Status == Pending && existing.RequesterID == recipientID && status == Pending:
+			// the recipient already sent a request, hence directly accept it
+			existing.Status = Accepted
+			if err := r.DB.Save(existing).Error; err != nil {
+				return nil, err
+			}
+			return existing, nil
+		default:
+			return nil, repositories.Conflict("friendship between %d and %d already exists", requesterID, recipientID)
+		}
+	}
+
 	friendship := Friendships{
 		RequesterID: Snowflake(requesterID),
 		RecipientID: Snowflake(recipientID),
 		Status:      status,
 		CreatedAt:   time.Now(),
 	}
-
-	// check if there is a similiar friendship already
-	// if yes, then directly accept it
-	var maybe_existing_friendship *Friendships
-	r.DB.Where("requester_id = ? AND recipient_id = ?", recipientID, requesterID).First(&maybe_existing_friendship)
-	if maybe_existing_friendship != nil && maybe_existing_friendship.Status == Pending {
-		// directly override existing friendship to be overridden
-		maybe_existing_friendship.Status = Accepted
-		return r.DB.Save(maybe_existing_friendship).Error
+	if err := r.DB.Create(&friendship).Error; err != nil {
+		return nil, err
 	}
-
-	// create Friendship
-	return r.DB.Create(&friendship).Error
+	return &friendship, nil
 }
 
-// UpdateFriendship updates the status of an existing friendship.
-func (r *GormFriendshipRepository) UpdateFriendship(friendshipID Snowflake, userID Snowflake, status FriendshipStatus) error {
-	var friendships []Friendships
-	if err := r.DB.Find(&friendships).Error; err != nil {
+// EstablishFriendship makes userA and userB accepted friends without a pending step.
+// An existing pending friendship is accepted, a blocked one is left untouched and an error is returned.
+func (r *GormFriendshipRepository) EstablishFriendship(userA Snowflake, userB Snowflake) error {
+	if userA == userB {
+		return repositories.Conflict("user %d can't be friends with himself", userA)
+	}
+
+	existing, err := r.findFriendship(userA, userB)
+	if err != nil {
 		return err
 	}
+	if existing == nil {
+		// no friendship yet
+		return r.DB.Create(&Friendships{
+			RequesterID: userA,
+			RecipientID: userB,
+			Status:      Accepted,
+			CreatedAt:   time.Now(),
+		}).Error
+	}
 
-	for _, friendship := range friendships {
-		fmt.Printf("ID: %d, RequesterID: %d, RecipientID: %d, Status: %s, CreatedAt: %s\n",
-			friendship.ID, friendship.RequesterID, friendship.RecipientID, friendship.Status, friendship.CreatedAt)
+	switch existing.Status {
+	case Accepted:
+		return nil
+	case Blocked:
+		return repositories.Forbidden("friendship between %d and %d is blocked", userA, userB)
+	default:
+		existing.Status = Accepted
+		return r.DB.Save(existing).Error
 	}
+}
 
+// returns the friendship between userA and userB in either direction or nil, if there is none
+func (r *GormFriendshipRepository) findFriendship(userA Snowflake, userB Snowflake) (*Friendships, error) {
+	var existing Friendships
+	err := r.DB.Where(
+		"(requester_id = ? AND recipient_id = ?) OR (requester_id = ? AND recipient_id = ?)",
+		userA, userB, userB, userA,
+	).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+// UpdateFriendship updates the status of an existing friendship and returns it together with
+// whether the status changed.
+func (r *GormFriendshipRepository) UpdateFriendship(friendshipID Snowflake, userID Snowflake, status FriendshipStatus) (*Friendships, bool, error) {
 	var friendship Friendships
 	if err := r.DB.First(&friendship, friendshipID).Error; err != nil {
-		return err
+		return nil, false, err
 	}
 
 	// Check if the status update is allowed.
 	if status == Accepted {
 		// Only the recipient can accept the friend request.
 		if friendship.RecipientID != userID {
-			return fmt.Errorf("user %d is not authorized to accept this request for user %d", userID, friendship.RecipientID)
+			return nil, false, repositories.Forbidden("user %d is not authorized to accept this request for user %d", userID, friendship.RecipientID)
 		}
 	}
+	if friendship.Status == status {
+		return &friendship, false, nil
+	}
 
 	// Update the status.
 	friendship.Status = status
-	return r.DB.Save(&friendship).Error
-
+	if err := r.DB.Save(&friendship).Error; err != nil {
+		return nil, false, err
+	}
+	return &friendship, true, nil
 }
 
 // DeleteFriendship deletes a friendship record.
@@ -1,8 +1,11 @@
 package db
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -53,7 +56,7 @@ func TestHavePositiveFriendshipStatus(t *testing.T) {
 	})
 
 	t.Run("accepted friendship returns true", func(t *testing.T) {
-		if err := repo.CreateFriendship(userA, userB, Accepted); err != nil {
+		if _, err := repo.CreateFriendship(userA, userB, Accepted); err != nil {
 			t.Fatalf("failed to create accepted friendship: %v", err)
 		}
 
@@ -75,11 +78,11 @@ func TestCreateFriendshipReversePendingAutoAccept(t *testing.T) {
 	requester := Snowflake(10)
 	recipient := Snowflake(20)
 
-	if err := repo.CreateFriendship(requester, recipient, Pending); err != nil {
+	if _, err := repo.CreateFriendship(requester, recipient, Pending); err != nil {
 		t.Fatalf("failed to create initial pending friendship: %v", err)
 	}
 
-	if err := repo.CreateFriendship(recipient, requester, Pending); err != nil {
+	if _, err := repo.CreateFriendship(recipient, requester, Pending); err != nil {
 		t.Fatalf("failed to create reverse friendship: %v", err)
 	}
 
@@ -98,6 +101,44 @@ func TestCreateFriendshipReversePendingAutoAccept(t *testing.T) {
 	}
 }
 
+// TestCreateFriendshipExisting verifies that blocked friendships in either direction are forbidden
+// and that requests can't be repeated.
+func TestCreateFriendshipExisting(t *testing.T) {
+	repo := newTestFriendshipRepo(t)
+
+	blocker := Snowflake(10)
+	blocked := Snowflake(20)
+	requester := Snowflake(30)
+	if _, err := repo.CreateFriendship(blocker, blocked, Blocked); err != nil {
+		t.Fatalf("failed to create blocked friendship: %v", err)
+	}
+	if _, err := repo.CreateFriendship(requester, blocker, Pending); err != nil {
+		t.Fatalf("failed to create pending friendship: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		requester, recipient Snowflake
+		want                 error
+	}{
+		{"blocked by recipient", blocked, blocker, repositories.ErrForbidden},
+		{"blocked by requester", blocker, blocked, repositories.ErrForbidden},
+		{"repeated request", requester, blocker, repositories.ErrConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := repo.CreateFriendship(tc.requester, tc.recipient, Pending); !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	var count int64
+	repo.DB.Model(&Friendships{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 friendship rows, got %d", count)
+	}
+}
+
 // TestUpdateFriendshipAcceptAuthorization verifies only the recipient can accept a request.
 func TestUpdateFriendshipAcceptAuthorization(t *testing.T) {
 	repo := newTestFriendshipRepo(t)
@@ -106,7 +147,7 @@ func TestUpdateFriendshipAcceptAuthorization(t *testing.T) {
 	recipient := Snowflake(2)
 	outsider := Snowflake(3)
 
-	if err := repo.CreateFriendship(requester, recipient, Pending); err != nil {
+	if _, err := repo.CreateFriendship(requester, recipient, Pending); err != nil {
 		t.Fatalf("failed to create pending friendship: %v", err)
 	}
 
@@ -116,12 +157,16 @@ func TestUpdateFriendshipAcceptAuthorization(t *testing.T) {
 	}
 
 	// A third-party user cannot accept another user's incoming friend request.
-	if err := repo.UpdateFriendship(created.ID, outsider, Accepted); err == nil {
+	if _, _, err := repo.UpdateFriendship(created.ID, outsider, Accepted); err == nil {
 		t.Fatalf("expected unauthorized user to fail accepting request")
 	}
 
-	if err := repo.UpdateFriendship(created.ID, recipient, Accepted); err != nil {
-		t.Fatalf("expected recipient to accept request, got error: %v", err)
+	if _, changed, err := repo.UpdateFriendship(created.ID, recipient, Accepted); err != nil || !changed {
+		t.Fatalf("expected recipient to accept request, got changed %v and error: %v", changed, err)
+	}
+	// accepting again doesn't change the friendship
+	if _, changed, err := repo.UpdateFriendship(created.ID, recipient, Accepted); err != nil || changed {
+		t.Fatalf("expected accepting again to change nothing, got changed %v and error: %v", changed, err)
 	}
 
 	var updated Friendships
@@ -142,10 +187,10 @@ func TestGetAndDeleteFriendships(t *testing.T) {
 	userB := Snowflake(1002)
 	userC := Snowflake(1003)
 
-	if err := repo.CreateFriendship(userA, userB, Pending); err != nil {
+	if _, err := repo.CreateFriendship(userA, userB, Pending); err != nil {
 		t.Fatalf("failed to create friendship userA-userB: %v", err)
 	}
-	if err := repo.CreateFriendship(userC, userA, Accepted); err != nil {
+	if _, err := repo.CreateFriendship(userC, userA, Accepted); err != nil {
 		t.Fatalf("failed to create friendship userC-userA: %v", err)
 	}
 
@@ -172,3 +217,155 @@ func TestGetAndDeleteFriendships(t *testing.T) {
 		t.Fatalf("expected 1 friendship after delete, got %d", len(remaining))
 	}
 }
+
+// TestEstablishFriendship verifies that friendships are accepted directly, without duplicates.
+func TestEstablishFriendship(t *testing.T) {
+	repo := newTestFriendshipRepo(t)
+
+	creator := Snowflake(1)
+	pendingUser := Snowflake(2)
+	newUser := Snowflake(3)
+	blockedUser := Snowflake(4)
+
+	if _, err := repo.CreateFriendship(pendingUser, creator, Pending); err != nil {
+		t.Fatalf("failed to create pending friendship: %v", err)
+	}
+	if _, err := repo.CreateFriendship(creator, blockedUser, Blocked); err != nil {
+		t.Fatalf("failed to create blocked friendship: %v", err)
+	}
+
+	// existing pending friendships are accepted instead of duplicated
+	if err := repo.EstablishFriendship(creator, pendingUser); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.EstablishFriendship(creator, newUser); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// establishing twice is a no-op
+	if err := repo.EstablishFriendship(newUser, creator); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.EstablishFriendship(creator, blockedUser); err == nil {
+		t.Fatalf("expected blocked friendship to fail")
+	}
+
+	for _, friend := range []Snowflake{pendingUser, newUser} {
+		ok, err := repo.HavePositiveFriendshipStatus(creator, friend)
+		if err != nil || !ok {
+			t.Errorf("expected accepted friendship with %d, got %v (%v)", friend, ok, err)
+		}
+	}
+
+	friendships, err := repo.GetFriendships(creator)
+	if err != nil {
+		t.Fatalf("GetFriendships failed: %v", err)
+	}
+	if len(friendships) != 3 {
+		t.Errorf("expected 3 friendships, got %d", len(friendships))
+	}
+}
+
+// TestGetFriendEntries verifies the joined friend list with filters and visibility of activities.
+func TestGetFriendEntries(t *testing.T) {
+	repo := newTestFriendshipRepo(t)
+	if err := repo.DB.AutoMigrate(&User{}, &UserSettings{}, &Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	me := Snowflake(1)
+	friend := Snowflake(2)
+	incoming := Snowflake(3)
+	outgoing := Snowflake(4)
+	privateFriend := Snowflake(5)
+
+	for _, u := range []User{
+		{ID: me, Username: "me"},
+		{ID: friend, Username: "friend"},
+		{ID: incoming, Username: "incoming"},
+		{ID: outgoing, Username: "outgoing"},
+		{ID: privateFriend, Username: "private", Email: "private@example.com"},
+	} {
+		repo.DB.Create(&u)
+	}
+	settings := DefaultUserSettings(privateFriend)
+	settings.ActivitiesVisibility = Private
+	settings.StreakVisibility = Private
+	repo.DB.Create(&settings)
+
+	lastActivity := time.Date(2023, 1, 6, 12, 0, 0, 0, time.UTC)
+	repo.DB.Create(&Sport{UserID: friend, Kind: "pushup", Amount: 1, Timedate: lastActivity.Add(-time.Hour)})
+	repo.DB.Create(&Sport{UserID: friend, Kind: "pushup", Amount: 1, Timedate: lastActivity})
+	repo.DB.Create(&Sport{UserID: privateFriend, Kind: "pushup", Amount: 1, Timedate: lastActivity})
+
+	for _, f := range []struct {
+		a, b   Snowflake
+		status FriendshipStatus
+	}{
+		{me, friend, Accepted},
+		{incoming, me, Pending},
+		{me, outgoing, Pending},
+		{privateFriend, me, Accepted},
+	} {
+		if _, err := repo.CreateFriendship(f.a, f.b, f.status); err != nil {
+			t.Fatalf("failed to create friendship: %v", err)
+		}
+	}
+
+	t.Run("all friendships with embedded users", func(t *testing.T) {
+		entries, err := repo.GetFriendEntries(me, FriendshipFilter{})
+		if err != nil {
+			t.Fatalf("GetFriendEntries failed: %v", err)
+		}
+		if len(entries) != 4 {
+			t.Fatalf("expected 4 entries, got %d", len(entries))
+		}
+		visibility := NewVisibilityService(repo, &GormUserSettingsRepository{DB: repo.DB})
+		if err := visibility.HideInvisible(me, entries); err != nil {
+			t.Fatalf("HideInvisible failed: %v", err)
+		}
+
+		byFriend := make(map[Snowflake]FriendEntry)
+		for _, e := range entries {
+			byFriend[e.Friend.ID] = e
+		}
+		if e := byFriend[friend]; e.LastActivity == nil || !e.LastActivity.Equal(lastActivity) || !e.StreakVisible {
+			t.Errorf("expected visible last activity %v of friend, got %+v", lastActivity, e)
+		}
+		if e := byFriend[privateFriend]; e.LastActivity != nil || e.StreakVisible {
+			t.Errorf("expected hidden activity of private friend, got %+v", e)
+		}
+		if byFriend[incoming].Direction != Incoming || byFriend[outgoing].Direction != Outgoing {
+			t.Errorf("unexpected directions: %v %v", byFriend[incoming].Direction, byFriend[outgoing].Direction)
+		}
+	})
+
+	var filterTests = []struct {
+		name   string
+		filter FriendshipFilter
+		want   []Snowflake
+	}{
+		{"accepted only", FriendshipFilter{Status: Accepted}, []Snowflake{friend, privateFriend}},
+		{"incoming pending", FriendshipFilter{Status: Pending, Direction: Incoming}, []Snowflake{incoming}},
+		{"outgoing pending", FriendshipFilter{Status: Pending, Direction: Outgoing}, []Snowflake{outgoing}},
+	}
+	for _, tt := range filterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := repo.GetFriendEntries(me, tt.filter)
+			if err != nil {
+				t.Fatalf("GetFriendEntries failed: %v", err)
+			}
+			got := make(map[Snowflake]bool)
+			for _, e := range entries {
+				got[e.Friend.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("expected %d in result %v", id, got)
+				}
+			}
+		})
+	}
+}
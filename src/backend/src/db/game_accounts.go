@@ -0,0 +1,138 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGameAccountNotFound = repositories.NotFound("game account not found")
+	ErrGameAccountLinked   = repositories.Conflict("an account of this connector is already linked")
+)
+
+// GameAccountRepository defines the interface for managing linked game accounts in the database.
+func NewGormGameAccountRepository(database *gorm.DB) repositories.GameAccountRepository {
+	repo := &GormGameAccountRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `GameAccountRepository` for GORM
+type GormGameAccountRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the GameAccount and ProcessedMatch GORM tables
+func (r *GormGameAccountRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&GameAccount{}, &ProcessedMatch{})
+}
+
+// Creates a new GameAccount record in the DB, if the user has no account of the connector yet.
+func (r *GormGameAccountRepository) Create(account *GameAccount) (*GameAccount, error) {
+	account.ID = 0 // ensure that GORM creates a new record
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var linked int64
+		err := tx.Model(&GameAccount{}).
+			Where(&GameAccount{UserID: account.UserID, Connector: account.Connector}).
+			Count(&linked).Error
+		if err != nil {
+			return err
+		}
+		if linked > 0 {
+			return fmt.Errorf("%w: %s", ErrGameAccountLinked, account.Connector)
+		}
+		return tx.Create(account).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Returns all accounts linked by <userID>
+func (r *GormGameAccountRepository) FetchByUserID(userID Snowflake) ([]GameAccount, error) {
+	var accounts []GameAccount
+	err := r.DB.Where(&GameAccount{UserID: userID}).Order("created_at").Find(&accounts).Error
+	return accounts, err
+}
+
+// Deletes the account <id>, if it was linked by <userID>
+func (r *GormGameAccountRepository) Delete(id Snowflake, userID Snowflake) error {
+	result := r.DB.Where(&GameAccount{ID: id, UserID: userID}).Delete(&GameAccount{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrGameAccountNotFound, id)
+	}
+	return nil
+}
+
+// Returns up to <limit> accounts of <connector>, which were never polled or not since <polledBefore>.
+// Accounts polled the longest time ago come first
+func (r *GormGameAccountRepository) FetchDue(connector string, polledBefore time.Time, limit int) ([]GameAccount, error) {
+	var accounts []GameAccount
+	err := r.DB.
+		Where("connector = ? AND (last_polled_at IS NULL OR last_polled_at < ?)", connector, polledBefore).
+		Order("last_polled_at IS NOT NULL, last_polled_at").
+		Limit(limit).
+		Find(&accounts).Error
+	return accounts, err
+}
+
+// Returns the IDs of the processed matches of <account>, which ended after <since>
+func (r *GormGameAccountRepository) FetchProcessedMatchIDs(account *GameAccount, since time.Time) (map[string]bool, error) {
+	var matchIDs []string
+	err := r.DB.Model(&ProcessedMatch{}).
+		Where("user_id = ? AND connector = ? AND ended_at >= ?", account.UserID, account.Connector, since).
+		Pluck("match_id", &matchIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(matchIDs))
+	for _, id := range matchIDs {
+		known[id] = true
+	}
+	return known, nil
+}
+
+// Stores the <matches>, which were not processed yet, adds their deaths to the overdue deaths
+// of their game and marks <account> as polled in one transaction. Returns the newly processed matches
+func (r *GormGameAccountRepository) RecordMatches(
+	account *GameAccount,
+	matches []ProcessedMatch,
+	now time.Time,
+) ([]ProcessedMatch, error) {
+	processed := []ProcessedMatch{}
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, match := range matches {
+			match.UserID = account.UserID
+			match.Connector = account.Connector
+			match.ProcessedAt = now
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&match)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				// the match was processed before
+				continue
+			}
+			if err := addOverdueDeaths(tx, account.UserID, match.Game, match.Deaths); err != nil {
+				return err
+			}
+			processed = append(processed, match)
+		}
+
+		account.LastPolledAt = &now
+		return tx.Model(account).Select("last_polled_at").Updates(account).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return processed, nil
+}
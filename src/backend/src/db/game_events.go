@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrEventSecretNotFound = repositories.NotFound("no event signing secret was created")
+	ErrGameEventNotFound   = repositories.NotFound("game event not found")
+)
+
+// GameEventRepository defines the interface for managing signing secrets and game events in the database.
+// The signing secrets are encrypted with <tokens>
+func NewGormGameEventRepository(database *gorm.DB, tokens *TokenCipher) repositories.GameEventRepository {
+	repo := &GormGameEventRepository{DB: database, Tokens: tokens}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `GameEventRepository` for GORM
+type GormGameEventRepository struct {
+	DB     *gorm.DB
+	Tokens *TokenCipher
+}
+
+// automigrates the EventSigningSecret and GameEvent GORM tables and encrypts secrets stored in
+// plain text by older versions
+func (r *GormGameEventRepository) InitRepo() error {
+	if err := r.DB.AutoMigrate(&EventSigningSecret{}, &GameEvent{}); err != nil {
+		return err
+	}
+
+	var secrets []EventSigningSecret
+	if err := r.DB.Find(&secrets).Error; err != nil {
+		return err
+	}
+	for _, secret := range secrets {
+		if IsEncrypted(secret.Secret) {
+			continue
+		}
+		if _, err := r.SaveSecret(&secret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the signing secret of <userID> or nil, if there is none. Secrets, which can't be
+// decrypted anymore, e.g. after the key changed, count as missing. The user has to create a new one
+func (r *GormGameEventRepository) FetchSecret(userID Snowflake) (*EventSigningSecret, error) {
+	var secret EventSigningSecret
+	err := r.DB.Where(&EventSigningSecret{UserID: userID}).First(&secret).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.Secret, err = r.Tokens.Decrypt(secret.Secret); err != nil {
+		log.Printf("ignoring the event signing secret of user %d: %v", userID, err)
+		return nil, nil
+	}
+	return &secret, nil
+}
+
+// Creates or replaces the signing secret of its user. The secret of <secret> stays in plain text,
+// only the stored one is encrypted
+func (r *GormGameEventRepository) SaveSecret(secret *EventSigningSecret) (*EventSigningSecret, error) {
+	stored := *secret
+	var err error
+	if stored.Secret, err = r.Tokens.Encrypt(secret.Secret); err != nil {
+		return nil, err
+	}
+	if err := r.DB.Save(&stored).Error; err != nil {
+		return nil, err
+	}
+	secret.CreatedAt = stored.CreatedAt
+	return secret, nil
+}
+
+// Deletes the signing secret of <userID>
+func (r *GormGameEventRepository) DeleteSecret(userID Snowflake) error {
+	result := r.DB.Where(&EventSigningSecret{UserID: userID}).Delete(&EventSigningSecret{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrEventSecretNotFound
+	}
+	return nil
+}
+
+// Stores <event>, unless an event with the same ID was stored for its user before
+func (r *GormGameEventRepository) Record(event *GameEvent) (bool, error) {
+	result := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Returns the event <eventID> of <userID>
+func (r *GormGameEventRepository) FetchEvent(userID Snowflake, eventID string) (*GameEvent, error) {
+	var event GameEvent
+	err := r.DB.Where(&GameEvent{UserID: userID, EventID: eventID}).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrGameEventNotFound, eventID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Sets status and error of the stored <event>
+func (r *GormGameEventRepository) UpdateStatus(event *GameEvent) error {
+	return r.DB.Model(&GameEvent{}).
+		Where(&GameEvent{UserID: event.UserID, EventID: event.EventID}).
+		Select("status", "error").
+		Updates(event).Error
+}
+
+// Returns up to <limit> events of <userID>, newest first
+func (r *GormGameEventRepository) FetchByUserID(userID Snowflake, limit int) ([]GameEvent, error) {
+	var events []GameEvent
+	err := r.DB.Where(&GameEvent{UserID: userID}).Order("received_at desc").Limit(limit).Find(&events).Error
+	return events, err
+}
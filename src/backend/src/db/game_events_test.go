@@ -0,0 +1,58 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestEventSigningSecretsEncrypted verifies that signing secrets are only stored encrypted, including
+// the plain text secrets of older versions, and that secrets of another key count as missing.
+func TestEventSigningSecretsEncrypted(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&EventSigningSecret{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// stored by an older version
+	database.Create(&EventSigningSecret{UserID: 2, Secret: "gths_old", Prefix: "gths_old"})
+
+	tokens, err := NewTokenCipher("secret")
+	if err != nil {
+		t.Fatalf("failed to create token cipher: %v", err)
+	}
+	repo := NewGormGameEventRepository(database, tokens)
+	secret, err := repo.SaveSecret(&EventSigningSecret{UserID: 1, Secret: "gths_new", Prefix: "gths_new"})
+	if err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	if secret.Secret != "gths_new" {
+		t.Errorf("got secret %q of the saved secret, want it in plain text", secret.Secret)
+	}
+
+	var stored []EventSigningSecret
+	database.Order("user_id").Find(&stored)
+	for _, s := range stored {
+		if !strings.HasPrefix(s.Secret, encryptedTokenPrefix) {
+			t.Errorf("got stored secret %q, want it encrypted", s.Secret)
+		}
+	}
+
+	for userID, want := range map[Snowflake]string{1: "gths_new", 2: "gths_old"} {
+		secret, err := repo.FetchSecret(userID)
+		if err != nil || secret == nil || secret.Secret != want {
+			t.Errorf("got secret %+v, %v of user %d; want %q", secret, err, userID, want)
+		}
+	}
+
+	otherTokens, _ := NewTokenCipher("other secret")
+	otherRepo := &GormGameEventRepository{DB: database, Tokens: otherTokens}
+	if secret, err := otherRepo.FetchSecret(1); err != nil || secret != nil {
+		t.Errorf("got secret %+v, %v with another key; want none", secret, err)
+	}
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrGameSessionNotFound           = repositories.NotFound("game session not found")
+	ErrGameSessionLive               = repositories.Conflict("there is already a live game session")
+	ErrGameSessionClosed             = repositories.Conflict("game session is already closed")
+	ErrGameSessionDeathsBelowCounted = repositories.Conflict("deaths can't be less than the deaths counted with the live death counter")
+)
+
+// GameSessionRepository defines the interface for managing game sessions in the database.
+func NewGormGameSessionRepository(database *gorm.DB) repositories.GameSessionRepository {
+	repo := &GormGameSessionRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `GameSessionRepository` for GORM
+type GormGameSessionRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the GameSession GORM table
+func (r *GormGameSessionRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&GameSession{})
+}
+
+// Creates a new live GameSession record in the DB, if the user has no live session yet.
+func (r *GormGameSessionRepository) Create(session *GameSession) (*GameSession, error) {
+	session.ID = 0 // ensure that GORM creates a new record
+	session.EndedAt = nil
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var live int64
+		err := tx.Model(&GameSession{}).
+			Where("user_id = ? AND ended_at IS NULL", session.UserID).
+			Count(&live).Error
+		if err != nil {
+			return err
+		}
+		if live > 0 {
+			return ErrGameSessionLive
+		}
+		return tx.Create(session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Returns the session with <id>
+func (r *GormGameSessionRepository) Fetch(id Snowflake) (*GameSession, error) {
+	var session GameSession
+	if err := r.DB.First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrGameSessionNotFound, id)
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Returns the live session of <userID> or nil, if there is none
+func (r *GormGameSessionRepository) FetchLive(userID Snowflake) (*GameSession, error) {
+	var session GameSession
+	err := r.DB.Where("user_id = ? AND ended_at IS NULL", userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Returns the sessions of <userID>, newest first
+func (r *GormGameSessionRepository) FetchByUserID(userID Snowflake, limit int, offset int) ([]GameSession, error) {
+	var sessions []GameSession
+	err := r.DB.Where(&GameSession{UserID: userID}).
+		Order("started_at desc").
+		Limit(limit).
+		Offset(offset).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Updates game, deaths and outcome of <session>. The deaths can't be set below the deaths, which
+// the live death counter added already. Deaths it adds meanwhile are kept on top of the new deaths
+func (r *GormGameSessionRepository) Update(session *GameSession) (*GameSession, error) {
+	if session.Deaths < session.OverdueDeaths {
+		return nil, fmt.Errorf("%w: %d were counted", ErrGameSessionDeathsBelowCounted, session.OverdueDeaths)
+	}
+	result := r.DB.Model(&GameSession{}).
+		Where("id = ? AND user_id = ?", session.ID, session.UserID).
+		Updates(map[string]any{
+			"game": session.Game,
+			// the counter adds to deaths and overdue_deaths of live sessions
+			"deaths":  gorm.Expr("? + overdue_deaths - ?", session.Deaths, session.OverdueDeaths),
+			"outcome": session.Outcome,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrGameSessionNotFound, session.ID)
+	}
+	return r.Fetch(session.ID)
+}
+
+// Ends the live session <id> of <userID> and adds <overdueDeaths> to the overdue deaths of its game
+// in one transaction
+func (r *GormGameSessionRepository) Close(
+	id Snowflake,
+	userID Snowflake,
+	endedAt time.Time,
+	overdueDeaths int64,
+) (*GameSession, error) {
+	var session GameSession
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where(&GameSession{ID: id, UserID: userID}).First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %d", ErrGameSessionNotFound, id)
+		}
+		if err != nil {
+			return err
+		}
+		if !session.IsLive() {
+			return ErrGameSessionClosed
+		}
+
+		session.EndedAt = &endedAt
+		session.OverdueDeaths += overdueDeaths
+		err = tx.Model(&session).Select("ended_at", "overdue_deaths").Updates(&session).Error
+		if err != nil {
+			return err
+		}
+		return addOverdueDeaths(tx, userID, session.Game, overdueDeaths)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Returns the sports linked to the session <id>, oldest first
+func (r *GormGameSessionRepository) FetchSports(id Snowflake) ([]Sport, error) {
+	var sports []Sport
+	err := r.DB.Where("session_id = ?", id).Order("timedate").Find(&sports).Error
+	return sports, err
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestGameSessionRepo(t *testing.T) (repositories.GameSessionRepository, *gorm.DB) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&Sport{}, &OverdueDeaths{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return NewGormGameSessionRepository(database), database
+}
+
+func TestGameSessionLifecycle(t *testing.T) {
+	repo, database := newTestGameSessionRepo(t)
+	start := time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC)
+
+	session, err := repo.Create(&GameSession{UserID: 1, Game: "overwatch", StartedAt: start})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if _, err := repo.Create(&GameSession{UserID: 1, Game: "league", StartedAt: start}); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("got %v for a second live session, want ErrConflict", err)
+	}
+	if _, err := repo.Create(&GameSession{UserID: 2, Game: "league", StartedAt: start}); err != nil {
+		t.Errorf("failed to create the live session of another user: %v", err)
+	}
+
+	live, err := repo.FetchLive(1)
+	if err != nil || live == nil || live.ID != session.ID {
+		t.Fatalf("got live session %+v, %v; want %d", live, err, session.ID)
+	}
+
+	session.Deaths = 4
+	session.Outcome = OutcomeWin
+	if _, err := repo.Update(session); err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+
+	sessionID := session.ID
+	database.Create(&Sport{UserID: 1, Kind: "pushup", Game: "overwatch", Amount: 20, Timedate: start, SessionID: &sessionID})
+	database.Create(&Sport{UserID: 1, Kind: "pushup", Game: "overwatch", Amount: 10, Timedate: start})
+	database.Create(&OverdueDeaths{UserID: 1, Game: "overwatch", Count: 2})
+
+	closed, err := repo.Close(session.ID, 1, start.Add(2*time.Hour), 3)
+	if err != nil {
+		t.Fatalf("failed to close session: %v", err)
+	}
+	if closed.IsLive() || closed.Deaths != 4 || closed.Outcome != OutcomeWin || closed.OverdueDeaths != 3 {
+		t.Errorf("got closed session %+v", closed)
+	}
+	if _, err := repo.Close(session.ID, 1, start.Add(3*time.Hour), 0); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("got %v when closing twice, want ErrConflict", err)
+	}
+	if _, err := repo.Close(session.ID, 2, start.Add(3*time.Hour), 0); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("got %v when closing the session of another user, want ErrNotFound", err)
+	}
+	if live, _ := repo.FetchLive(1); live != nil {
+		t.Errorf("got live session %+v after closing it", live)
+	}
+
+	// the overdue deaths of the session are added to the existing ones
+	var overdue OverdueDeaths
+	database.Where(&OverdueDeaths{UserID: 1, Game: "overwatch"}).First(&overdue)
+	if overdue.Count != 5 {
+		t.Errorf("got %d overdue deaths, want 5", overdue.Count)
+	}
+
+	sports, err := repo.FetchSports(session.ID)
+	if err != nil || len(sports) != 1 {
+		t.Fatalf("got linked sports %+v, %v; want one", sports, err)
+	}
+	stats := NewGameSessionStats(closed, sports, start.Add(5*time.Hour))
+	if stats.DurationSeconds != 7200 || stats.DeathsPerHour != 2 || stats.Exercises != 20 || stats.ExercisesPerDeath != 5 {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+// TestGameSessionUpdateKeepsCountedDeaths verifies that setting the deaths neither drops deaths of
+// the live death counter nor goes below them
+func TestGameSessionUpdateKeepsCountedDeaths(t *testing.T) {
+	repo, database := newTestGameSessionRepo(t)
+	counter := NewGormLiveDeathRepository(database)
+	start := time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC)
+
+	created, err := repo.Create(&GameSession{UserID: 1, Game: "overwatch", StartedAt: start})
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if err := counter.Flush([]LiveDeathCount{{UserID: 1, Game: "overwatch", Count: 3}}); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	session, err := repo.Fetch(created.ID)
+	if err != nil {
+		t.Fatalf("failed to fetch session: %v", err)
+	}
+
+	// the counter adds deaths, after the session was fetched
+	if err := counter.Flush([]LiveDeathCount{{UserID: 1, Game: "overwatch", Count: 2}}); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	session.Deaths = 5
+	updated, err := repo.Update(session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v", err)
+	}
+	if updated.Deaths != 7 || updated.OverdueDeaths != 5 {
+		t.Errorf("got %d deaths, %d overdue; want 7, 5", updated.Deaths, updated.OverdueDeaths)
+	}
+
+	updated.Deaths = 4
+	if _, err := repo.Update(updated); !errors.Is(err, repositories.ErrConflict) {
+		t.Errorf("got %v for deaths below the counted ones, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobRunRepository defines the interface for managing the runs of background jobs in the database.
+func NewGormJobRunRepository(database *gorm.DB) repositories.JobRunRepository {
+	repo := &GormJobRunRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `JobRunRepository` for GORM
+type GormJobRunRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the JobRun GORM table
+func (r *GormJobRunRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&JobRun{})
+}
+
+// Returns the run of the job <name>, which is created, if it doesn't exist yet
+func (r *GormJobRunRepository) Fetch(name string) (*JobRun, error) {
+	run := JobRun{Name: name}
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error; err != nil {
+		return nil, err
+	}
+	if err := r.DB.Where(&JobRun{Name: name}).First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Locks the job <name> for <owner> with a single UPDATE, which only matches an unlocked job whose
+// last run is still <lastRunAt>
+func (r *GormJobRunRepository) Lock(name string, owner string, lastRunAt *time.Time, now time.Time, until time.Time) (bool, error) {
+	query := r.DB.Model(&JobRun{}).
+		Where("name = ? AND (locked_until IS NULL OR locked_until < ?)", name, now)
+	if lastRunAt == nil {
+		query = query.Where("last_run_at IS NULL")
+	} else {
+		query = query.Where("last_run_at = ?", *lastRunAt)
+	}
+	result := query.Updates(map[string]any{"locked_by": owner, "locked_until": until})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Stores the run and releases the lock, as long as <owner> still holds it
+func (r *GormJobRunRepository) Finish(name string, owner string, startedAt time.Time, duration time.Duration, runErr error) error {
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	return r.DB.Model(&JobRun{}).
+		Where("name = ? AND locked_by = ?", name, owner).
+		Updates(map[string]any{
+			"last_run_at":      startedAt,
+			"last_duration_ms": duration.Milliseconds(),
+			"last_error":       lastError,
+			"locked_by":        "",
+			"locked_until":     nil,
+		}).Error
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LiveDeathRepository defines the interface for persisting the live death counter.
+func NewGormLiveDeathRepository(database *gorm.DB) repositories.LiveDeathRepository {
+	repo := &GormLiveDeathRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `LiveDeathRepository` for GORM
+type GormLiveDeathRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the LiveDeathCount GORM table
+func (r *GormLiveDeathRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&LiveDeathCount{})
+}
+
+// Returns all counts, which were not flushed yet
+func (r *GormLiveDeathRepository) FetchAll() ([]LiveDeathCount, error) {
+	var counts []LiveDeathCount
+	err := r.DB.Order("user_id, game").Find(&counts).Error
+	return counts, err
+}
+
+// Stores <counts> in one transaction. Counts of 0 are removed
+func (r *GormLiveDeathRepository) Save(counts []LiveDeathCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, count := range counts {
+			var err error
+			if count.Count == 0 {
+				err = tx.Where(&LiveDeathCount{UserID: count.UserID, Game: count.Game}).Delete(&LiveDeathCount{}).Error
+			} else {
+				err = tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&count).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Adds <counts> to the overdue deaths, the deaths of the live game session of the same game and
+// removes them from the snapshot in one transaction
+func (r *GormLiveDeathRepository) Flush(counts []LiveDeathCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, count := range counts {
+			if err := addOverdueDeaths(tx, count.UserID, count.Game, count.Count); err != nil {
+				return err
+			}
+			err := tx.Model(&GameSession{}).
+				Where("user_id = ? AND game = ? AND ended_at IS NULL", count.UserID, count.Game).
+				Updates(map[string]any{
+					"deaths":         gorm.Expr("deaths + ?", count.Count),
+					"overdue_deaths": gorm.Expr("overdue_deaths + ?", count.Count),
+				}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where(&LiveDeathCount{UserID: count.UserID, Game: count.Game}).Delete(&LiveDeathCount{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// INotificationService informs users about changes other users made, like friend requests.
+// It is shared by all controllers, which change state of more than one user.
+type INotificationService interface {
+	Notify(userID Snowflake, notificationType NotificationType, actorID Snowflake, subjectID Snowflake) (*Notification, error)
+}
+
+// NotificationChannel delivers notifications outside of the app, e.g. by email
+type NotificationChannel interface {
+	Deliver(ctx context.Context, notification Notification) error
+}
+
+// NotificationService stores notifications in the inbox of the users and delivers them
+// to every channel
+type NotificationService struct {
+	Repo     repositories.NotificationRepository
+	Channels []NotificationChannel
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewNotificationService(
+	repo repositories.NotificationRepository,
+	channels []NotificationChannel,
+	Now func() time.Time,
+) *NotificationService {
+	return &NotificationService{Repo: repo, Channels: channels, Now: Now}
+}
+
+// Notify adds a notification of <notificationType> to the inbox of <userID>. Users are not
+// notified about their own actions. Channels are served in the background, so that slow
+// mail servers don't delay the request
+func (s *NotificationService) Notify(
+	userID Snowflake,
+	notificationType NotificationType,
+	actorID Snowflake,
+	subjectID Snowflake,
+) (*Notification, error) {
+	if userID == actorID {
+		return nil, nil
+	}
+	notification, err := s.Repo.Create(&Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		ActorID:   actorID,
+		SubjectID: subjectID,
+		CreatedAt: s.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range s.Channels {
+		go func(channel NotificationChannel, notification Notification) {
+			if err := channel.Deliver(context.Background(), notification); err != nil {
+				log.Printf("Failed to deliver notification %d to user %d: %v", notification.ID, notification.UserID, err)
+			}
+		}(channel, *notification)
+	}
+	return notification, nil
+}
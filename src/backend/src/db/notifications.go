@@ -0,0 +1,99 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrNotificationNotFound = repositories.NotFound("notification not found")
+
+// NotificationRepository defines the interface for managing notifications in the database.
+func NewGormNotificationRepository(database *gorm.DB) repositories.NotificationRepository {
+	repo := &GormNotificationRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `NotificationRepository` for GORM
+type GormNotificationRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the Notification GORM table
+func (r *GormNotificationRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&Notification{})
+}
+
+// Creates a new Notification record in the DB.
+func (r *GormNotificationRepository) Create(notification *Notification) (*Notification, error) {
+	notification.ID = 0 // ensure that GORM creates a new record
+	if err := r.DB.Create(notification).Error; err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// Returns the notifications of <userID> matching <filter>, newest first
+func (r *GormNotificationRepository) FetchByUserID(userID Snowflake, filter NotificationFilter) ([]Notification, error) {
+	query := r.DB.Where("user_id = ?", userID)
+	if filter.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if filter.Before != 0 {
+		query = query.Where("id < ?", filter.Before)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	notifications := []Notification{}
+	err := query.Order("id desc").Find(&notifications).Error
+	return notifications, err
+}
+
+// Counts the notifications of <userID>, which were not read yet
+func (r *GormNotificationRepository) CountUnread(userID Snowflake) (int64, error) {
+	var count int64
+	err := r.DB.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// Marks the notification <id> of <userID> as read. Notifications, which were read already, keep their read time
+func (r *GormNotificationRepository) MarkRead(userID Snowflake, id Snowflake, now time.Time) error {
+	result := r.DB.Model(&Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := r.DB.Model(&Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %d", ErrNotificationNotFound, id)
+	}
+	return nil
+}
+
+// Marks all unread notifications of <userID> as read
+func (r *GormNotificationRepository) MarkAllRead(userID Snowflake, now time.Time) (int64, error) {
+	result := r.DB.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// Deletes all notifications, which were read before <before>
+func (r *GormNotificationRepository) DeleteReadBefore(before time.Time) (int64, error) {
+	result := r.DB.Where("read_at < ?", before).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
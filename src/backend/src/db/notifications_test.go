@@ -0,0 +1,127 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestNotificationService builds a service with an isolated in-memory repository for each test.
+func newTestNotificationService(t *testing.T, now time.Time) *NotificationService {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+
+	repo := &GormNotificationRepository{DB: database}
+	if err := repo.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate notifications table: %v", err)
+	}
+	return NewNotificationService(repo, nil, func() time.Time { return now })
+}
+
+// TestNotificationPages verifies that pages follow each other without gaps and respect the unread filter.
+func TestNotificationPages(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := newTestNotificationService(t, now)
+	repo := service.Repo
+
+	var created []Snowflake
+	for actor := Snowflake(10); actor < 15; actor++ {
+		notification, err := service.Notify(1, NotificationFriendRequest, actor, 0)
+		if err != nil {
+			t.Fatalf("failed to notify: %v", err)
+		}
+		created = append(created, notification.ID)
+	}
+	if _, err := service.Notify(2, NotificationFriendRequest, 10, 0); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+
+	// users are not notified about their own actions
+	if notification, err := service.Notify(1, NotificationFriendAccepted, 1, 0); err != nil || notification != nil {
+		t.Fatalf("got notification %+v (%v) about own action, want none", notification, err)
+	}
+
+	first, err := repo.FetchByUserID(1, NotificationFilter{Limit: 3})
+	if err != nil {
+		t.Fatalf("failed to fetch notifications: %v", err)
+	}
+	second, err := repo.FetchByUserID(1, NotificationFilter{Limit: 3, Before: first[len(first)-1].ID})
+	if err != nil {
+		t.Fatalf("failed to fetch notifications: %v", err)
+	}
+	got := append(first, second...)
+	if len(got) != len(created) {
+		t.Fatalf("got %d notifications, want %d", len(got), len(created))
+	}
+	for i, notification := range got {
+		if want := created[len(created)-1-i]; notification.ID != want {
+			t.Errorf("notification %d: got ID %d, want %d", i, notification.ID, want)
+		}
+	}
+
+	if err := repo.MarkRead(1, created[0], now); err != nil {
+		t.Fatalf("failed to mark notification as read: %v", err)
+	}
+	// marking it again keeps it read
+	if err := repo.MarkRead(1, created[0], now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to mark notification as read again: %v", err)
+	}
+	if err := repo.MarkRead(2, created[1], now); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("got %v for the notification of another user, want ErrNotificationNotFound", err)
+	}
+
+	unread, err := repo.FetchByUserID(1, NotificationFilter{UnreadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to fetch unread notifications: %v", err)
+	}
+	if len(unread) != 4 || unread[len(unread)-1].ID != created[1] {
+		t.Errorf("got %d unread notifications, want 4 without the read one", len(unread))
+	}
+
+	marked, err := repo.MarkAllRead(1, now)
+	if err != nil || marked != 4 {
+		t.Errorf("marked %d notifications as read (%v), want 4", marked, err)
+	}
+	for userID, want := range map[Snowflake]int64{1: 0, 2: 1} {
+		count, err := repo.CountUnread(userID)
+		if err != nil || count != want {
+			t.Errorf("user %d: got %d unread notifications (%v), want %d", userID, count, err, want)
+		}
+	}
+}
+
+// TestNotificationDeleteReadBefore verifies that only notifications read before the given time are deleted.
+func TestNotificationDeleteReadBefore(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := newTestNotificationService(t, now)
+	repo := service.Repo
+
+	old, _ := service.Notify(1, NotificationFriendRequest, 2, 0)
+	recent, _ := service.Notify(1, NotificationFriendRequest, 3, 0)
+	if _, err := service.Notify(1, NotificationFriendRequest, 4, 0); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+	if err := repo.MarkRead(1, old.ID, now.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("failed to mark notification as read: %v", err)
+	}
+	if err := repo.MarkRead(1, recent.ID, now); err != nil {
+		t.Fatalf("failed to mark notification as read: %v", err)
+	}
+
+	deleted, err := repo.DeleteReadBefore(now.Add(-24 * time.Hour))
+	if err != nil || deleted != 1 {
+		t.Errorf("deleted %d notifications (%v), want 1", deleted, err)
+	}
+	remaining, err := repo.FetchByUserID(1, NotificationFilter{})
+	if err != nil || len(remaining) != 2 {
+		t.Errorf("got %d remaining notifications (%v), want 2", len(remaining), err)
+	}
+}
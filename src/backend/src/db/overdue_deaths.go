@@ -3,6 +3,7 @@ package db
 import (
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OverdueDeathRepository defines the interface for managing overdue deaths in the database.
@@ -75,3 +76,14 @@ func (r *GormOverdueDeathsRepository) FetchAll(userID Snowflake) ([]OverdueDeath
 func (r *GormOverdueDeathsRepository) Delete(userID Snowflake, game string) error {
 	return r.DB.Where(&OverdueDeaths{UserID: userID, Game: game}).Delete(&OverdueDeaths{}).Error
 }
+
+// adds <count> to the overdue deaths of <userID> in <game>, creating the record if needed
+func addOverdueDeaths(tx *gorm.DB, userID Snowflake, game string, count int64) error {
+	if count == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "game"}},
+		DoUpdates: clause.Assignments(map[string]any{"count": gorm.Expr("overdue_deaths.count + ?", count)}),
+	}).Create(&OverdueDeaths{UserID: userID, Game: game, Count: count}).Error
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
-	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"gorm.io/gorm"
 )
@@ -44,27 +43,9 @@ func (r *GormPersonalGoalsRepository) Update(goal *PersonalGoal) (*PersonalGoal,
 }
 
 // Fetches PersonalGoal by UserID
-func (r *GormPersonalGoalsRepository) FetchByUserID(userID Snowflake, requester Snowflake) ([]PersonalGoal, error) {
+func (r *GormPersonalGoalsRepository) FetchByUserID(userID Snowflake) ([]PersonalGoal, error) {
 	var goals []PersonalGoal
-	var err error
-
-	if userID == requester {
-		// user fetches his own goals
-		err = r.DB.Model(&PersonalGoal{}).Where(&PersonalGoal{UserID: requester}).Find(&goals).Error
-	} else {
-		// ensure, that the requestING user is allowed to
-		// view requestED user
-		err = r.DB.Model(&PersonalGoal{}).
-			Where(&PersonalGoal{UserID: userID}).
-			Where(
-				"user_id = ? AND EXISTS (?)",
-				userID,
-				r.DB.Model(&models.Friendships{}).Where(
-					"requester_id = ? OR recipient_id = ? AND status = ?",
-					requester, requester, models.Accepted,
-				),
-			).Find(&goals).Error
-	}
+	err := r.DB.Model(&PersonalGoal{}).Where(&PersonalGoal{UserID: userID}).Find(&goals).Error
 	if err != nil {
 		return nil, err
 	}
@@ -73,9 +54,20 @@ func (r *GormPersonalGoalsRepository) FetchByUserID(userID Snowflake, requester
 
 // Deletes a PersonalGoal by its ID if the user owns it.
 func (r *GormPersonalGoalsRepository) DeleteByID(goal *PersonalGoal) (*PersonalGoal, error) {
-	err := r.DB.Where(&PersonalGoal{ID: goal.ID, UserID: goal.UserID}).Delete(&PersonalGoal{}).Error
+	result := r.DB.Where(&PersonalGoal{ID: goal.ID, UserID: goal.UserID}).Delete(&PersonalGoal{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return nil, repositories.NotFound("no goal with ID %d found for user %d", goal.ID, goal.UserID)
+	}
+	err := result.Error
 	if err != nil {
 		return nil, err
 	}
 	return goal, nil
 }
+
+// Fetches the goals of all users ordered by user
+func (r *GormPersonalGoalsRepository) FetchAll() ([]PersonalGoal, error) {
+	var goals []PersonalGoal
+	err := r.DB.Order("user_id, id").Find(&goals).Error
+	return goals, err
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"testing"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestGoalsDB builds an isolated in-memory database with all tables needed for goal lookups.
+func newTestGoalsDB(t *testing.T) (*GormPersonalGoalsRepository, *GormUserSettingsRepository, *GormFriendshipRepository) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+
+	goals := &GormPersonalGoalsRepository{DB: database}
+	settings := &GormUserSettingsRepository{DB: database}
+	friendships := &GormFriendshipRepository{DB: database}
+	for _, migrate := range []func() error{goals.InitRepo, settings.InitRepo, friendships.InitRepo} {
+		if err := migrate(); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+	}
+	return goals, settings, friendships
+}
+
+// TestGoalsVisibility verifies that foreign goals are only visible when the goals visibility allows it.
+func TestGoalsVisibility(t *testing.T) {
+	goals, settings, friendships := newTestGoalsDB(t)
+	visibility := NewVisibilityService(friendships, settings)
+
+	owner := Snowflake(1)
+	friend := Snowflake(2)
+	stranger := Snowflake(3)
+	// friendship between two other users must not grant access to the stranger
+	otherA := Snowflake(4)
+
+	if _, err := friendships.CreateFriendship(owner, friend, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	if _, err := friendships.CreateFriendship(stranger, otherA, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	if _, err := goals.Insert(&PersonalGoal{UserID: owner, Amount: 10, Frequency: Daily, Sport: "pushup"}); err != nil {
+		t.Fatalf("failed to insert goal: %v", err)
+	}
+
+	setGoalsVisibility := func(visibility Visibility) {
+		s := DefaultUserSettings(owner)
+		s.GoalsVisibility = visibility
+		if _, err := settings.Save(&s); err != nil {
+			t.Fatalf("failed to save settings: %v", err)
+		}
+	}
+
+	var tests = []struct {
+		name       string
+		visibility Visibility
+		requester  Snowflake
+		want       bool
+	}{
+		{"Owner always sees own goals", Private, owner, true},
+		{"Friend sees goals with friends visibility", FriendsOnly, friend, true},
+		{"Stranger does not see goals with friends visibility", FriendsOnly, stranger, false},
+		{"Stranger sees public goals", Public, stranger, true},
+		{"Friend does not see private goals", Private, friend, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setGoalsVisibility(tt.visibility)
+			got, err := visibility.CanView(tt.requester, owner, ResourceGoals)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got visible %v, want %v", got, tt.want)
+			}
+			// the batched check has to agree
+			visible, _, err := visibility.FilterVisible(tt.requester, []Snowflake{owner}, ResourceGoals)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (len(visible) == 1) != tt.want {
+				t.Errorf("got visible %v from FilterVisible, want %v", visible, tt.want)
+			}
+		})
+	}
+}
+
+// TestGoalsVisibilityWithoutSettings verifies that users without settings fall back to friends visibility.
+func TestGoalsVisibilityWithoutSettings(t *testing.T) {
+	goals, settings, friendships := newTestGoalsDB(t)
+	visibility := NewVisibilityService(friendships, settings)
+
+	owner := Snowflake(10)
+	friend := Snowflake(20)
+	stranger := Snowflake(30)
+
+	if _, err := friendships.CreateFriendship(friend, owner, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	if _, err := goals.Insert(&PersonalGoal{UserID: owner, Amount: 5, Frequency: Weekly, Sport: "plank"}); err != nil {
+		t.Fatalf("failed to insert goal: %v", err)
+	}
+
+	if got, _ := goals.FetchByUserID(owner); len(got) != 1 {
+		t.Errorf("expected 1 goal of the owner, got %d", len(got))
+	}
+	if ok, _ := visibility.CanView(friend, owner, ResourceGoals); !ok {
+		t.Errorf("expected friend to see the goals")
+	}
+	if ok, _ := visibility.CanView(stranger, owner, ResourceGoals); ok {
+		t.Errorf("expected stranger not to see the goals")
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrPushSubscriptionNotFound = repositories.NotFound("push subscription not found")
+
+// PushSubscriptionRepository defines the interface for managing push subscriptions in the database.
+func NewGormPushSubscriptionRepository(database *gorm.DB) repositories.PushSubscriptionRepository {
+	repo := &GormPushSubscriptionRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `PushSubscriptionRepository` for GORM
+type GormPushSubscriptionRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the PushSubscription GORM table
+func (r *GormPushSubscriptionRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&PushSubscription{})
+}
+
+// Stores <subscription>. An existing subscription with the same endpoint is moved to the
+// user of <subscription> and gets its keys, since only the current browser profile receives them
+func (r *GormPushSubscriptionRepository) Save(subscription *PushSubscription) (*PushSubscription, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing PushSubscription
+		err := tx.Where(&PushSubscription{Endpoint: subscription.Endpoint}).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			subscription.ID = 0 // ensure that GORM creates a new record
+			return tx.Create(subscription).Error
+		}
+		if err != nil {
+			return err
+		}
+		subscription.ID = existing.ID
+		subscription.CreatedAt = existing.CreatedAt
+		return tx.Save(subscription).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Returns the subscriptions of <userID>, newest first
+func (r *GormPushSubscriptionRepository) FetchByUserID(userID Snowflake) ([]PushSubscription, error) {
+	subscriptions := []PushSubscription{}
+	err := r.DB.Where(&PushSubscription{UserID: userID}).Order("created_at desc, id desc").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Deletes the subscription with <id>. Only the owner can delete a subscription
+func (r *GormPushSubscriptionRepository) Delete(id Snowflake, userID Snowflake) error {
+	result := r.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&PushSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// Deletes the subscription with <endpoint>. Deleting a missing subscription is no error, since
+// more than one notification can report the same expired subscription
+func (r *GormPushSubscriptionRepository) DeleteByEndpoint(endpoint string) error {
+	return r.DB.Where(&PushSubscription{Endpoint: endpoint}).Delete(&PushSubscription{}).Error
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = repositories.NotFound("session not found")
+
+// SessionRepository defines the interface for managing login sessions in the database.
+func NewGormSessionRepository(database *gorm.DB) repositories.SessionRepository {
+	repo := &GormSessionRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `SessionRepository` for GORM
+type GormSessionRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the Session GORM table
+func (r *GormSessionRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&Session{})
+}
+
+// Returns the session with <id> or nil, if there is none
+func (r *GormSessionRepository) Fetch(id string) (*Session, error) {
+	var session Session
+	err := r.DB.Where(&Session{ID: id}).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Creates or updates <session>
+func (r *GormSessionRepository) Save(session *Session) error {
+	return r.DB.Save(session).Error
+}
+
+// Sets the last usage of the session with <id> to <now>
+func (r *GormSessionRepository) Touch(id string, now time.Time) error {
+	return r.DB.Model(&Session{}).Where("id = ?", id).Update("last_seen_at", now).Error
+}
+
+// Deletes the session with <id>
+func (r *GormSessionRepository) Delete(id string) error {
+	return r.DB.Where("id = ?", id).Delete(&Session{}).Error
+}
+
+// Returns all sessions of <userID>, most recently used first
+func (r *GormSessionRepository) FetchByUserID(userID Snowflake) ([]Session, error) {
+	var sessions []Session
+	err := r.DB.Where(&Session{UserID: userID}).Order("last_seen_at desc").Find(&sessions).Error
+	return sessions, err
+}
+
+// Deletes the session with the public ID <publicID>. Only the owner can delete a session
+func (r *GormSessionRepository) DeleteByPublicID(publicID string, userID Snowflake) error {
+	result := r.DB.Where("public_id = ? AND user_id = ?", publicID, userID).Delete(&Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// Deletes sessions which expired at <now> or were not used since <idleSince>
+func (r *GormSessionRepository) DeleteExpired(now time.Time, idleSince time.Time) (int64, error) {
+	result := r.DB.Where("expires_at <= ? OR last_seen_at < ?", now, idleSince).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+// In-memory implementation of `SessionRepository`, used for tests and when no database is needed
+type MemorySessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: make(map[string]Session)}
+}
+
+func (r *MemorySessionRepository) InitRepo() error {
+	return nil
+}
+
+func (r *MemorySessionRepository) Fetch(id string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &session, nil
+}
+
+func (r *MemorySessionRepository) Save(session *Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *MemorySessionRepository) Touch(id string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = now
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *MemorySessionRepository) FetchByUserID(userID Snowflake) ([]Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := []Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) DeleteByPublicID(publicID string, userID Snowflake) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.sessions {
+		if session.PublicID == publicID && session.UserID == userID {
+			delete(r.sessions, id)
+			return nil
+		}
+	}
+	return ErrSessionNotFound
+}
+
+func (r *MemorySessionRepository) DeleteExpired(now time.Time, idleSince time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for id, session := range r.sessions {
+		if !now.Before(session.ExpiresAt) || session.LastSeenAt.Before(idleSince) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
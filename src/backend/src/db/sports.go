@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
 	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
 	"gorm.io/driver/sqlite"
@@ -14,13 +15,22 @@ import (
 // Updated SportRepository interface to include full CRUD operations using the Sport struct.
 type SportRepository interface {
 	InsertSport(sport Sport) error
+	// inserts all <sports> in batches, keeping their timedate
+	InsertSports(sports []Sport) error
+	// returns all sports of <userID> done between <from> and <to>, both inclusive
+	GetSportsBetween(userID Snowflake, from time.Time, to time.Time) ([]Sport, error)
+	// returns the amounts of <userID> per UTC day, sport and game with a timedate in [<from>, <to>)
+	GetDailyTotals(userID Snowflake, from time.Time, to time.Time) ([]SportDayTotal, error)
 	GetSports(userIDs []Snowflake, limit int, offset int) ([]Sport, error)
 	UpdateSport(sport Sport) error
 	PatchSport(sport Sport) error
 	DeleteSport(id Snowflake, userID Snowflake) error
 	GetTotalAmounts(userID Snowflake) ([]SportAmount, error)
 	GetActicityDates(userID Snowflake) ([]time.Time, error)
+	// returns the users, whose last activity was on the UTC day of <day>
+	GetUserIDsLastActiveOn(day time.Time) ([]Snowflake, error)
 	GetCurrentStreak(userID Snowflake) (DayStreak, error)
+	GetCurrentStreaks(userIDs []Snowflake) (map[Snowflake]DayStreak, error)
 	GetLongestStreak(userID Snowflake) (DayStreak, error)
 }
 
@@ -50,6 +60,39 @@ func (r *OrmSportRepository) InsertSport(sport Sport) error {
 	return result.Error
 }
 
+// number of rows inserted with one statement. SQLite allows 32766 variables per statement
+const sportInsertBatchSize = 500
+
+// InsertSports adds all Sport entries in batches using ORM.
+func (r *OrmSportRepository) InsertSports(sports []Sport) error {
+	if len(sports) == 0 {
+		return nil
+	}
+	return r.DB.CreateInBatches(sports, sportInsertBatchSize).Error
+}
+
+// GetSportsBetween retrieves all Sport entries of <userID> with a timedate between <from> and <to>.
+func (r *OrmSportRepository) GetSportsBetween(userID Snowflake, from time.Time, to time.Time) ([]Sport, error) {
+	var sports []Sport
+	result := r.DB.
+		Where("user_id = ? AND timedate BETWEEN ? AND ?", userID, from, to).
+		Order("timedate").
+		Find(&sports)
+	return sports, result.Error
+}
+
+// GetDailyTotals sums the amounts of <userID> per UTC day, sport and game with a timedate in [<from>, <to>).
+func (r *OrmSportRepository) GetDailyTotals(userID Snowflake, from time.Time, to time.Time) ([]SportDayTotal, error) {
+	var totals []SportDayTotal
+	result := r.DB.Model(&Sport{}).
+		Select("DATE(timedate) AS date, kind, game, SUM(amount) AS amount, COUNT(*) AS activities").
+		Where("user_id = ? AND timedate >= ? AND timedate < ?", userID, from, to).
+		Group("DATE(timedate), kind, game").
+		Order("date, kind, game").
+		Scan(&totals)
+	return totals, result.Error
+}
+
 // GetSports retrieves Sport entries for any of the provided userIDs.
 // Now checks that user_id is any of the slice values and limits the result to 50.
 func (r *OrmSportRepository) GetSports(userIDs []Snowflake, limit int, offset int) ([]Sport, error) {
@@ -83,17 +126,26 @@ func (r *OrmSportRepository) UpdateSport(sport Sport) error {
 // PatchSport updates a Sport entry using ORM. Patch does not CREATE if it does not exist
 func (r *OrmSportRepository) PatchSport(sport Sport) error {
 	result := r.DB.Model(&sport).Where(&Sport{ID: sport.ID, UserID: sport.UserID}).Updates(sport)
+	if result.Error != nil {
+		return result.Error
+	}
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("no record found for ID %d and UserID %d", sport.ID, sport.UserID)
+		return repositories.NotFound("no sport with ID %d found for user %d", sport.ID, sport.UserID)
 	}
-	return result.Error
+	return nil
 }
 
 // DeleteSport removes a Sport entry by ID using ORM. Record needs to match both `userID` AND `id`
 // to be deleted
 func (r *OrmSportRepository) DeleteSport(id Snowflake, userID Snowflake) error {
 	result := r.DB.Where(&Sport{UserID: userID, ID: id}).Delete(&Sport{})
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.NotFound("no sport with ID %d found for user %d", id, userID)
+	}
+	return nil
 }
 
 // GetLongestDayStreak returns the longest streak in days the user ever had
@@ -143,3 +195,57 @@ func (r *OrmSportRepository) GetActicityDates(userID Snowflake) ([]time.Time, er
 	}
 	return parsedDates, nil
 }
+
+// returns the users, whose last activity was on the UTC day of <day>. Their current streak breaks,
+// unless they are active on the following day
+func (r *OrmSportRepository) GetUserIDsLastActiveOn(day time.Time) ([]Snowflake, error) {
+	var userIDs []Snowflake
+	result := r.DB.Model(&Sport{}).
+		Select("user_id").
+		Where("timedate IS NOT NULL AND timedate > ?", "2000-01-01").
+		Group("user_id").
+		Having("DATE(MAX(timedate)) = ?", day.UTC().Format(time.DateOnly)).
+		Pluck("user_id", &userIDs)
+	return userIDs, result.Error
+}
+
+// GetCurrentStreaks calculates the current streak of every user in <userIDs> with a single query.
+// Users without activities have a streak of 0
+func (r *OrmSportRepository) GetCurrentStreaks(userIDs []Snowflake) (map[Snowflake]DayStreak, error) {
+	streaks := make(map[Snowflake]DayStreak, len(userIDs))
+	if len(userIDs) == 0 {
+		return streaks, nil
+	}
+
+	var rows []struct {
+		UserID Snowflake
+		Date   string
+	}
+	result := r.DB.Model(&Sport{}).
+		Select("DISTINCT user_id, DATE(timedate) as date").
+		Where("user_id IN (?) AND timedate IS NOT NULL AND timedate > ?", userIDs, "2000-01-01").
+		Order("user_id, DATE(timedate) DESC").
+		Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// group the dates by user, the order of the dates is kept
+	datesByUser := make(map[Snowflake][]string, len(userIDs))
+	for _, row := range rows {
+		datesByUser[row.UserID] = append(datesByUser[row.UserID], row.Date)
+	}
+
+	for _, userID := range userIDs {
+		activityDates, err := r.StreakService.ParseDates(datesByUser[userID])
+		if err != nil {
+			return nil, err
+		}
+		days, err := r.StreakService.GetCurrentStreak(activityDates)
+		if err != nil {
+			return nil, err
+		}
+		streaks[userID] = DayStreak{UserID: userID, Days: days}
+	}
+	return streaks, nil
+}
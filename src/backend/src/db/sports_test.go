@@ -0,0 +1,47 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGetCurrentStreaks(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	now := time.Date(2023, 1, 6, 20, 0, 0, 0, time.UTC)
+	repo := &OrmSportRepository{DB: database, StreakService: NewStreakService(func() time.Time { return now })}
+
+	// user 1 was active today and the two days before, user 2 only two days ago
+	for _, s := range []models.Sport{
+		{UserID: 1, Kind: "pushup", Amount: 1, Timedate: now},
+		{UserID: 1, Kind: "pushup", Amount: 1, Timedate: now.AddDate(0, 0, -1)},
+		{UserID: 1, Kind: "plank", Amount: 1, Timedate: now.AddDate(0, 0, -1)},
+		{UserID: 1, Kind: "pushup", Amount: 1, Timedate: now.AddDate(0, 0, -2)},
+		{UserID: 2, Kind: "pushup", Amount: 1, Timedate: now.AddDate(0, 0, -2)},
+	} {
+		database.Create(&s)
+	}
+
+	streaks, err := repo.GetCurrentStreaks([]models.Snowflake{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetCurrentStreaks failed: %v", err)
+	}
+	want := map[models.Snowflake]models.DayStreak{
+		1: {UserID: 1, Days: 3},
+		2: {UserID: 2, Days: 0},
+		3: {UserID: 3, Days: 0},
+	}
+	if !reflect.DeepEqual(streaks, want) {
+		t.Errorf("got %v, want %v", streaks, want)
+	}
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+const statsDateLayout = "2006-01-02"
+
+type IStatsService interface {
+	GetStats(userID models.Snowflake, viewerID models.Snowflake, query models.StatsQuery) (*models.UserStats, error)
+}
+
+// StatsService aggregates the sports of a user to statistics
+type StatsService struct {
+	SportRepo  SportRepository
+	Visibility IVisibilityService
+	// base multiplier of every sport, sports without one use 1
+	Multipliers map[string]float64
+	// multiplier of every game, games without one use 1
+	GameMultipliers map[string]float64
+}
+
+func NewStatsService(
+	sportRepo SportRepository,
+	visibility IVisibilityService,
+	multipliers map[string]float64,
+	gameMultipliers map[string]float64,
+) *StatsService {
+	return &StatsService{SportRepo: sportRepo, Visibility: visibility, Multipliers: multipliers, GameMultipliers: gameMultipliers}
+}
+
+// GetStats returns the statistics of <userID> for <query>, if <viewerID> is allowed to see his activities
+func (s *StatsService) GetStats(
+	userID models.Snowflake,
+	viewerID models.Snowflake,
+	query models.StatsQuery,
+) (*models.UserStats, error) {
+	allowed, err := s.Visibility.CanView(viewerID, userID, models.ResourceActivities)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, repositories.Forbidden("user %v is not allowed to view the activities of %v", viewerID, userID)
+	}
+
+	from := truncateDay(query.From)
+	to := truncateDay(query.To)
+	totals, err := s.SportRepo.GetDailyTotals(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return s.aggregate(userID, from, to, query.Bucket, totals)
+}
+
+// aggregates the daily <totals> of the UTC days <from> to <to>
+func (s *StatsService) aggregate(
+	userID models.Snowflake,
+	from time.Time,
+	to time.Time,
+	bucket models.StatsBucketSize,
+	totals []models.SportDayTotal,
+) (*models.UserStats, error) {
+	stats := &models.UserStats{
+		UserID:   userID,
+		From:     from.Format(statsDateLayout),
+		To:       to.Format(statsDateLayout),
+		Bucket:   bucket,
+		Days:     []models.StatsDay{},
+		Sports:   []models.StatsSport{},
+		Games:    []models.StatsGame{},
+		Weekdays: make([]models.StatsWeekday, 7),
+	}
+
+	// one bucket for every day, week or month in the range
+	bucketIndex := map[string]int{}
+	for start := bucketStart(from, bucket); !start.After(to); start = nextBucket(start, bucket) {
+		bucketIndex[start.Format(statsDateLayout)] = len(stats.Buckets)
+		stats.Buckets = append(stats.Buckets, models.StatsBucket{Start: start.Format(statsDateLayout)})
+	}
+	for i := range stats.Weekdays {
+		// index 0 is monday
+		stats.Weekdays[i].Weekday = strings.ToLower(time.Weekday((i + 1) % 7).String())
+	}
+
+	sports := map[string]*models.StatsSport{}
+	games := map[string]*models.StatsGame{}
+	for _, total := range totals {
+		day, err := time.Parse(statsDateLayout, total.Date)
+		if err != nil {
+			return nil, err
+		}
+		multiplier := s.multiplier(total.Kind, total.Game)
+
+		// totals are ordered by date, so the day is either the last one or a new one
+		if len(stats.Days) == 0 || stats.Days[len(stats.Days)-1].Date != total.Date {
+			stats.Days = append(stats.Days, models.StatsDay{Date: total.Date})
+			stats.Weekdays[weekdayIndex(day)].ActiveDays++
+		}
+		stats.Days[len(stats.Days)-1].Add(total.Amount, multiplier, total.Activities)
+
+		stats.Total.Add(total.Amount, multiplier, total.Activities)
+		stats.Weekdays[weekdayIndex(day)].Add(total.Amount, multiplier, total.Activities)
+		if i, ok := bucketIndex[bucketStart(day, bucket).Format(statsDateLayout)]; ok {
+			stats.Buckets[i].Add(total.Amount, multiplier, total.Activities)
+		}
+		if _, ok := sports[total.Kind]; !ok {
+			sports[total.Kind] = &models.StatsSport{Kind: total.Kind}
+		}
+		sports[total.Kind].Add(total.Amount, multiplier, total.Activities)
+		if _, ok := games[total.Game]; !ok {
+			games[total.Game] = &models.StatsGame{Game: total.Game}
+		}
+		games[total.Game].Add(total.Amount, multiplier, total.Activities)
+	}
+
+	for _, sport := range sports {
+		stats.Sports = append(stats.Sports, *sport)
+	}
+	sort.Slice(stats.Sports, func(i, j int) bool {
+		return heavier(stats.Sports[i].StatsTotal, stats.Sports[j].StatsTotal, stats.Sports[i].Kind, stats.Sports[j].Kind)
+	})
+	for _, game := range games {
+		stats.Games = append(stats.Games, *game)
+	}
+	sort.Slice(stats.Games, func(i, j int) bool {
+		return heavier(stats.Games[i].StatsTotal, stats.Games[j].StatsTotal, stats.Games[i].Game, stats.Games[j].Game)
+	})
+
+	stats.ActiveDays = len(stats.Days)
+	if stats.ActiveDays > 0 {
+		stats.AveragePerActiveDay = float64(stats.Total.Amount) / float64(stats.ActiveDays)
+		stats.WeightedAveragePerActiveDay = stats.Total.Weighted / float64(stats.ActiveDays)
+	}
+	for i := range stats.Days {
+		// the earliest day wins a tie
+		if stats.BestDay == nil || stats.Days[i].Weighted > stats.BestDay.Weighted {
+			best := stats.Days[i]
+			stats.BestDay = &best
+		}
+	}
+	return stats, nil
+}
+
+// returns the multiplier of <kind> played in <game>, which is the base multiplier of the sport
+// times the multiplier of the game, like the amount of a death is calculated
+func (s *StatsService) multiplier(kind string, game string) float64 {
+	return positiveOrOne(s.Multipliers[kind]) * positiveOrOne(s.GameMultipliers[game])
+}
+
+// returns <multiplier> or 1 for missing and invalid multipliers
+func positiveOrOne(multiplier float64) float64 {
+	if multiplier > 0 {
+		return multiplier
+	}
+	return 1
+}
+
+// orders totals by their weighted amount, names break ties
+func heavier(a models.StatsTotal, b models.StatsTotal, nameA string, nameB string) bool {
+	if a.Weighted != b.Weighted {
+		return a.Weighted > b.Weighted
+	}
+	return nameA < nameB
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// returns the index of the weekday of <day> with monday as 0
+func weekdayIndex(day time.Time) int {
+	return (int(day.Weekday()) + 6) % 7
+}
+
+// returns the first day of the bucket containing <day>
+func bucketStart(day time.Time, bucket models.StatsBucketSize) time.Time {
+	switch bucket {
+	case models.BucketWeek:
+		return day.AddDate(0, 0, -weekdayIndex(day))
+	case models.BucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// returns the start of the bucket following the bucket starting at <start>
+func nextBucket(start time.Time, bucket models.StatsBucketSize) time.Time {
+	switch bucket {
+	case models.BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case models.BucketMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestStatsService(t *testing.T) (*StatsService, *gorm.DB) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := &OrmSportRepository{DB: database, StreakService: NewStreakService(time.Now)}
+	visibility := NewVisibilityService(newTestFriendshipRepo(t), mapVisibilityProvider{3: models.Public})
+	return NewStatsService(repo, visibility, map[string]float64{"pushup": 2, "plank": 10}, nil), database
+}
+
+func TestGetStats(t *testing.T) {
+	service, database := newTestStatsService(t)
+
+	// 2023-01-02 is a monday
+	monday := time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC)
+	for _, s := range []models.Sport{
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 20, Timedate: monday},
+		{UserID: 1, Kind: "plank", Game: "league", Amount: 100, Timedate: monday.Add(time.Hour)},
+		{UserID: 1, Kind: "pushup", Game: "tft", Amount: 30, Timedate: monday.AddDate(0, 0, 1)},
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: monday.AddDate(0, 0, 7)},
+		// outside of the range
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 500, Timedate: monday.AddDate(0, 0, 30)},
+		// other user
+		{UserID: 2, Kind: "pushup", Game: "league", Amount: 500, Timedate: monday},
+	} {
+		database.Create(&s)
+	}
+
+	stats, err := service.GetStats(1, 1, models.StatsQuery{
+		From:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC),
+		Bucket: models.BucketWeek,
+	})
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+
+	if stats.Total.Amount != 160 || stats.Total.Weighted != 40 || stats.Total.Activities != 4 {
+		t.Errorf("got total %+v, want amount 160, weighted 40 and 4 activities", stats.Total)
+	}
+	if stats.ActiveDays != 3 || len(stats.Days) != 3 {
+		t.Errorf("got %d active days and %d days, want 3", stats.ActiveDays, len(stats.Days))
+	}
+	if stats.BestDay == nil || stats.BestDay.Date != "2023-01-02" || stats.BestDay.Weighted != 20 {
+		t.Errorf("got best day %+v, want 2023-01-02 with weighted 20", stats.BestDay)
+	}
+
+	// the range starts on a sunday, so its week starts on the monday before
+	wantBuckets := []struct {
+		start  string
+		amount int
+	}{{"2022-12-26", 0}, {"2023-01-02", 150}, {"2023-01-09", 10}}
+	if len(stats.Buckets) != len(wantBuckets) {
+		t.Fatalf("got buckets %+v, want %d", stats.Buckets, len(wantBuckets))
+	}
+	for i, want := range wantBuckets {
+		if stats.Buckets[i].Start != want.start || stats.Buckets[i].Amount != want.amount {
+			t.Errorf("bucket %d: got %+v, want start %s and amount %d", i, stats.Buckets[i], want.start, want.amount)
+		}
+	}
+
+	if len(stats.Weekdays) != 7 || stats.Weekdays[0].Weekday != "monday" || stats.Weekdays[6].Weekday != "sunday" {
+		t.Fatalf("got weekdays %+v, want monday to sunday", stats.Weekdays)
+	}
+	if stats.Weekdays[0].ActiveDays != 2 || stats.Weekdays[0].Amount != 130 || stats.Weekdays[1].Amount != 30 {
+		t.Errorf("got monday %+v and tuesday %+v", stats.Weekdays[0], stats.Weekdays[1])
+	}
+
+	// pushups weigh 30, the plank 10
+	if len(stats.Sports) != 2 || stats.Sports[0].Kind != "pushup" || stats.Sports[0].Weighted != 30 {
+		t.Errorf("got sports %+v, want pushup first", stats.Sports)
+	}
+	if len(stats.Games) != 2 || stats.Games[0].Game != "league" || stats.Games[0].Amount != 130 {
+		t.Errorf("got games %+v, want league first", stats.Games)
+	}
+}
+
+func TestGetStatsWithoutSports(t *testing.T) {
+	service, _ := newTestStatsService(t)
+
+	stats, err := service.GetStats(1, 1, models.StatsQuery{
+		From:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Bucket: models.BucketMonth,
+	})
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.BestDay != nil || stats.ActiveDays != 0 || len(stats.Days) != 0 {
+		t.Errorf("got %+v, want no active days", stats)
+	}
+	if len(stats.Buckets) != 3 || stats.Buckets[2].Start != "2023-03-01" {
+		t.Errorf("got buckets %+v, want the three months", stats.Buckets)
+	}
+}
+
+// TestGetStatsGameMultipliers verifies that weighted amounts are divided by the multipliers of sport and game
+func TestGetStatsGameMultipliers(t *testing.T) {
+	service, database := newTestStatsService(t)
+	service.GameMultipliers = map[string]float64{"league": 1.5}
+
+	day := time.Date(2023, 1, 2, 18, 0, 0, 0, time.UTC)
+	database.Create(&models.Sport{UserID: 1, Kind: "pushup", Game: "league", Amount: 30, Timedate: day})
+	database.Create(&models.Sport{UserID: 1, Kind: "pushup", Game: "tft", Amount: 20, Timedate: day})
+
+	stats, err := service.GetStats(1, 1, models.StatsQuery{From: day, To: day, Bucket: models.BucketDay})
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	// 30 / (2 * 1.5) + 20 / 2
+	if stats.Total.Weighted != 20 {
+		t.Errorf("got weighted %v, want 20", stats.Total.Weighted)
+	}
+	// both games weigh the same
+	if len(stats.Games) != 2 || stats.Games[0].Weighted != 10 || stats.Games[1].Weighted != 10 {
+		t.Errorf("got games %+v, want both weighted 10", stats.Games)
+	}
+}
+
+func TestGetStatsRespectsVisibility(t *testing.T) {
+	service, _ := newTestStatsService(t)
+	query := models.StatsQuery{
+		From:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Bucket: models.BucketDay,
+	}
+
+	if _, err := service.GetStats(2, 1, query); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("got %v for a stranger, want ErrForbidden", err)
+	}
+	if _, err := service.GetStats(3, 1, query); err != nil {
+		t.Errorf("got %v for a public stranger, want no error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+// StreakReminderRepository defines the interface for managing the streak reminders in the database.
+func NewGormStreakReminderRepository(database *gorm.DB) repositories.StreakReminderRepository {
+	repo := &GormStreakReminderRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `StreakReminderRepository` for GORM
+type GormStreakReminderRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the StreakReminder GORM table
+func (r *GormStreakReminderRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&StreakReminder{})
+}
+
+// Returns the reminder settings of <userID> or enabled reminders, if there are none
+func (r *GormStreakReminderRepository) Fetch(userID Snowflake) (*StreakReminder, error) {
+	var reminder StreakReminder
+	err := r.DB.Where(&StreakReminder{UserID: userID}).First(&reminder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &StreakReminder{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// Creates or replaces the settings of the user of <reminder>
+func (r *GormStreakReminderRepository) Save(reminder *StreakReminder) error {
+	return r.DB.Save(reminder).Error
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// prefix of encrypted values. Values without it were stored in plain text by older versions
+const encryptedTokenPrefix = "enc1:"
+
+var errInvalidEncryptedToken = errors.New("encrypted token is invalid or was encrypted with another key")
+
+// TokenCipher encrypts secrets like the OAuth tokens of providers with AES-256-GCM, before
+// they are stored in the database
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher returns a cipher with a key derived from <secret>
+func NewTokenCipher(secret string) (*TokenCipher, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// Encrypt returns <plaintext> encrypted with a random nonce. Empty values stay empty,
+// so that queries can still tell whether there is a token
+func (c *TokenCipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedTokenPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of <value>. Values without the prefix are returned as they are
+func (c *TokenCipher) Decrypt(value string) (string, error) {
+	encoded, found := strings.CutPrefix(value, encryptedTokenPrefix)
+	if !found {
+		return value, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errInvalidEncryptedToken
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errInvalidEncryptedToken
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted returns whether <value> is empty or was encrypted by a TokenCipher
+func IsEncrypted(value string) bool {
+	return value == "" || strings.HasPrefix(value, encryptedTokenPrefix)
+}
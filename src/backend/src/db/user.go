@@ -2,9 +2,11 @@ package db
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
@@ -13,6 +15,7 @@ type UserRepository interface {
 	CreateUser(user *models.User) error
 	UpdateUser(user *models.User) error
 	DeleteUserByID(id models.Snowflake) error
+	SearchDiscoverable(query string, excludeID models.Snowflake, limit int) ([]models.User, error)
 }
 
 type GormUserRepository struct {
@@ -63,7 +66,49 @@ func (r *GormUserRepository) UpdateUser(user *models.User) error {
 	return r.DB.Save(user).Error
 }
 
-// DeleteUserByID deletes a user by its ID.
+// DeleteUserByID deletes a user by its ID together with all of his data.
 func (r *GormUserRepository) DeleteUserByID(id models.Snowflake) error {
-	return r.DB.Delete(&models.User{}, id).Error
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return purgeUser(tx, id)
+	})
+}
+
+// SearchDiscoverable returns users which opted into discoverability and whose username matches <query>.
+// Exact matches come first, then prefix matches, then substring matches and at last fuzzy matches,
+// where all characters of <query> appear in the same order in the username.
+func (r *GormUserRepository) SearchDiscoverable(query string, excludeID models.Snowflake, limit int) ([]models.User, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	escaped := escapeLike(query)
+
+	// %q%u%e%r%y% matches every username containing the characters of the query in order
+	var fuzzy strings.Builder
+	fuzzy.WriteString("%")
+	for _, char := range query {
+		fuzzy.WriteString(escapeLike(string(char)))
+		fuzzy.WriteString("%")
+	}
+
+	var users []models.User
+	err := r.DB.Model(&models.User{}).
+		Joins("JOIN user_settings ON user_settings.user_id = users.id").
+		Where("user_settings.discoverable = ? AND users.id <> ?", true, excludeID).
+		Where("LOWER(users.username) LIKE ? ESCAPE '\\'", fuzzy.String()).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL: "CASE WHEN LOWER(users.username) = ? THEN 0 " +
+				"WHEN LOWER(users.username) LIKE ? ESCAPE '\\' THEN 1 " +
+				"WHEN LOWER(users.username) LIKE ? ESCAPE '\\' THEN 2 " +
+				"ELSE 3 END, LENGTH(users.username), users.username",
+			Vars: []interface{}{query, escaped + "%", "%" + escaped + "%"},
+		}}).
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// escapes the wildcards of a LIKE pattern with a backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
 }
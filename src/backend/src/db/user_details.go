@@ -1,8 +1,6 @@
 package db
 
 import (
-	"fmt"
-
 	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 )
@@ -15,7 +13,7 @@ type UserDetailsFacade struct {
 	SportRepo         SportRepository
 	UserRepo          UserRepository
 	PersonalGoalsRepo repositories.PersonalGoalsRepository
-	FriendshipRepo    FriendshipRepository
+	Visibility        IVisibilityService
 }
 
 func (s *UserDetailsFacade) GetDetails(
@@ -23,10 +21,13 @@ func (s *UserDetailsFacade) GetDetails(
 	requestingUserID models.Snowflake,
 ) (models.GetUserDetailsReply, error) {
 
-	// check if they are friends and exit otherwise
-	statusPositive, _ := s.FriendshipRepo.HavePositiveFriendshipStatus(userID, requestingUserID)
-	if !statusPositive {
-		return models.GetUserDetailsReply{}, fmt.Errorf("There is no friendship between %v and %v", userID, requestingUserID)
+	// check if the requesting user is allowed to see the profile and exit otherwise
+	allowed, err := s.Visibility.CanView(requestingUserID, userID, models.ResourceProfile)
+	if err != nil {
+		return models.GetUserDetailsReply{}, err
+	}
+	if !allowed {
+		return models.GetUserDetailsReply{}, repositories.Forbidden("user %v is not allowed to view the profile of %v", requestingUserID, userID)
 	}
 
 	// get username, discriminator (# value) and avatar
@@ -35,27 +36,52 @@ func (s *UserDetailsFacade) GetDetails(
 		return models.GetUserDetailsReply{}, err
 	}
 
+	// streaks, activities and goals are only added, if the user allows it. Otherwise
+	// they stay empty
+	currentStreak := models.DayStreak{UserID: userID}
+	LongestStreak := models.DayStreak{UserID: userID}
+	LastActivities := []models.Sport{}
+
 	// get streaks
-	currentStreak, err := s.SportRepo.GetCurrentStreak(userID)
+	streakVisible, err := s.Visibility.CanView(requestingUserID, userID, models.ResourceStreak)
 	if err != nil {
 		return models.GetUserDetailsReply{}, err
 	}
-	LongestStreak, err := s.SportRepo.GetLongestStreak(userID)
-	if err != nil {
-		return models.GetUserDetailsReply{}, err
+	if streakVisible {
+		currentStreak, err = s.SportRepo.GetCurrentStreak(userID)
+		if err != nil {
+			return models.GetUserDetailsReply{}, err
+		}
+		LongestStreak, err = s.SportRepo.GetLongestStreak(userID)
+		if err != nil {
+			return models.GetUserDetailsReply{}, err
+		}
 	}
 
 	// get last activities
-	LastActivities, err := s.SportRepo.GetSports([]models.Snowflake{userID}, 50, 0)
+	activitiesVisible, err := s.Visibility.CanView(requestingUserID, userID, models.ResourceActivities)
 	if err != nil {
 		return models.GetUserDetailsReply{}, err
 	}
+	if activitiesVisible {
+		LastActivities, err = s.SportRepo.GetSports([]models.Snowflake{userID}, 50, 0)
+		if err != nil {
+			return models.GetUserDetailsReply{}, err
+		}
+	}
 
 	// get goals
-	goals, err := s.PersonalGoalsRepo.FetchByUserID(userID, requestingUserID)
+	goals := []models.PersonalGoal{}
+	goalsVisible, err := s.Visibility.CanView(requestingUserID, userID, models.ResourceGoals)
 	if err != nil {
 		return models.GetUserDetailsReply{}, err
 	}
+	if goalsVisible {
+		goals, err = s.PersonalGoalsRepo.FetchByUserID(userID)
+		if err != nil {
+			return models.GetUserDetailsReply{}, err
+		}
+	}
 
 	// build up response
 	details := models.GetUserDetailsReply{
@@ -75,13 +101,13 @@ func NewUserDetailsFacade(
 	sportRepo SportRepository,
 	userRepo UserRepository,
 	personalGoalsRepo repositories.PersonalGoalsRepository,
-	friendshipRepo FriendshipRepository,
+	visibility IVisibilityService,
 
 ) IUserDetailsFacade {
 	return &UserDetailsFacade{
 		SportRepo:         sportRepo,
 		UserRepo:          userRepo,
 		PersonalGoalsRepo: personalGoalsRepo,
-		FriendshipRepo:    friendshipRepo,
+		Visibility:        visibility,
 	}
 }
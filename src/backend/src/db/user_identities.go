@@ -0,0 +1,190 @@
+package db
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLastIdentity     = repositories.Conflict("the last login method of a user can't be removed")
+	ErrIdentityNotFound = repositories.NotFound("no account of this provider is linked")
+)
+
+// UserIdentityRepository defines the interface for managing linked login identities in the database.
+// The OAuth tokens are encrypted with <tokens>
+func NewGormUserIdentityRepository(database *gorm.DB, tokens *TokenCipher) repositories.UserIdentityRepository {
+	repo := &GormUserIdentityRepository{DB: database, Tokens: tokens}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `UserIdentityRepository` for GORM
+type GormUserIdentityRepository struct {
+	DB     *gorm.DB
+	Tokens *TokenCipher
+}
+
+// automigrates the UserIdentity GORM table and encrypts tokens stored in plain text by older versions
+func (r *GormUserIdentityRepository) InitRepo() error {
+	if err := r.DB.AutoMigrate(&UserIdentity{}); err != nil {
+		return err
+	}
+	// refresh tokens were indexed by older versions
+	if r.DB.Migrator().HasIndex(&UserIdentity{}, "idx_user_identities_refresh_token") {
+		if err := r.DB.Migrator().DropIndex(&UserIdentity{}, "idx_user_identities_refresh_token"); err != nil {
+			return err
+		}
+	}
+
+	var identities []UserIdentity
+	if err := r.DB.Find(&identities).Error; err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if IsEncrypted(identity.AccessToken) && IsEncrypted(identity.RefreshToken) {
+			continue
+		}
+		if _, err := r.Update(&identity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns a copy of <identity> with encrypted tokens, which is stored instead of it
+func (r *GormUserIdentityRepository) encrypt(identity *UserIdentity) (*UserIdentity, error) {
+	stored := *identity
+	var err error
+	if stored.AccessToken, err = r.Tokens.Encrypt(identity.AccessToken); err != nil {
+		return nil, err
+	}
+	if stored.RefreshToken, err = r.Tokens.Encrypt(identity.RefreshToken); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// decrypts the tokens of <identity> in place. Tokens, which can't be decrypted anymore, e.g.
+// after the key changed, are dropped. The user gets new ones with his next login
+func (r *GormUserIdentityRepository) decrypt(identity *UserIdentity) {
+	accessToken, accessErr := r.Tokens.Decrypt(identity.AccessToken)
+	refreshToken, refreshErr := r.Tokens.Decrypt(identity.RefreshToken)
+	if err := errors.Join(accessErr, refreshErr); err != nil {
+		log.Printf("dropping the tokens of identity %d: %v", identity.ID, err)
+		accessToken, refreshToken = "", ""
+	}
+	identity.AccessToken = accessToken
+	identity.RefreshToken = refreshToken
+}
+
+// Returns the identity of <subject> at <provider> or nil, if it's not linked yet
+func (r *GormUserIdentityRepository) FetchByProviderSubject(provider string, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := r.DB.Where(&UserIdentity{Provider: provider, Subject: subject}).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.decrypt(&identity)
+	return &identity, nil
+}
+
+// Returns all identities linked to <userID>
+func (r *GormUserIdentityRepository) FetchByUserID(userID Snowflake) ([]UserIdentity, error) {
+	var identities []UserIdentity
+	if err := r.DB.Where(&UserIdentity{UserID: userID}).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	for i := range identities {
+		r.decrypt(&identities[i])
+	}
+	return identities, nil
+}
+
+// Returns identities of <provider>, whose profile should be refreshed in the background.
+// Users count as active, when one of their sessions was used since <activeSince>
+func (r *GormUserIdentityRepository) FetchRefreshable(
+	provider string,
+	activeSince time.Time,
+	refreshedBefore time.Time,
+	limit int,
+) ([]UserIdentity, error) {
+	activeUsers := r.DB.Model(&Session{}).Select("user_id").Where("last_seen_at >= ?", activeSince)
+
+	var identities []UserIdentity
+	err := r.DB.
+		Where("provider = ? AND refresh_token <> ''", provider).
+		Where("refreshed_at IS NULL OR refreshed_at < ?", refreshedBefore).
+		Where("user_id IN (?)", activeUsers).
+		Order("refreshed_at IS NOT NULL, refreshed_at").
+		Limit(limit).
+		Find(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range identities {
+		r.decrypt(&identities[i])
+	}
+	return identities, nil
+}
+
+// Creates a new UserIdentity record in the DB. The tokens of <identity> stay in plain text,
+// only the stored ones are encrypted
+func (r *GormUserIdentityRepository) Create(identity *UserIdentity) (*UserIdentity, error) {
+	identity.ID = 0 // ensure that GORM creates a new record
+	stored, err := r.encrypt(identity)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.DB.Create(stored).Error; err != nil {
+		return nil, err
+	}
+	identity.ID = stored.ID
+	identity.CreatedAt = stored.CreatedAt
+	return identity, nil
+}
+
+// Updates a UserIdentity record in the DB. The tokens of <identity> stay in plain text,
+// only the stored ones are encrypted
+func (r *GormUserIdentityRepository) Update(identity *UserIdentity) (*UserIdentity, error) {
+	stored, err := r.encrypt(identity)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.DB.Save(stored).Error; err != nil {
+		return nil, err
+	}
+	identity.ID = stored.ID
+	identity.CreatedAt = stored.CreatedAt
+	return identity, nil
+}
+
+// Removes the identity of <provider> from <userID>. The last identity can't be removed,
+// since the user would not be able to log in anymore
+func (r *GormUserIdentityRepository) Delete(userID Snowflake, provider string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&UserIdentity{}).Where(&UserIdentity{UserID: userID}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count <= 1 {
+			return ErrLastIdentity
+		}
+
+		result := tx.Where(&UserIdentity{UserID: userID, Provider: provider}).Delete(&UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrIdentityNotFound
+		}
+		return nil
+	})
+}
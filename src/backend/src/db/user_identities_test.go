@@ -0,0 +1,59 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestUserIdentityTokensEncrypted verifies that OAuth tokens are only stored encrypted, including
+// the plain text tokens of older versions, and that tokens of another key are dropped.
+func TestUserIdentityTokensEncrypted(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&UserIdentity{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// stored by an older version
+	database.Create(&UserIdentity{UserID: 2, Provider: "discord", Subject: "200", AccessToken: "old-access", RefreshToken: "old-refresh"})
+
+	tokens, err := NewTokenCipher("secret")
+	if err != nil {
+		t.Fatalf("failed to create token cipher: %v", err)
+	}
+	repo := NewGormUserIdentityRepository(database, tokens)
+	identity, err := repo.Create(&UserIdentity{UserID: 1, Provider: "discord", Subject: "100", AccessToken: "access", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatalf("failed to create identity: %v", err)
+	}
+	if identity.AccessToken != "access" {
+		t.Errorf("got access token %q of the created identity, want it in plain text", identity.AccessToken)
+	}
+
+	var stored []UserIdentity
+	database.Order("user_id").Find(&stored)
+	for _, s := range stored {
+		if !strings.HasPrefix(s.AccessToken, encryptedTokenPrefix) || !strings.HasPrefix(s.RefreshToken, encryptedTokenPrefix) {
+			t.Errorf("got stored tokens %q, %q; want them encrypted", s.AccessToken, s.RefreshToken)
+		}
+	}
+
+	for userID, want := range map[Snowflake]string{1: "refresh", 2: "old-refresh"} {
+		identities, err := repo.FetchByUserID(userID)
+		if err != nil || len(identities) != 1 || identities[0].RefreshToken != want {
+			t.Errorf("got identities %+v, %v of user %d; want refresh token %q", identities, err, userID, want)
+		}
+	}
+
+	otherTokens, _ := NewTokenCipher("other secret")
+	otherRepo := &GormUserIdentityRepository{DB: database, Tokens: otherTokens}
+	identities, err := otherRepo.FetchByUserID(1)
+	if err != nil || len(identities) != 1 || identities[0].AccessToken != "" || identities[0].RefreshToken != "" {
+		t.Errorf("got identities %+v, %v with another key; want the tokens dropped", identities, err)
+	}
+}
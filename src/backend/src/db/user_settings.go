@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/gorm"
+)
+
+// UserSettingsRepository defines the interface for managing user settings in the database.
+func NewGormUserSettingsRepository(database *gorm.DB) repositories.UserSettingsRepository {
+	repo := &GormUserSettingsRepository{DB: database}
+	repo.InitRepo()
+	return repo
+}
+
+// Specific implementation of `UserSettingsRepository` for GORM
+type GormUserSettingsRepository struct {
+	DB *gorm.DB
+}
+
+// automigrates the UserSettings GORM table
+func (r *GormUserSettingsRepository) InitRepo() error {
+	return r.DB.AutoMigrate(&UserSettings{})
+}
+
+// Returns the settings of the user. If the user has no record yet, the defaults are returned
+func (r *GormUserSettingsRepository) Get(userID Snowflake) (*UserSettings, error) {
+	var settings UserSettings
+	err := r.DB.First(&settings, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		settings = DefaultUserSettings(userID)
+		return &settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Inserts or updates the settings of a user
+func (r *GormUserSettingsRepository) Save(settings *UserSettings) (*UserSettings, error) {
+	if err := r.DB.Save(settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Returns the visibility the owner has chosen for <resource>. Implements `VisibilityProvider`
+func (r *GormUserSettingsRepository) GetVisibility(ownerID Snowflake, resource Resource) (Visibility, error) {
+	settings, err := r.Get(ownerID)
+	if err != nil {
+		return DefaultVisibility, err
+	}
+	return settings.VisibilityOf(resource), nil
+}
+
+// Returns the visibilities the owners have chosen for <resource> with one query. Implements `VisibilityProvider`
+func (r *GormUserSettingsRepository) GetVisibilities(ownerIDs []Snowflake, resource Resource) (map[Snowflake]Visibility, error) {
+	visibilities := make(map[Snowflake]Visibility, len(ownerIDs))
+	if len(ownerIDs) == 0 {
+		return visibilities, nil
+	}
+	var settings []UserSettings
+	if err := r.DB.Where("user_id IN ?", ownerIDs).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	for _, ownerID := range ownerIDs {
+		visibilities[ownerID] = DefaultVisibility
+	}
+	for _, s := range settings {
+		visibilities[s.UserID] = s.VisibilityOf(resource)
+	}
+	return visibilities, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestSearchDiscoverable verifies matching, ordering and the discoverability opt-in.
+func TestSearchDiscoverable(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	users := &GormUserRepository{DB: database}
+	settings := &GormUserSettingsRepository{DB: database}
+	if err := users.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate users: %v", err)
+	}
+	if err := settings.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate settings: %v", err)
+	}
+
+	searcher := Snowflake(1)
+	fixtures := []struct {
+		user         User
+		discoverable bool
+	}{
+		{User{ID: searcher, Username: "inu_searcher"}, true},
+		{User{ID: 2, Username: "inu"}, true},
+		{User{ID: 3, Username: "inuyasha"}, true},
+		{User{ID: 4, Username: "kuramainu"}, true},
+		{User{ID: 5, Username: "i_n_u"}, true},
+		{User{ID: 6, Username: "inuhidden"}, false},
+		{User{ID: 7, Username: "nobody"}, true},
+	}
+	for _, f := range fixtures {
+		if err := users.CreateUser(&f.user); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		s := DefaultUserSettings(f.user.ID)
+		s.Discoverable = f.discoverable
+		if _, err := settings.Save(&s); err != nil {
+			t.Fatalf("failed to save settings: %v", err)
+		}
+	}
+
+	var tests = []struct {
+		name  string
+		query string
+		want  []Snowflake
+	}{
+		{"Exact, prefix, substring and fuzzy matches in that order", "INU", []Snowflake{2, 3, 4, 5}},
+		{"Wildcards in the query are matched literally", "i_n", []Snowflake{5}},
+		{"No match returns nothing", "zzz", []Snowflake{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := users.SearchDiscoverable(tt.query, searcher, 10)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make([]Snowflake, 0, len(found))
+			for _, u := range found {
+				got = append(got, u.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
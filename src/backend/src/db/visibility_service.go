@@ -0,0 +1,163 @@
+package db
+
+import (
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// VisibilityProvider returns the visibility an owner has chosen for one of his resources
+type VisibilityProvider interface {
+	GetVisibility(ownerID Snowflake, resource Resource) (Visibility, error)
+	// returns the visibilities of all <ownerIDs> at once
+	GetVisibilities(ownerIDs []Snowflake, resource Resource) (map[Snowflake]Visibility, error)
+}
+
+// DefaultVisibilityProvider returns `DefaultVisibility` for every user and resource
+type DefaultVisibilityProvider struct{}
+
+func (DefaultVisibilityProvider) GetVisibility(ownerID Snowflake, resource Resource) (Visibility, error) {
+	return DefaultVisibility, nil
+}
+
+func (DefaultVisibilityProvider) GetVisibilities(ownerIDs []Snowflake, resource Resource) (map[Snowflake]Visibility, error) {
+	visibilities := make(map[Snowflake]Visibility, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		visibilities[ownerID] = DefaultVisibility
+	}
+	return visibilities, nil
+}
+
+// IVisibilityService decides whether a user (viewer) is allowed to read user scoped
+// resources (activities, streaks, goals, ...) of another user (owner).
+// It is shared by everything, which returns data of users other than the logged in one, so that
+// the rules exist only here.
+type IVisibilityService interface {
+	CanView(viewerID Snowflake, ownerID Snowflake, resource Resource) (bool, error)
+	FilterVisible(viewerID Snowflake, ownerIDs []Snowflake, resource Resource) (visible []Snowflake, forbidden []Snowflake, err error)
+	// hides streak and last activity of the friends in <entries>, who don't allow the viewer to see them
+	HideInvisible(viewerID Snowflake, entries []FriendEntry) error
+}
+
+type VisibilityService struct {
+	FriendshipRepo FriendshipRepository
+	Visibilities   VisibilityProvider
+}
+
+func NewVisibilityService(friendshipRepo FriendshipRepository, visibilities VisibilityProvider) *VisibilityService {
+	if visibilities == nil {
+		visibilities = DefaultVisibilityProvider{}
+	}
+	return &VisibilityService{
+		FriendshipRepo: friendshipRepo,
+		Visibilities:   visibilities,
+	}
+}
+
+// CanView returns whether <viewerID> is allowed to see <resource> of <ownerID>.
+// The owner can always see his own data.
+func (s *VisibilityService) CanView(viewerID Snowflake, ownerID Snowflake, resource Resource) (bool, error) {
+	if viewerID == ownerID {
+		return true, nil
+	}
+
+	visibility, err := s.Visibilities.GetVisibility(ownerID, resource)
+	if err != nil {
+		return false, err
+	}
+	isFriend := false
+	if visibility == FriendsOnly {
+		isFriend, err = s.FriendshipRepo.HavePositiveFriendshipStatus(viewerID, ownerID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return visibility.Allows(false, isFriend), nil
+}
+
+// FilterVisible splits <ownerIDs> into the IDs, whose <resource> the viewer is allowed to see
+// and the ones which are forbidden. The order of the given IDs is kept and duplicates are removed.
+// Visibilities and friendships are loaded at once, instead of once per owner.
+func (s *VisibilityService) FilterVisible(
+	viewerID Snowflake,
+	ownerIDs []Snowflake,
+	resource Resource,
+) (visible []Snowflake, forbidden []Snowflake, err error) {
+	visible = make([]Snowflake, 0, len(ownerIDs))
+	forbidden = make([]Snowflake, 0)
+	if len(ownerIDs) == 0 {
+		return visible, forbidden, nil
+	}
+
+	visibilities, err := s.Visibilities.GetVisibilities(ownerIDs, resource)
+	if err != nil {
+		return nil, nil, err
+	}
+	friendships, err := s.FriendshipRepo.GetFriendships(viewerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	friends := make(map[Snowflake]bool, len(friendships))
+	for _, friendship := range friendships {
+		if friendship.Status != Accepted {
+			continue
+		}
+		friends[friendship.RequesterID] = true
+		friends[friendship.RecipientID] = true
+	}
+
+	seen := make(map[Snowflake]bool, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		if seen[ownerID] {
+			continue
+		}
+		seen[ownerID] = true
+
+		visibility, ok := visibilities[ownerID]
+		if !ok {
+			visibility = DefaultVisibility
+		}
+		if visibility.Allows(ownerID == viewerID, friends[ownerID]) {
+			visible = append(visible, ownerID)
+		} else {
+			forbidden = append(forbidden, ownerID)
+		}
+	}
+	return visible, forbidden, nil
+}
+
+// HideInvisible sets `StreakVisible` of the friends in <entries>, who allow the viewer to see their
+// streak and removes the last activity of the ones, who don't allow him to see their activities
+func (s *VisibilityService) HideInvisible(viewerID Snowflake, entries []FriendEntry) error {
+	friendIDs := make([]Snowflake, 0, len(entries))
+	for _, entry := range entries {
+		friendIDs = append(friendIDs, entry.Friend.ID)
+	}
+	streakVisible, err := s.visibleSet(viewerID, friendIDs, ResourceStreak)
+	if err != nil {
+		return err
+	}
+	activitiesVisible, err := s.visibleSet(viewerID, friendIDs, ResourceActivities)
+	if err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].StreakVisible = streakVisible[entries[i].Friend.ID]
+		if !activitiesVisible[entries[i].Friend.ID] {
+			entries[i].LastActivity = nil
+		}
+	}
+	return nil
+}
+
+// returns the set of <ownerIDs>, whose <resource> the viewer is allowed to see
+func (s *VisibilityService) visibleSet(viewerID Snowflake, ownerIDs []Snowflake, resource Resource) (map[Snowflake]bool, error) {
+	visible, _, err := s.FilterVisible(viewerID, ownerIDs, resource)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[Snowflake]bool, len(visible))
+	for _, ownerID := range visible {
+		set[ownerID] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// mapVisibilityProvider returns the visibility stored for the owner or the default one
+type mapVisibilityProvider map[Snowflake]Visibility
+
+func (m mapVisibilityProvider) GetVisibility(ownerID Snowflake, resource Resource) (Visibility, error) {
+	if visibility, ok := m[ownerID]; ok {
+		return visibility, nil
+	}
+	return DefaultVisibility, nil
+}
+
+func (m mapVisibilityProvider) GetVisibilities(ownerIDs []Snowflake, resource Resource) (map[Snowflake]Visibility, error) {
+	visibilities := make(map[Snowflake]Visibility, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		visibilities[ownerID], _ = m.GetVisibility(ownerID, resource)
+	}
+	return visibilities, nil
+}
+
+func TestVisibilityServiceCanView(t *testing.T) {
+	repo := newTestFriendshipRepo(t)
+
+	viewer := Snowflake(1)
+	friend := Snowflake(2)
+	stranger := Snowflake(3)
+	publicStranger := Snowflake(4)
+	privateFriend := Snowflake(5)
+
+	if _, err := repo.CreateFriendship(viewer, friend, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	if _, err := repo.CreateFriendship(privateFriend, viewer, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+
+	service := NewVisibilityService(repo, mapVisibilityProvider{
+		publicStranger: Public,
+		privateFriend:  Private,
+	})
+
+	var tests = []struct {
+		name  string
+		owner Snowflake
+		want  bool
+	}{
+		{"Own data is always visible", viewer, true},
+		{"Accepted friend with default visibility is visible", friend, true},
+		{"Stranger with default visibility is not visible", stranger, false},
+		{"Stranger with public visibility is visible", publicStranger, true},
+		{"Friend with private visibility is not visible", privateFriend, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.CanView(viewer, tt.owner, ResourceActivities)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			visible, _, err := service.FilterVisible(viewer, []Snowflake{tt.owner}, ResourceActivities)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (len(visible) == 1) != tt.want {
+				t.Errorf("got visible %v from FilterVisible, want %v", visible, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisibilityServiceFilterVisible(t *testing.T) {
+	repo := newTestFriendshipRepo(t)
+
+	viewer := Snowflake(10)
+	friend := Snowflake(20)
+	pending := Snowflake(30)
+
+	if _, err := repo.CreateFriendship(viewer, friend, Accepted); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+	if _, err := repo.CreateFriendship(viewer, pending, Pending); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+
+	service := NewVisibilityService(repo, nil)
+
+	visible, forbidden, err := service.FilterVisible(viewer, []Snowflake{friend, pending, viewer, friend}, ResourceStreak)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// duplicates are removed and the order is kept
+	if want := []Snowflake{friend, viewer}; !reflect.DeepEqual(visible, want) {
+		t.Errorf("visible: got %v, want %v", visible, want)
+	}
+	// pending friendships do not grant access
+	if want := []Snowflake{pending}; !reflect.DeepEqual(forbidden, want) {
+		t.Errorf("forbidden: got %v, want %v", forbidden, want)
+	}
+}
@@ -0,0 +1,268 @@
+package discordbot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+const (
+	// provider of the identities, which link Discord users to users
+	discordProvider        = "discord"
+	maxDeathsPerCommand    = 100
+	maxExercisesPerCommand = 10000
+	leaderboardSize        = 10
+)
+
+// commandError is an error caused by the options of a command, which is shown to the user
+type commandError string
+
+func (e commandError) Error() string {
+	return string(e)
+}
+
+// Bot answers the slash commands of Discord users, whose discord identity is linked to a user
+type Bot struct {
+	Identities   repositories.UserIdentityRepository
+	Users        db.UserRepository
+	Sports       db.SportRepository
+	GameSessions repositories.GameSessionRepository
+	Deaths       db.IDeathCounter
+	Friendships  db.FriendshipRepository
+	Visibility   db.IVisibilityService
+	Reminders    repositories.StreakReminderRepository
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewBot(
+	identities repositories.UserIdentityRepository,
+	users db.UserRepository,
+	sports db.SportRepository,
+	gameSessions repositories.GameSessionRepository,
+	deaths db.IDeathCounter,
+	friendships db.FriendshipRepository,
+	visibility db.IVisibilityService,
+	reminders repositories.StreakReminderRepository,
+	Now func() time.Time,
+) *Bot {
+	return &Bot{
+		Identities:   identities,
+		Users:        users,
+		Sports:       sports,
+		GameSessions: gameSessions,
+		Deaths:       deaths,
+		Friendships:  friendships,
+		Visibility:   visibility,
+		Reminders:    reminders,
+		Now:          Now,
+	}
+}
+
+// Handle answers <interaction>. Errors are answered with a message for the user
+func (b *Bot) Handle(interaction *Interaction) InteractionResponse {
+	if interaction.Type == InteractionPing {
+		return InteractionResponse{Type: ResponsePong}
+	}
+	discordUser := interaction.Invoker()
+	if interaction.Type != InteractionApplicationCommand || interaction.Data == nil || discordUser == nil {
+		return reply("This interaction is not supported.")
+	}
+
+	identity, err := b.Identities.FetchByProviderSubject(discordProvider, discordUser.ID)
+	if err != nil {
+		log.Printf("Failed to fetch the identity of discord user %s: %v", discordUser.ID, err)
+		return reply("Something went wrong, please try again later.")
+	}
+	if identity == nil {
+		return reply("Your Discord account is not linked yet. Log in with Discord on the website first.")
+	}
+
+	content, err := b.run(identity.UserID, interaction.Data)
+	var cmdErr commandError
+	var repoErr *repositories.Error
+	switch {
+	case errors.As(err, &cmdErr), errors.As(err, &repoErr):
+		return reply(err.Error())
+	case err != nil:
+		log.Printf("Failed to run /%s for user %d: %v", interaction.Data.Name, identity.UserID, err)
+		return reply("Something went wrong, please try again later.")
+	}
+	return reply(content)
+}
+
+// runs the command <data> for <userID> and returns the message for the user
+func (b *Bot) run(userID models.Snowflake, data *CommandData) (string, error) {
+	switch data.Name {
+	case "died":
+		return b.died(userID, data)
+	case "did":
+		return b.did(userID, data)
+	case "streak":
+		return b.streak(userID)
+	case "leaderboard":
+		return b.leaderboard(userID)
+	case "reminders":
+		return b.reminders(userID, data)
+	}
+	return "", commandError(fmt.Sprintf("Unknown command /%s.", data.Name))
+}
+
+// returns <game> or the game of the live session of <userID>, if <game> is empty
+func (b *Bot) gameOrLive(userID models.Snowflake, game string) (string, *models.GameSession, error) {
+	live, err := b.GameSessions.FetchLive(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	if game == "" && live == nil {
+		return "", nil, commandError("Please name the game, since you have no live game session.")
+	}
+	if game == "" {
+		game = live.Game
+	}
+	return models.NormalizeGame(game), live, nil
+}
+
+// adds deaths to the live death counter
+func (b *Bot) died(userID models.Snowflake, data *CommandData) (string, error) {
+	count, ok := data.intOption("count")
+	if !ok || count < 1 || count > maxDeathsPerCommand {
+		return "", commandError(fmt.Sprintf("The count has to be between 1 and %d.", maxDeathsPerCommand))
+	}
+	game, _, err := b.gameOrLive(userID, data.stringOption("game"))
+	if err != nil {
+		return "", err
+	}
+	pending := b.Deaths.Increment(userID, game, count)
+	return fmt.Sprintf("Added %d deaths in %s. %d new deaths will be added to your overdue deaths shortly.", count, game, pending.Count), nil
+}
+
+// logs exercises like `POST /sports`
+func (b *Bot) did(userID models.Snowflake, data *CommandData) (string, error) {
+	amount, ok := data.intOption("amount")
+	if !ok || amount < 1 || amount > maxExercisesPerCommand {
+		return "", commandError(fmt.Sprintf("The amount has to be between 1 and %d.", maxExercisesPerCommand))
+	}
+	kind := strings.ToLower(data.stringOption("sport"))
+	if kind == "" {
+		return "", commandError("Please name the sport.")
+	}
+	game, live, err := b.gameOrLive(userID, data.stringOption("game"))
+	if err != nil {
+		return "", err
+	}
+
+	sport := models.Sport{
+		Kind:     kind,
+		Game:     game,
+		Amount:   int(amount),
+		UserID:   userID,
+		Timedate: b.Now().UTC(),
+	}
+	if live != nil && live.Game == game {
+		sport.SessionID = &live.ID
+	}
+	if err := b.Sports.InsertSport(sport); err != nil {
+		return "", err
+	}
+	streak, err := b.Sports.GetCurrentStreak(userID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Logged %d %s for %s. Your streak is %s.", amount, kind, game, days(streak.Days)), nil
+}
+
+// shows the current and the longest streak
+func (b *Bot) streak(userID models.Snowflake) (string, error) {
+	current, err := b.Sports.GetCurrentStreak(userID)
+	if err != nil {
+		return "", err
+	}
+	longest, err := b.Sports.GetLongestStreak(userID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Your current streak is %s, your longest %s.", days(current.Days), days(longest.Days)), nil
+}
+
+// shows the current streaks of the user and his friends, who allow him to see them
+func (b *Bot) leaderboard(userID models.Snowflake) (string, error) {
+	user, err := b.Users.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	friends, err := b.Friendships.GetFriendEntries(userID, models.FriendshipFilter{Status: models.Accepted})
+	if err != nil {
+		return "", err
+	}
+	if err := b.Visibility.HideInvisible(userID, friends); err != nil {
+		return "", err
+	}
+
+	names := map[models.Snowflake]string{userID: user.Username}
+	userIDs := []models.Snowflake{userID}
+	for _, friend := range friends {
+		if friend.StreakVisible {
+			names[friend.Friend.ID] = friend.Friend.Username
+			userIDs = append(userIDs, friend.Friend.ID)
+		}
+	}
+	streaks, err := b.Sports.GetCurrentStreaks(userIDs)
+	if err != nil {
+		return "", err
+	}
+
+	sort.SliceStable(userIDs, func(i, j int) bool {
+		a, b := streaks[userIDs[i]].Days, streaks[userIDs[j]].Days
+		if a != b {
+			return a > b
+		}
+		return names[userIDs[i]] < names[userIDs[j]]
+	})
+	if len(userIDs) > leaderboardSize {
+		userIDs = userIDs[:leaderboardSize]
+	}
+
+	var lines strings.Builder
+	lines.WriteString("**Current streaks**")
+	for i, id := range userIDs {
+		fmt.Fprintf(&lines, "\n%d. %s: %s", i+1, names[id], days(streaks[id].Days))
+	}
+	return lines.String(), nil
+}
+
+// turns the streak reminders and weekly digests on or off
+func (b *Bot) reminders(userID models.Snowflake, data *CommandData) (string, error) {
+	enabled, ok := data.boolOption("enabled")
+	if !ok {
+		return "", commandError("Please choose whether you want to be reminded.")
+	}
+	reminder, err := b.Reminders.Fetch(userID)
+	if err != nil {
+		return "", err
+	}
+	reminder.Disabled = !enabled
+	if err := b.Reminders.Save(reminder); err != nil {
+		return "", err
+	}
+	if enabled {
+		return "I'll send you a message, when your streak is about to break.", nil
+	}
+	return "You won't get reminders about your streak or weekly digests anymore.", nil
+}
+
+// formats <n> days
+func days(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
@@ -0,0 +1,251 @@
+package discordbot
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type testEnv struct {
+	bot    *Bot
+	sports *db.OrmSportRepository
+}
+
+// creates the users 1 (discord 100) and 2 (discord 200), who are friends, and user 3 without discord
+func newTestEnv(t *testing.T, Now func() time.Time) *testEnv {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.Sport{}, &models.OverdueDeaths{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	users := db.NewGormUserRepository(database)
+	tokens, err := db.NewTokenCipher("test secret")
+	if err != nil {
+		t.Fatalf("failed to create token cipher: %v", err)
+	}
+	identities := db.NewGormUserIdentityRepository(database, tokens)
+	friendships := db.NewGormFriendshipRepository(database)
+	settings := db.NewGormUserSettingsRepository(database)
+	deaths, err := db.NewDeathCounter(db.NewGormLiveDeathRepository(database), Now)
+	if err != nil {
+		t.Fatalf("failed to create death counter: %v", err)
+	}
+	sports := &db.OrmSportRepository{DB: database, StreakService: db.NewStreakService(Now)}
+
+	for _, user := range []models.User{{ID: 1, Username: "inu"}, {ID: 2, Username: "kurama"}, {ID: 3, Username: "nobody"}} {
+		if err := users.CreateUser(&user); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	for userID, subject := range map[models.Snowflake]string{1: "100", 2: "200"} {
+		if _, err := identities.Create(&models.UserIdentity{UserID: userID, Provider: "discord", Subject: subject}); err != nil {
+			t.Fatalf("failed to create identity: %v", err)
+		}
+	}
+	if err := friendships.EstablishFriendship(1, 2); err != nil {
+		t.Fatalf("failed to create friendship: %v", err)
+	}
+
+	bot := NewBot(
+		identities,
+		users,
+		sports,
+		db.NewGormGameSessionRepository(database),
+		deaths,
+		friendships,
+		db.NewVisibilityService(friendships, settings),
+		db.NewGormStreakReminderRepository(database),
+		Now,
+	)
+	return &testEnv{bot: bot, sports: sports}
+}
+
+// returns an interaction of the command <name> used by the Discord user <discordID> in a guild
+func command(discordID string, name string, options map[string]any) *Interaction {
+	interaction := &Interaction{Type: InteractionApplicationCommand, Data: &CommandData{Name: name}}
+	interaction.Member = &struct {
+		User *DiscordUser `json:"user"`
+	}{User: &DiscordUser{ID: discordID}}
+	for key, value := range options {
+		raw, _ := json.Marshal(value)
+		interaction.Data.Options = append(interaction.Data.Options, CommandOption{Name: key, Value: raw})
+	}
+	return interaction
+}
+
+func TestVerifySignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	body := []byte(`{"type":1}`)
+	signature := hex.EncodeToString(ed25519.Sign(privateKey, append([]byte("1683000000"), body...)))
+
+	tests := []struct {
+		name      string
+		key       ed25519.PublicKey
+		signature string
+		timestamp string
+		body      []byte
+		valid     bool
+	}{
+		{"valid", publicKey, signature, "1683000000", body, true},
+		{"other timestamp", publicKey, signature, "1683000001", body, false},
+		{"other body", publicKey, signature, "1683000000", []byte(`{"type":2}`), false},
+		{"not hex", publicKey, "xyz", "1683000000", body, false},
+		{"missing public key", nil, signature, "1683000000", body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.key, tt.signature, tt.timestamp, tt.body); got != tt.valid {
+				t.Errorf("got %v, want %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, func() time.Time { return now })
+	env.sports.InsertSport(models.Sport{UserID: 2, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -1)})
+	env.sports.InsertSport(models.Sport{UserID: 2, Kind: "pushup", Game: "league", Amount: 10, Timedate: now})
+
+	tests := []struct {
+		name        string
+		interaction *Interaction
+		want        string
+	}{
+		{"unlinked user", command("999", "streak", nil), "not linked"},
+		{"died without game", command("100", "died", map[string]any{"count": 5}), "name the game"},
+		{"died", command("100", "died", map[string]any{"count": 5, "game": " Overwatch "}), "Added 5 deaths in overwatch"},
+		{"died too often", command("100", "died", map[string]any{"count": 500, "game": "overwatch"}), "between 1 and 100"},
+		{"did", command("100", "did", map[string]any{"amount": 30, "sport": "pushup", "game": "overwatch"}), "Your streak is 1 day"},
+		{"streak", command("100", "streak", nil), "current streak is 1 day, your longest 1 day"},
+		{"leaderboard", command("100", "leaderboard", nil), "1. kurama: 2 days\n2. inu: 1 day"},
+		{"reminders", command("100", "reminders", map[string]any{"enabled": false}), "won't get reminders"},
+		{"unknown command", command("100", "dance", nil), "Unknown command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := env.bot.Handle(tt.interaction)
+			if response.Type != ResponseChannelMessageWithSource || !strings.Contains(response.Data.Content, tt.want) {
+				t.Errorf("got %+v, want a message containing %q", response.Data, tt.want)
+			}
+		})
+	}
+
+	if pending := env.bot.Deaths.Pending(1); len(pending) != 1 || pending[0].Count != 5 {
+		t.Errorf("got pending deaths %+v, want 5", pending)
+	}
+	if response := env.bot.Handle(&Interaction{Type: InteractionPing}); response.Type != ResponsePong {
+		t.Errorf("got %+v for a ping, want a pong", response)
+	}
+}
+
+// fakeDiscord stands in for the Discord API and records the sent direct messages
+type fakeDiscord struct {
+	mu       sync.Mutex
+	messages map[string][]string
+	commands []Command
+	// Discord users, who don't accept direct messages
+	closed map[string]bool
+}
+
+func (f *fakeDiscord) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bot test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && r.URL.Path == "/applications/42/commands":
+		json.NewDecoder(r.Body).Decode(&f.commands)
+		w.Write([]byte(`[]`))
+	case r.Method == http.MethodPost && r.URL.Path == "/users/@me/channels":
+		var body struct {
+			RecipientID string `json:"recipient_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]string{"id": "dm-" + body.RecipientID})
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/channels/dm-"):
+		recipient := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/channels/dm-"), "/messages")
+		if f.closed[recipient] {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Cannot send messages to this user","code":50007}`))
+			return
+		}
+		var message ResponseMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		f.messages[recipient] = append(f.messages[recipient], message.Content)
+		w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestStreakReminder(t *testing.T) {
+	now := time.Date(2023, 5, 1, 19, 0, 0, 0, time.UTC)
+	Now := func() time.Time { return now }
+	env := newTestEnv(t, Now)
+	// user 1 and 3 were active yesterday, user 2 today. User 3 has no Discord account
+	env.sports.InsertSport(models.Sport{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -2)})
+	env.sports.InsertSport(models.Sport{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -1)})
+	env.sports.InsertSport(models.Sport{UserID: 2, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -1)})
+	env.sports.InsertSport(models.Sport{UserID: 2, Kind: "pushup", Game: "league", Amount: 10, Timedate: now})
+	env.sports.InsertSport(models.Sport{UserID: 3, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -1)})
+
+	fake := &fakeDiscord{messages: map[string][]string{}, closed: map[string]bool{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := NewClient(server.URL, "42", "test-token", Now)
+	if err := client.RegisterCommands(context.Background(), Commands()); err != nil || len(fake.commands) != len(Commands()) {
+		t.Fatalf("got %d registered commands, %v; want %d", len(fake.commands), err, len(Commands()))
+	}
+	reminder := NewStreakReminder(env.sports, env.bot.Identities, env.bot.Reminders, client, Now)
+
+	if sent, err := reminder.RemindAll(context.Background()); err != nil || sent != 0 {
+		t.Errorf("got %d reminders before the reminder hour, %v; want 0", sent, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	fake.closed["100"] = true
+	if sent, err := reminder.RemindAll(context.Background()); err != nil || sent != 0 {
+		t.Errorf("got %d reminders for closed direct messages, %v; want 0", sent, err)
+	}
+
+	fake.closed["100"] = false
+	if sent, err := reminder.RemindAll(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("got %d reminders, %v; want 1", sent, err)
+	}
+	if messages := fake.messages["100"]; len(messages) != 1 || !strings.Contains(messages[0], "streak of 2 days") {
+		t.Errorf("got messages %v, want a reminder of the 2 days streak", messages)
+	}
+	if sent, _ := reminder.RemindAll(context.Background()); sent != 0 {
+		t.Errorf("got %d reminders on the same day, want 0", sent)
+	}
+
+	// the next evening user 1 has to be active again, but disabled the reminders
+	now = now.AddDate(0, 0, 1)
+	env.bot.Handle(command("100", "reminders", map[string]any{"enabled": false}))
+	env.sports.InsertSport(models.Sport{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: now.AddDate(0, 0, -1)})
+	if sent, _ := reminder.RemindAll(context.Background()); sent != 1 || len(fake.messages["100"]) != 1 || len(fake.messages["200"]) != 1 {
+		t.Errorf("got %d reminders and messages %v, want only one for user 2", sent, fake.messages)
+	}
+}
@@ -0,0 +1,84 @@
+package discordbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/auth"
+)
+
+// Client calls the Discord API as the bot user of the application
+type Client struct {
+	// base URL of the Discord API like https://discord.com/api
+	APIURL        string
+	ApplicationID string
+	Token         string
+	HTTP          *http.Client
+}
+
+func NewClient(apiURL string, applicationID string, token string, Now func() time.Time) *Client {
+	if apiURL == "" {
+		apiURL = auth.DefaultDiscordAPIURL
+	}
+	return &Client{
+		APIURL:        strings.TrimRight(apiURL, "/"),
+		ApplicationID: applicationID,
+		Token:         token,
+		HTTP: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: auth.NewDiscordRateLimiter(nil, Now),
+		},
+	}
+}
+
+// RegisterCommands replaces the global slash commands of the application with <commands>
+func (c *Client) RegisterCommands(ctx context.Context, commands []Command) error {
+	return c.do(ctx, http.MethodPut, "/applications/"+c.ApplicationID+"/commands", commands, nil)
+}
+
+// SendDM sends <content> as direct message to the Discord user <discordUserID>
+func (c *Client) SendDM(ctx context.Context, discordUserID string, content string) error {
+	var channel struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/users/@me/channels", map[string]string{"recipient_id": discordUserID}, &channel)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, "/channels/"+channel.ID+"/messages", ResponseMessage{Content: content}, nil)
+}
+
+// sends <body> as JSON to <path> and decodes the answer into <target>, if it's not nil
+func (c *Client) do(ctx context.Context, method string, path string, body any, target any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, message)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,233 @@
+// Package discordbot offers slash commands like /died and /did as a Discord application, which
+// receives its interactions via HTTP, and sends reminders as direct messages.
+// Discord users are linked to users by their discord identity.
+package discordbot
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/config"
+)
+
+// InteractionType is the type of an interaction sent by Discord
+type InteractionType int
+
+const (
+	InteractionPing               InteractionType = 1
+	InteractionApplicationCommand InteractionType = 2
+)
+
+// ResponseType is the type of the response to an interaction
+type ResponseType int
+
+const (
+	ResponsePong                     ResponseType = 1
+	ResponseChannelMessageWithSource ResponseType = 4
+)
+
+// OptionType is the type of the value of a command option
+type OptionType int
+
+const (
+	OptionString  OptionType = 3
+	OptionInteger OptionType = 4
+	OptionBoolean OptionType = 5
+)
+
+// messages with this flag are only shown to the user, who used the command
+const flagEphemeral = 1 << 6
+
+// DiscordUser is the user object of the Discord API
+type DiscordUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// Interaction is sent by Discord, when a user uses a command of the application
+type Interaction struct {
+	ID            string          `json:"id"`
+	ApplicationID string          `json:"application_id"`
+	Type          InteractionType `json:"type"`
+	Data          *CommandData    `json:"data,omitempty"`
+	// set for interactions in a guild
+	Member *struct {
+		User *DiscordUser `json:"user"`
+	} `json:"member,omitempty"`
+	// set for interactions in direct messages
+	User *DiscordUser `json:"user,omitempty"`
+}
+
+// Invoker returns the Discord user, who used the command
+func (i *Interaction) Invoker() *DiscordUser {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// CommandData contains the name and the options of the used command
+type CommandData struct {
+	Name    string          `json:"name"`
+	Options []CommandOption `json:"options,omitempty"`
+}
+
+// CommandOption is an option of a used command with its value
+type CommandOption struct {
+	Name  string          `json:"name"`
+	Type  OptionType      `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// returns the string value of the option <name> or "", if it's missing
+func (d *CommandData) stringOption(name string) string {
+	for _, option := range d.Options {
+		if option.Name == name {
+			var value string
+			if json.Unmarshal(option.Value, &value) == nil {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return ""
+}
+
+// returns the integer value of the option <name> or false, if it's missing
+func (d *CommandData) intOption(name string) (int64, bool) {
+	for _, option := range d.Options {
+		if option.Name == name {
+			value, err := strconv.ParseInt(string(option.Value), 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+// returns the boolean value of the option <name> or false, if it's missing
+func (d *CommandData) boolOption(name string) (bool, bool) {
+	for _, option := range d.Options {
+		if option.Name == name {
+			value, err := strconv.ParseBool(string(option.Value))
+			return value, err == nil
+		}
+	}
+	return false, false
+}
+
+// InteractionResponse is the reply to an interaction
+type InteractionResponse struct {
+	Type ResponseType     `json:"type"`
+	Data *ResponseMessage `json:"data,omitempty"`
+}
+
+// ResponseMessage is the message sent as response
+type ResponseMessage struct {
+	Content string `json:"content"`
+	Flags   int    `json:"flags,omitempty"`
+}
+
+// returns a response with a message, which only the user of the command sees
+func reply(content string) InteractionResponse {
+	return InteractionResponse{
+		Type: ResponseChannelMessageWithSource,
+		Data: &ResponseMessage{Content: content, Flags: flagEphemeral},
+	}
+}
+
+// VerifySignature checks the Ed25519 signature Discord sends with every interaction in the headers
+// X-Signature-Ed25519 and X-Signature-Timestamp. The signature covers the timestamp followed by the body
+func VerifySignature(publicKey ed25519.PublicKey, signature string, timestamp string, body []byte) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	message := make([]byte, 0, len(timestamp)+len(body))
+	message = append(message, timestamp...)
+	message = append(message, body...)
+	return ed25519.Verify(publicKey, message, sig)
+}
+
+// Command is the definition of a slash command, which is registered at Discord
+type Command struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Options     []OptionDefinition `json:"options,omitempty"`
+}
+
+// OptionDefinition is the definition of an option of a slash command
+type OptionDefinition struct {
+	Type        OptionType     `json:"type"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required,omitempty"`
+	MinValue    *int64         `json:"min_value,omitempty"`
+	MaxValue    *int64         `json:"max_value,omitempty"`
+	Choices     []OptionChoice `json:"choices,omitempty"`
+}
+
+// OptionChoice is a predefined value of an option
+type OptionChoice struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Discord allows at most 25 choices per option
+const maxChoices = 25
+
+// returns the first column of a default CSV like `config.DefaultSportsCsv` as choices
+func choicesFromCsv(csv [][]string) []OptionChoice {
+	choices := []OptionChoice{}
+	for i, record := range csv {
+		if i == 0 || len(record) == 0 || len(choices) == maxChoices {
+			continue
+		}
+		choices = append(choices, OptionChoice{Name: record[0], Value: record[0]})
+	}
+	return choices
+}
+
+func ptr(value int64) *int64 {
+	return &value
+}
+
+// Commands returns the slash commands of the bot
+func Commands() []Command {
+	games := choicesFromCsv(config.DefaultGamesCsv)
+	return []Command{
+		{
+			Name:        "died",
+			Description: "Adds deaths to your overdue deaths",
+			Options: []OptionDefinition{
+				{Type: OptionInteger, Name: "count", Description: "Number of deaths", Required: true, MinValue: ptr(1), MaxValue: ptr(maxDeathsPerCommand)},
+				{Type: OptionString, Name: "game", Description: "The game, defaults to the game of your live session", Choices: games},
+			},
+		},
+		{
+			Name:        "did",
+			Description: "Logs exercises you did",
+			Options: []OptionDefinition{
+				{Type: OptionInteger, Name: "amount", Description: "Number of exercises", Required: true, MinValue: ptr(1), MaxValue: ptr(maxExercisesPerCommand)},
+				{Type: OptionString, Name: "sport", Description: "The sport", Required: true, Choices: choicesFromCsv(config.DefaultSportsCsv)},
+				{Type: OptionString, Name: "game", Description: "The game, defaults to the game of your live session", Choices: games},
+			},
+		},
+		{
+			Name:        "streak",
+			Description: "Shows your current and longest streak",
+		},
+		{
+			Name:        "leaderboard",
+			Description: "Shows the current streaks of you and your friends",
+		},
+		{
+			Name:        "reminders",
+			Description: "Turns the direct messages about your streak and goals on or off",
+			Options: []OptionDefinition{
+				{Type: OptionBoolean, Name: "enabled", Description: "Whether you want to be reminded", Required: true},
+			},
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package discordbot
+
+import (
+	"context"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Notifier sends messages to users as direct messages of the bot
+type Notifier struct {
+	Identities repositories.UserIdentityRepository
+	Sender     DMSender
+}
+
+func NewNotifier(identities repositories.UserIdentityRepository, sender DMSender) *Notifier {
+	return &Notifier{Identities: identities, Sender: sender}
+}
+
+// Notify sends <message> to <userID>. Returns false, if the user didn't link a Discord account
+func (n *Notifier) Notify(ctx context.Context, userID models.Snowflake, message string) (bool, error) {
+	discordID, err := discordUserID(n.Identities, userID)
+	if err != nil || discordID == "" {
+		return false, err
+	}
+	if err := n.Sender.SendDM(ctx, discordID, message); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// returns the ID of the Discord account of <userID> or "", if none is linked
+func discordUserID(identities repositories.UserIdentityRepository, userID models.Snowflake) (string, error) {
+	linked, err := identities.FetchByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, identity := range linked {
+		if identity.Provider == discordProvider {
+			return identity.Subject, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,105 @@
+package discordbot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+)
+
+// UTC hour, after which streaks are reminded by default
+const DefaultReminderHour = 20
+
+// DMSender sends direct messages to Discord users
+type DMSender interface {
+	SendDM(ctx context.Context, discordUserID string, content string) error
+}
+
+// StreakReminder sends a direct message to users, whose streak breaks at midnight UTC, since they
+// were active yesterday but not today yet
+type StreakReminder struct {
+	Sports     db.SportRepository
+	Identities repositories.UserIdentityRepository
+	Reminders  repositories.StreakReminderRepository
+	Sender     DMSender
+	// UTC hour, after which users are reminded
+	Hour int
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewStreakReminder(
+	sports db.SportRepository,
+	identities repositories.UserIdentityRepository,
+	reminders repositories.StreakReminderRepository,
+	sender DMSender,
+	Now func() time.Time,
+) *StreakReminder {
+	return &StreakReminder{
+		Sports:     sports,
+		Identities: identities,
+		Reminders:  reminders,
+		Sender:     sender,
+		Hour:       DefaultReminderHour,
+		Now:        Now,
+	}
+}
+
+// RemindAll reminds every user with a streak at risk at most once per day and returns the
+// number of sent messages. Before <Hour> nobody is reminded
+func (r *StreakReminder) RemindAll(ctx context.Context) (int, error) {
+	now := r.Now().UTC()
+	if now.Hour() < r.Hour {
+		return 0, nil
+	}
+	today := now.Format(time.DateOnly)
+
+	userIDs, err := r.Sports.GetUserIDsLastActiveOn(now.AddDate(0, 0, -1))
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, userID := range userIDs {
+		reminder, err := r.Reminders.Fetch(userID)
+		if err != nil {
+			return sent, err
+		}
+		if reminder.Disabled || reminder.LastSentOn == today {
+			continue
+		}
+		discordID, err := discordUserID(r.Identities, userID)
+		if err != nil {
+			return sent, err
+		}
+		if discordID == "" {
+			continue
+		}
+		streak, err := r.Sports.GetCurrentStreak(userID)
+		if err != nil {
+			return sent, err
+		}
+		if streak.Days == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf(
+			"Your streak of %s ends at midnight UTC. Do some exercises today to keep it! Use `/reminders enabled:false` to stop these messages.",
+			days(streak.Days),
+		)
+		// users can close their direct messages, which shouldn't stop the other reminders
+		if err := r.Sender.SendDM(ctx, discordID, message); err != nil {
+			log.Printf("Failed to send the streak reminder to user %d: %v", userID, err)
+			continue
+		}
+		reminder.LastSentOn = today
+		if err := r.Reminders.Save(reminder); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
@@ -0,0 +1,247 @@
+// Package events ingests game events like deaths, which are sent by games and tools like OBS
+// plugins. Events are signed with a secret of the user and mapped to overdue deaths and game sessions.
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+const (
+	// signatures older than this are rejected, so that captured events can't be replayed later
+	defaultSignatureTolerance = 5 * time.Minute
+	maxEventIDLength          = 128
+	maxDeathsPerEvent         = 100
+)
+
+// ErrInvalidSignature is returned for events without a valid signature of the user
+var ErrInvalidSignature = errors.New("invalid or expired event signature")
+
+// InvalidEventError is the reason of rejected events, which can't be parsed or miss fields
+type InvalidEventError struct {
+	Message string
+}
+
+func (e *InvalidEventError) Error() string {
+	return e.Message
+}
+
+func invalidEvent(format string, args ...any) error {
+	return &InvalidEventError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Event is the body of a game event
+type Event struct {
+	// unique ID of the event. Events with an ID sent before are ignored
+	ID   string               `json:"id" example:"9b2f0c6e-4a51-4a0e-8d0b-5c1f0f2d7e11"`
+	Type models.GameEventType `json:"type" example:"death"`
+	// required for death and match_start, match_end defaults to the game of the live session
+	Game string `json:"game" example:"overwatch"`
+	// when the event happened, defaults to the time it was received
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
+	// number of deaths of a death event, defaults to 1
+	Count int64 `json:"count,omitempty" example:"1"`
+	// result of the match of a match_end event
+	Outcome models.MatchOutcome `json:"outcome,omitempty" example:"win"`
+}
+
+// Processor verifies game events and applies them
+type Processor struct {
+	Events       repositories.GameEventRepository
+	GameSessions repositories.GameSessionRepository
+	Deaths       db.IDeathCounter
+	// maximum age of a signature
+	Tolerance time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewProcessor(
+	events repositories.GameEventRepository,
+	gameSessions repositories.GameSessionRepository,
+	deaths db.IDeathCounter,
+	Now func() time.Time,
+) *Processor {
+	return &Processor{
+		Events:       events,
+		GameSessions: gameSessions,
+		Deaths:       deaths,
+		Tolerance:    defaultSignatureTolerance,
+		Now:          Now,
+	}
+}
+
+// Sign returns the signature of <body> sent at the unix <timestamp> in the format sha256=<hex HMAC>.
+// The HMAC-SHA256 is calculated with <secret> over "<timestamp>.<body>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return models.EventSignatureScheme + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks, that <body> was signed with the secret of <userID> at <timestamp> within the tolerance
+func (p *Processor) Verify(userID models.Snowflake, timestamp string, signature string, body []byte) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := p.Now().Sub(time.Unix(sentAt, 0))
+	if age > p.Tolerance || age < -p.Tolerance {
+		return ErrInvalidSignature
+	}
+
+	secret, err := p.Events.FetchSecret(userID)
+	if err != nil {
+		return err
+	}
+	if secret == nil || !strings.HasPrefix(signature, models.EventSignatureScheme) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret.Secret, sentAt, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ingest stores the verified event <body> of <userID> and applies it. Events with an ID sent before
+// are not applied again and true is returned. Events, which are invalid or can't be applied, e.g. a
+// match_end without a live game session, are stored with the status rejected and the reason as error
+func (p *Processor) Ingest(userID models.Snowflake, body []byte) (*models.GameEvent, bool, error) {
+	now := p.Now().UTC()
+	var event Event
+	invalid := json.Unmarshal(body, &event)
+	if invalid != nil {
+		invalid = invalidEvent("invalid event: %v", invalid)
+	} else {
+		invalid = p.validate(&event, now)
+	}
+
+	stored := &models.GameEvent{
+		UserID:     userID,
+		EventID:    event.ID,
+		Type:       event.Type,
+		Game:       event.Game,
+		Payload:    string(body),
+		Status:     models.EventProcessed,
+		ReceivedAt: now,
+	}
+	if invalid != nil {
+		stored.Status = models.EventRejected
+		stored.Error = invalid.Error()
+		if event.ID == "" || len(event.ID) > maxEventIDLength {
+			stored.EventID = invalidEventID(body)
+		}
+	}
+	created, err := p.Events.Record(stored)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		previous, err := p.Events.FetchEvent(userID, stored.EventID)
+		if err != nil {
+			return nil, false, err
+		}
+		return previous, true, nil
+	}
+	if invalid != nil {
+		return stored, false, nil
+	}
+
+	if err := p.apply(userID, &event); err != nil {
+		stored.Status = models.EventRejected
+		stored.Error = err.Error()
+		if err := p.Events.UpdateStatus(stored); err != nil {
+			return nil, false, err
+		}
+	}
+	return stored, false, nil
+}
+
+// returns the ID of an invalid event without a usable ID. It's derived from <body>, so that
+// resending the same event is detected as duplicate
+func invalidEventID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "invalid-" + hex.EncodeToString(sum[:])
+}
+
+// checks the fields of <event> and sets the defaults
+func (p *Processor) validate(event *Event, now time.Time) error {
+	if event.ID == "" || len(event.ID) > maxEventIDLength {
+		return invalidEvent("id is required and can have at most %d characters", maxEventIDLength)
+	}
+	if !event.Type.IsValid() {
+		return invalidEvent("invalid type %q, expected one of death, match_start, match_end", event.Type)
+	}
+	if event.Game == "" && event.Type != models.EventMatchEnd {
+		return invalidEvent("game is required for %s events", event.Type)
+	}
+	if !event.Outcome.IsValid() {
+		return invalidEvent("invalid outcome %q, expected one of win, loss, draw", event.Outcome)
+	}
+	if event.Count == 0 {
+		event.Count = 1
+	}
+	if event.Count < 0 || event.Count > maxDeathsPerEvent {
+		return invalidEvent("count has to be between 1 and %d", maxDeathsPerEvent)
+	}
+	if event.OccurredAt == nil || event.OccurredAt.After(now) {
+		event.OccurredAt = &now
+	}
+	return nil
+}
+
+// maps <event> to overdue deaths or game session updates
+func (p *Processor) apply(userID models.Snowflake, event *Event) error {
+	occurredAt := event.OccurredAt.UTC()
+	switch event.Type {
+	case models.EventDeath:
+		p.Deaths.Increment(userID, event.Game, event.Count)
+		return nil
+
+	case models.EventMatchStart:
+		_, err := p.GameSessions.Create(&models.GameSession{UserID: userID, Game: event.Game, StartedAt: occurredAt})
+		return err
+
+	case models.EventMatchEnd:
+		// deaths of the live death counter belong to the session
+		if err := p.Deaths.FlushUser(userID); err != nil {
+			return err
+		}
+		session, err := p.GameSessions.FetchLive(userID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return repositories.NotFound("there is no live game session")
+		}
+		if event.Game != "" && event.Game != session.Game {
+			return repositories.Conflict("the live game session is for %s, not %s", session.Game, event.Game)
+		}
+		if event.Outcome != "" {
+			session.Outcome = event.Outcome
+			if _, err := p.GameSessions.Update(session); err != nil {
+				return err
+			}
+		}
+		if occurredAt.Before(session.StartedAt) {
+			occurredAt = session.StartedAt
+		}
+		_, err = p.GameSessions.Close(session.ID, userID, occurredAt, 0)
+		return err
+	}
+	return invalidEvent("invalid type %q", event.Type)
+}
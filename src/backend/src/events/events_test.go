@@ -0,0 +1,140 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testSecret = models.EventSecretPrefix + "secret"
+
+func newTestProcessor(t *testing.T, now *time.Time) (*Processor, *gorm.DB) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.OverdueDeaths{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	Now := func() time.Time { return *now }
+	deaths, err := db.NewDeathCounter(db.NewGormLiveDeathRepository(database), Now)
+	if err != nil {
+		t.Fatalf("failed to create death counter: %v", err)
+	}
+	tokens, err := db.NewTokenCipher("secret")
+	if err != nil {
+		t.Fatalf("failed to create token cipher: %v", err)
+	}
+	repo := db.NewGormGameEventRepository(database, tokens)
+	if _, err := repo.SaveSecret(&models.EventSigningSecret{UserID: 1, Secret: testSecret}); err != nil {
+		t.Fatalf("failed to save secret: %v", err)
+	}
+	return NewProcessor(repo, db.NewGormGameSessionRepository(database), deaths, Now), database
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC)
+	processor, _ := newTestProcessor(t, &now)
+	body := []byte(`{"id":"1","type":"death","game":"overwatch"}`)
+	timestamp := now.Unix()
+
+	tests := []struct {
+		name      string
+		userID    models.Snowflake
+		timestamp string
+		signature string
+		valid     bool
+	}{
+		{"valid", 1, strconv.FormatInt(timestamp, 10), Sign(testSecret, timestamp, body), true},
+		{"slightly in the future", 1, strconv.FormatInt(timestamp+30, 10), Sign(testSecret, timestamp+30, body), true},
+		{"wrong secret", 1, strconv.FormatInt(timestamp, 10), Sign("gths_other", timestamp, body), false},
+		{"signed with another timestamp", 1, strconv.FormatInt(timestamp, 10), Sign(testSecret, timestamp-1, body), false},
+		{"expired", 1, strconv.FormatInt(timestamp-600, 10), Sign(testSecret, timestamp-600, body), false},
+		{"missing scheme", 1, strconv.FormatInt(timestamp, 10), Sign(testSecret, timestamp, body)[len(models.EventSignatureScheme):], false},
+		{"invalid timestamp", 1, "yesterday", Sign(testSecret, timestamp, body), false},
+		{"user without secret", 2, strconv.FormatInt(timestamp, 10), Sign(testSecret, timestamp, body), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := processor.Verify(tt.userID, tt.timestamp, tt.signature, body)
+			if tt.valid && err != nil {
+				t.Errorf("got %v, want a valid signature", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+// TestIngest verifies that events are applied once and rejected events are stored with the reason
+func TestIngest(t *testing.T) {
+	now := time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC)
+	processor, database := newTestProcessor(t, &now)
+
+	ingest := func(body string) (*models.GameEvent, bool) {
+		t.Helper()
+		event, duplicate, err := processor.Ingest(1, []byte(body))
+		if err != nil {
+			t.Fatalf("failed to ingest %s: %v", body, err)
+		}
+		return event, duplicate
+	}
+
+	// invalid events are stored as rejected with the reason
+	for _, body := range []string{`{"type":"death","game":"overwatch"}`, `{"id":"x1","type":"respawn","game":"overwatch"}`, `{"id":"x2","type":"death"}`, `{"id":"x3","type":"death","game":"overwatch","count":101}`, `[]`} {
+		if event, duplicate := ingest(body); duplicate || event.Status != models.EventRejected || event.Error == "" {
+			t.Errorf("got %+v, duplicate %v for %s, want it rejected", event, duplicate, body)
+		}
+	}
+	if event, duplicate := ingest(`[]`); !duplicate || event.Status != models.EventRejected {
+		t.Errorf("got %+v, duplicate %v for a resent invalid event, want the rejected duplicate", event, duplicate)
+	}
+	if pending := processor.Deaths.Pending(1); len(pending) != 0 {
+		t.Errorf("got pending deaths %+v of invalid events", pending)
+	}
+
+	if event, _ := ingest(`{"id":"end-0","type":"match_end"}`); event.Status != models.EventRejected || event.Error == "" {
+		t.Errorf("got %+v for a match_end without live session, want it rejected", event)
+	}
+
+	ingest(`{"id":"start-1","type":"match_start","game":"overwatch"}`)
+	ingest(`{"id":"death-1","type":"death","game":"overwatch","count":2}`)
+	if event, duplicate := ingest(`{"id":"death-1","type":"death","game":"overwatch","count":2}`); !duplicate || event.Status != models.EventProcessed {
+		t.Errorf("got %+v, duplicate %v for a resent event, want the processed duplicate", event, duplicate)
+	}
+	if pending := processor.Deaths.Pending(1); len(pending) != 1 || pending[0].Count != 2 {
+		t.Errorf("got pending deaths %+v, want 2", pending)
+	}
+	if event, _ := ingest(`{"id":"end-1","type":"match_end","game":"league"}`); event.Status != models.EventRejected {
+		t.Errorf("got %+v for a match_end of another game, want it rejected", event)
+	}
+
+	now = now.Add(time.Hour)
+	if event, _ := ingest(`{"id":"end-2","type":"match_end","outcome":"loss"}`); event.Status != models.EventProcessed {
+		t.Fatalf("got %+v for match_end, want it processed", event)
+	}
+	var session models.GameSession
+	database.First(&session)
+	if session.IsLive() || session.Deaths != 2 || session.Outcome != models.OutcomeLoss || session.Duration(now) != time.Hour {
+		t.Errorf("got session %+v, want a closed 1h loss with 2 deaths", session)
+	}
+	var overdue models.OverdueDeaths
+	database.Where(&models.OverdueDeaths{UserID: 1, Game: "overwatch"}).First(&overdue)
+	if overdue.Count != 2 {
+		t.Errorf("got %d overdue deaths, want 2", overdue.Count)
+	}
+
+	stored, err := processor.Events.FetchByUserID(1, 20)
+	if err != nil || len(stored) != 10 {
+		t.Errorf("got %d stored events, %v; want 10", len(stored), err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package importer imports workouts from CSV and JSON files, e.g. exported from the
+// spreadsheets used before GoToHell.
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/config"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Report is the result of an import
+// swagger:model ImportReport
+type Report struct {
+	// whether or not the sports were only checked and not inserted
+	DryRun bool `json:"dry_run"`
+	// number of rows in the file
+	Total int `json:"total" example:"120"`
+	// number of sports, which were (or would be) imported
+	Imported int `json:"imported" example:"112"`
+	// number of rows, which were already imported or appear twice in the file
+	Duplicates int `json:"duplicates" example:"6"`
+	// number of rows, which can't be imported
+	Invalid int `json:"invalid" example:"2"`
+	// sport names of the file, which were mapped to a sport through the alias table
+	Aliases map[string]string `json:"aliases,omitempty" example:"Push-Ups:pushup"`
+	Errors  []RowError        `json:"errors"`
+	// sports, which would be imported. Only set for dry runs
+	Sports []models.Sport `json:"sports,omitempty"`
+}
+
+// Importer converts the rows of import files to sports of a user and inserts them
+type Importer struct {
+	Repo db.SportRepository
+	// names of the known sports
+	Sports map[string]bool
+	// normalized alias -> name of the sport
+	Aliases map[string]string
+}
+
+// New returns an importer, which knows the default sports and the aliases of `config.SportAliasesCsv`.
+// Fails, if the embedded aliases reference unknown sports
+func New(repo db.SportRepository) (*Importer, error) {
+	importer := &Importer{
+		Repo:    repo,
+		Sports:  map[string]bool{},
+		Aliases: map[string]string{},
+	}
+	for i, record := range config.DefaultSportsCsv {
+		if i == 0 || len(record) == 0 {
+			continue
+		}
+		importer.Sports[record[0]] = true
+	}
+	if err := importer.AddAliases(config.SportAliasesCsv); err != nil {
+		return nil, fmt.Errorf("invalid embedded sport aliases: %w", err)
+	}
+	return importer, nil
+}
+
+// AddAliases adds aliases from CSV records with the header alias,sport
+func (im *Importer) AddAliases(records [][]string) error {
+	for i, record := range records {
+		if i == 0 {
+			continue
+		}
+		if len(record) != 2 {
+			return fmt.Errorf("alias line %d: expected alias,sport", i+1)
+		}
+		sport := normalizeName(record[1])
+		if !im.Sports[sport] {
+			return fmt.Errorf("alias line %d: unknown sport %q", i+1, record[1])
+		}
+		im.Aliases[normalizeName(record[0])] = sport
+	}
+	return nil
+}
+
+// Import parses <r> and inserts the sports of it for <userID>. Exact duplicates (same sport,
+// game, amount and time) within the file or of already stored sports are skipped.
+// With <dryRun>, nothing is inserted and the report contains the sports, which would be imported
+func (im *Importer) Import(userID models.Snowflake, r io.Reader, format Format, dryRun bool) (*Report, error) {
+	rows, rowErrors, err := Parse(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		DryRun:  dryRun,
+		Total:   len(rows) + len(rowErrors),
+		Aliases: map[string]string{},
+		Errors:  append([]RowError{}, rowErrors...),
+	}
+
+	sports := make([]models.Sport, 0, len(rows))
+	for _, row := range rows {
+		kind, ok := im.resolveSport(row.Sport)
+		if !ok {
+			report.Errors = append(report.Errors, RowError{Line: row.Line, Message: fmt.Sprintf("unknown sport %q", row.Sport)})
+			continue
+		}
+		if kind != row.Sport {
+			report.Aliases[row.Sport] = kind
+		}
+		sports = append(sports, models.Sport{
+			Kind:     kind,
+			Amount:   row.Amount,
+			Game:     row.Game,
+			UserID:   userID,
+			Timedate: row.Date,
+		})
+	}
+	report.Invalid = len(report.Errors)
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+
+	unique, err := im.dropDuplicates(userID, sports)
+	if err != nil {
+		return nil, err
+	}
+	report.Duplicates = len(sports) - len(unique)
+	report.Imported = len(unique)
+	sports = unique
+
+	if dryRun {
+		report.Sports = sports
+		return report, nil
+	}
+	if err := im.Repo.InsertSports(sports); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// returns the name of the sport <name> refers to
+func (im *Importer) resolveSport(name string) (string, bool) {
+	normalized := normalizeName(name)
+	if im.Sports[normalized] {
+		return normalized, true
+	}
+	// e.g. "Leg Raises" -> leg_raises
+	underscored := strings.NewReplacer(" ", "_", "-", "_").Replace(normalized)
+	if im.Sports[underscored] {
+		return underscored, true
+	}
+	sport, ok := im.Aliases[normalized]
+	return sport, ok
+}
+
+// removes sports, which appear twice in <sports> or are already stored for <userID>
+func (im *Importer) dropDuplicates(userID models.Snowflake, sports []models.Sport) ([]models.Sport, error) {
+	if len(sports) == 0 {
+		return sports, nil
+	}
+	from, to := sports[0].Timedate, sports[0].Timedate
+	for _, sport := range sports {
+		if sport.Timedate.Before(from) {
+			from = sport.Timedate
+		}
+		if sport.Timedate.After(to) {
+			to = sport.Timedate
+		}
+	}
+	existing, err := im.Repo.GetSportsBetween(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(existing)+len(sports))
+	for _, sport := range existing {
+		seen[duplicateKey(sport)] = true
+	}
+	unique := make([]models.Sport, 0, len(sports))
+	for _, sport := range sports {
+		key := duplicateKey(sport)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, sport)
+	}
+	return unique, nil
+}
+
+// sports with the same key are exact duplicates
+func duplicateKey(sport models.Sport) string {
+	return fmt.Sprintf("%s|%s|%d|%d", sport.Kind, sport.Game, sport.Amount, sport.Timedate.UTC().UnixNano())
+}
+
+// lower cases <name> and collapses whitespace
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestImporter(t *testing.T) (*Importer, *db.OrmSportRepository) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.Sport{}); err != nil {
+		t.Fatalf("failed to migrate sports table: %v", err)
+	}
+	repo := &db.OrmSportRepository{DB: database, StreakService: db.NewStreakService(time.Now)}
+	importer, err := New(repo)
+	if err != nil {
+		t.Fatalf("failed to create importer: %v", err)
+	}
+	return importer, repo
+}
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name       string
+		format     Format
+		input      string
+		wantRows   int
+		wantErrors []int
+		wantFail   bool
+	}{
+		{
+			"CSV with columns in any order",
+			FormatCSV,
+			"Game,Date,Amount,Sport\nleague,2023-01-31,20,pushup\noverwatch,31.01.2023 18:30,5,plank\n",
+			2, nil, false,
+		},
+		{
+			"CSV with invalid rows",
+			FormatCSV,
+			"date,sport,amount,game\n2023-01-31,pushup,-3,league\nyesterday,pushup,3,league\n\n2023-02-01T10:00:00Z,pushup,3,league\n",
+			1, []int{2, 3}, false,
+		},
+		{"CSV without game column", FormatCSV, "date,sport,amount\n2023-01-31,pushup,20\n", 0, nil, true},
+		{
+			"JSON with string and number amounts",
+			FormatJSON,
+			`[{"date":"2023-01-31","sport":"pushup","amount":20,"game":"league"},{"date":"1675188000","sport":"plank","amount":"30","game":"tft"},{"date":"2023-01-31","sport":"","amount":1,"game":"tft"}]`,
+			2, []int{3}, false,
+		},
+		{"JSON object instead of array", FormatJSON, `{"date":"2023-01-31"}`, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, err := Parse(strings.NewReader(tt.input), tt.format)
+			var fileErr *FileError
+			if tt.wantFail {
+				if !errors.As(err, &fileErr) {
+					t.Fatalf("got error %v, want a FileError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("got %d rows, want %d", len(rows), tt.wantRows)
+			}
+			if len(rowErrors) != len(tt.wantErrors) {
+				t.Fatalf("got errors %v, want errors in lines %v", rowErrors, tt.wantErrors)
+			}
+			for i, line := range tt.wantErrors {
+				if rowErrors[i].Line != line {
+					t.Errorf("got error in line %d, want line %d", rowErrors[i].Line, line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDates(t *testing.T) {
+	rows, _, err := Parse(strings.NewReader(
+		"date,sport,amount,game\n"+
+			"2023-01-31,pushup,1,league\n"+
+			"31.01.2023 18:30,pushup,1,league\n"+
+			"2023-01-31T18:30:00+01:00,pushup,1,league\n",
+	), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []time.Time{
+		time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 31, 18, 30, 0, 0, time.UTC),
+		time.Date(2023, 1, 31, 17, 30, 0, 0, time.UTC),
+	}
+	for i, row := range rows {
+		if !row.Date.Equal(want[i]) {
+			t.Errorf("row %d: got %v, want %v", i, row.Date, want[i])
+		}
+	}
+}
+
+// TestImport verifies alias mapping, the dry run and that exact duplicates are skipped
+func TestImport(t *testing.T) {
+	importer, repo := newTestImporter(t)
+	csvFile := "date,sport,amount,game\n" +
+		"2023-01-31 18:00,Push-Ups,20,League\n" +
+		"2023-01-31 18:00,pushup,20,league\n" + // duplicate of the line before
+		"2023-01-31 19:00,Leg Raises,10,league\n" +
+		"2023-02-01 12:00,burpees,10,league\n" // unknown sport
+
+	report, err := importer.Import(1, strings.NewReader(csvFile), FormatCSV, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if report.Total != 4 || report.Imported != 2 || report.Duplicates != 1 || report.Invalid != 1 {
+		t.Errorf("got report %+v, want 4 total, 2 imported, 1 duplicate, 1 invalid", report)
+	}
+	if report.Aliases["Push-Ups"] != "pushup" || report.Aliases["Leg Raises"] != "leg_raises" {
+		t.Errorf("got aliases %v", report.Aliases)
+	}
+	if len(report.Sports) != 2 {
+		t.Errorf("got %d sports in the dry run report, want 2", len(report.Sports))
+	}
+	stored, _ := repo.GetSportsBetween(1, time.Time{}, time.Now())
+	if len(stored) != 0 {
+		t.Fatalf("dry run stored %d sports", len(stored))
+	}
+
+	report, err = importer.Import(1, strings.NewReader(csvFile), FormatCSV, false)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Imported != 2 || report.Sports != nil {
+		t.Errorf("got report %+v, want 2 imported without sports", report)
+	}
+	stored, _ = repo.GetSportsBetween(1, time.Time{}, time.Now())
+	if len(stored) != 2 {
+		t.Fatalf("got %d stored sports, want 2", len(stored))
+	}
+	// original timestamps are kept
+	if want := time.Date(2023, 1, 31, 18, 0, 0, 0, time.UTC); !stored[0].Timedate.Equal(want) {
+		t.Errorf("got timedate %v, want %v", stored[0].Timedate, want)
+	}
+
+	// importing the same file again only finds duplicates
+	report, err = importer.Import(1, strings.NewReader(csvFile), FormatCSV, false)
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if report.Imported != 0 || report.Duplicates != 3 {
+		t.Errorf("got report %+v, want 0 imported and 3 duplicates", report)
+	}
+
+	// the sports of other users are no duplicates
+	report, err = importer.Import(2, strings.NewReader(csvFile), FormatCSV, false)
+	if err != nil || report.Imported != 2 {
+		t.Errorf("import of other user returned %+v, %v; want 2 imported", report, err)
+	}
+}
+
+func TestAddAliases(t *testing.T) {
+	importer, _ := newTestImporter(t)
+	if err := importer.AddAliases([][]string{{"alias", "sport"}, {"Liegestütz", "pushup"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sport, ok := importer.resolveSport("liegestütz"); !ok || sport != "pushup" {
+		t.Errorf("got %q, %v; want pushup", sport, ok)
+	}
+	if err := importer.AddAliases([][]string{{"alias", "sport"}, {"burpee", "burpees"}}); err == nil {
+		t.Errorf("expected an error for an alias of an unknown sport")
+	}
+}
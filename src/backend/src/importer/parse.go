@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format of an import file
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format of <name>, which is either a format like csv or a filename
+// or content type ending with it
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "csv", strings.HasSuffix(name, ".csv"), strings.HasSuffix(name, "/csv"):
+		return FormatCSV, nil
+	case name == "json", strings.HasSuffix(name, ".json"), strings.HasSuffix(name, "/json"):
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q, expected csv or json", name)
+	}
+}
+
+// Row is one workout of an import file, before the sport was resolved
+type Row struct {
+	// line of the CSV file or index of the JSON array, starting at 1
+	Line   int
+	Date   time.Time
+	Sport  string
+	Amount int
+	Game   string
+}
+
+// RowError describes a row, which can't be imported
+// swagger:model ImportRowError
+type RowError struct {
+	Line    int    `json:"line" example:"12"`
+	Message string `json:"message" example:"unknown sport \"burpees\""`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// FileError is returned, when an import file can't be read at all, e.g. because the header is missing
+type FileError struct {
+	Err error
+}
+
+func (e *FileError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// columns of an import file
+var columns = []string{"date", "sport", "amount", "game"}
+
+// formats of the date column, tried in this order. Dates without a time zone are UTC
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// Parse reads the rows of an import file in <format>. Rows with invalid values are returned
+// as RowError, a FileError is only returned when the file itself can't be read
+func Parse(r io.Reader, format Format) ([]Row, []RowError, error) {
+	var rows []Row
+	var rowErrors []RowError
+	var err error
+	switch format {
+	case FormatCSV:
+		rows, rowErrors, err = parseCSV(r)
+	case FormatJSON:
+		rows, rowErrors, err = parseJSON(r)
+	default:
+		err = fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, nil, &FileError{Err: err}
+	}
+	return rows, rowErrors, nil
+}
+
+// parses a CSV file with a header containing the columns date, sport, amount and game in any order
+func parseCSV(r io.Reader) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return nil, nil, fmt.Errorf("the header needs the columns %s, missing %s", strings.Join(columns, ", "), column)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if isEmpty(record) {
+			continue
+		}
+
+		field := func(column string) string {
+			if i := index[column]; i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		row, err := newRow(line, field("date"), field("sport"), field("amount"), field("game"))
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// jsonRow is a row of a JSON import. The amount can be a number or a string
+type jsonRow struct {
+	Date   string          `json:"date"`
+	Sport  string          `json:"sport"`
+	Amount json.RawMessage `json:"amount"`
+	Game   string          `json:"game"`
+}
+
+// parses a JSON array of objects with the fields date, sport, amount and game
+func parseJSON(r io.Reader) ([]Row, []RowError, error) {
+	var records []jsonRow
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, nil, fmt.Errorf("expected a JSON array of workouts: %w", err)
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for i, record := range records {
+		line := i + 1
+		amount := strings.Trim(string(record.Amount), `"`)
+		row, err := newRow(line, record.Date, record.Sport, amount, record.Game)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// validates and converts the fields of a row
+func newRow(line int, date string, sport string, amount string, game string) (Row, error) {
+	parsedDate, err := parseDate(date)
+	if err != nil {
+		return Row{}, err
+	}
+	sport = strings.TrimSpace(sport)
+	if sport == "" {
+		return Row{}, errors.New("sport is empty")
+	}
+	parsedAmount, err := strconv.Atoi(strings.TrimSpace(amount))
+	if err != nil || parsedAmount <= 0 {
+		return Row{}, fmt.Errorf("amount %q is not a positive number", amount)
+	}
+	game = strings.ToLower(strings.TrimSpace(game))
+	if game == "" {
+		return Row{}, errors.New("game is empty")
+	}
+	return Row{Line: line, Date: parsedDate, Sport: sport, Amount: parsedAmount, Game: game}, nil
+}
+
+// parses <value> with the first matching layout of `dateLayouts` or as unix timestamp
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.UTC(), nil
+		}
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("date %q has an unknown format, expected e.g. 2023-01-31 or 2023-01-31T18:30:00Z", value)
+}
+
+func isEmpty(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
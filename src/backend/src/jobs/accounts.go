@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+)
+
+// AccountPurge deletes the accounts, whose deletion grace period is over
+type AccountPurge struct {
+	Accounts repositories.AccountRepository
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewAccountPurge(accounts repositories.AccountRepository, Now func() time.Time) *AccountPurge {
+	return &AccountPurge{Accounts: accounts, Now: Now}
+}
+
+// Run deletes every due account. A failed deletion doesn't stop the others
+func (p *AccountPurge) Run(ctx context.Context) error {
+	deletions, err := p.Accounts.FetchDueDeletions(p.Now().UTC())
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, deletion := range deletions {
+		if err := p.Accounts.Purge(deletion.UserID); err != nil {
+			log.Printf("Failed to delete account of user %d: %v", deletion.UserID, err)
+			failed++
+			continue
+		}
+		log.Printf("Deleted account of user %d", deletion.UserID)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d accounts", failed, len(deletions))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+)
+
+// SessionCleaner removes login sessions, which expired
+type SessionCleaner interface {
+	DeleteExpired() (int64, error)
+}
+
+// how long read notifications are kept
+const readNotificationRetention = 30 * 24 * time.Hour
+
+// Cleanup removes expired sessions, friend invites, which can't be redeemed anymore, and
+// notifications, which were read a while ago
+type Cleanup struct {
+	Sessions      SessionCleaner
+	Invites       repositories.FriendInviteRepository
+	Notifications repositories.NotificationRepository
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewCleanup(
+	sessions SessionCleaner,
+	invites repositories.FriendInviteRepository,
+	notifications repositories.NotificationRepository,
+	Now func() time.Time,
+) *Cleanup {
+	return &Cleanup{Sessions: sessions, Invites: invites, Notifications: notifications, Now: Now}
+}
+
+func (c *Cleanup) Run(ctx context.Context) error {
+	sessions, err := c.Sessions.DeleteExpired()
+	if err != nil {
+		return err
+	}
+	now := c.Now().UTC()
+	invites, err := c.Invites.DeleteUnusable(now)
+	if err != nil {
+		return err
+	}
+	notifications, err := c.Notifications.DeleteReadBefore(now.Add(-readNotificationRetention))
+	if err != nil {
+		return err
+	}
+	if sessions > 0 || invites > 0 || notifications > 0 {
+		log.Printf("Deleted %d expired sessions, %d friend invites and %d notifications", sessions, invites, notifications)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// GoalProgress is the progress of a goal in the week of a digest
+type GoalProgress struct {
+	Goal models.PersonalGoal
+	// exercises done for weekly and monthly goals, days on which the goal was reached for daily goals
+	Done int
+	// amount of weekly and monthly goals, 7 days for daily goals
+	Target int
+}
+
+func (p GoalProgress) Reached() bool {
+	return p.Done >= p.Target
+}
+
+// Digest is the data of the email of a weekly digest
+type Digest struct {
+	From time.Time
+	// the last day of the week, the digest ends before the following day
+	LastDay  time.Time
+	Progress []GoalProgress
+	// number of reached goals
+	Reached int
+}
+
+// NewDigest returns the digest of the week [<from>, <to>)
+func NewDigest(progress []GoalProgress, from time.Time, to time.Time) Digest {
+	digest := Digest{From: from, LastDay: to.AddDate(0, 0, -1), Progress: progress}
+	for _, entry := range progress {
+		if entry.Reached() {
+			digest.Reached++
+		}
+	}
+	return digest
+}
+
+// WeeklyDigest sends every user with goals the progress of his goals in the past week.
+// Both channels are optional
+type WeeklyDigest struct {
+	Goals  repositories.PersonalGoalsRepository
+	Sports db.SportRepository
+	// users, who turned off the messages of the Discord bot with /reminders, don't get the
+	// digest from <Notifier>
+	Reminders repositories.StreakReminderRepository
+	Notifier  Notifier
+	Mailer    Mailer
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewWeeklyDigest(
+	goals repositories.PersonalGoalsRepository,
+	sports db.SportRepository,
+	reminders repositories.StreakReminderRepository,
+	notifier Notifier,
+	mailer Mailer,
+	Now func() time.Time,
+) *WeeklyDigest {
+	return &WeeklyDigest{Goals: goals, Sports: sports, Reminders: reminders, Notifier: notifier, Mailer: mailer, Now: Now}
+}
+
+// Run sends the digest of the last full week, which ended on the last monday
+func (d *WeeklyDigest) Run(ctx context.Context) error {
+	_, sent, err := d.SendAll(ctx)
+	if sent > 0 {
+		log.Printf("Sent %d weekly digests", sent)
+	}
+	return err
+}
+
+// SendAll sends the digests and returns the number of users with goals and of users, who got
+// the digest on at least one channel
+func (d *WeeklyDigest) SendAll(ctx context.Context) (int, int, error) {
+	goals, err := d.Goals.FetchAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	goalsByUser := map[models.Snowflake][]models.PersonalGoal{}
+	userIDs := []models.Snowflake{}
+	for _, goal := range goals {
+		if _, ok := goalsByUser[goal.UserID]; !ok {
+			userIDs = append(userIDs, goal.UserID)
+		}
+		goalsByUser[goal.UserID] = append(goalsByUser[goal.UserID], goal)
+	}
+
+	weekly := models.PersonalGoal{Frequency: models.Weekly}
+	to, _ := weekly.Window(d.Now())
+	from := to.AddDate(0, 0, -7)
+	sent := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return len(userIDs), sent, err
+		}
+		progress, err := d.Progress(userID, goalsByUser[userID], from, to)
+		if err != nil {
+			return len(userIDs), sent, err
+		}
+		if d.send(ctx, userID, progress, from, to) {
+			sent++
+		}
+	}
+	return len(userIDs), sent, nil
+}
+
+// sends the digest on every channel and returns whether the user got it on any of them
+func (d *WeeklyDigest) send(ctx context.Context, userID models.Snowflake, progress []GoalProgress, from time.Time, to time.Time) bool {
+	received := false
+	if d.Notifier != nil {
+		ok, err := d.notify(ctx, userID, FormatDigest(progress, from, to))
+		if err != nil {
+			log.Printf("Failed to send the weekly digest to user %d: %v", userID, err)
+		}
+		received = received || ok
+	}
+	if d.Mailer != nil {
+		ok, err := d.Mailer.Send(ctx, userID, models.NotificationWeeklyDigest, NewDigest(progress, from, to))
+		if err != nil {
+			log.Printf("Failed to email the weekly digest to user %d: %v", userID, err)
+		}
+		received = received || ok
+	}
+	return received
+}
+
+// sends <message> with the notifier, unless <userID> turned off the messages of the bot
+func (d *WeeklyDigest) notify(ctx context.Context, userID models.Snowflake, message string) (bool, error) {
+	reminder, err := d.Reminders.Fetch(userID)
+	if err != nil {
+		return false, err
+	}
+	if reminder.Disabled {
+		return false, nil
+	}
+	return d.Notifier.Notify(ctx, userID, message)
+}
+
+// Progress returns the progress of <goals> of <userID> in the week [<from>, <to>).
+// Monthly goals count the exercises of their month up to <to>
+func (d *WeeklyDigest) Progress(userID models.Snowflake, goals []models.PersonalGoal, from time.Time, to time.Time) ([]GoalProgress, error) {
+	monthly := models.PersonalGoal{Frequency: models.Monthly}
+	monthStart, _ := monthly.Window(to.Add(-time.Nanosecond))
+	totalsFrom := from
+	if monthStart.Before(totalsFrom) {
+		totalsFrom = monthStart
+	}
+	totals, err := d.Sports.GetDailyTotals(userID, totalsFrom, to)
+	if err != nil {
+		return nil, err
+	}
+	weekStart := from.Format(time.DateOnly)
+	monthStartDate := monthStart.Format(time.DateOnly)
+
+	progress := make([]GoalProgress, 0, len(goals))
+	for _, goal := range goals {
+		// amounts of the sport of the goal by day
+		amounts := map[string]int{}
+		for _, total := range totals {
+			if total.Kind == goal.Sport {
+				amounts[total.Date] += total.Amount
+			}
+		}
+
+		entry := GoalProgress{Goal: goal, Target: goal.Amount}
+		switch goal.Frequency {
+		case models.Daily:
+			entry.Target = 7
+			for day, amount := range amounts {
+				if day >= weekStart && amount >= goal.Amount {
+					entry.Done++
+				}
+			}
+		case models.Monthly:
+			for day, amount := range amounts {
+				if day >= monthStartDate {
+					entry.Done += amount
+				}
+			}
+		default:
+			for day, amount := range amounts {
+				if day >= weekStart {
+					entry.Done += amount
+				}
+			}
+		}
+		progress = append(progress, entry)
+	}
+	return progress, nil
+}
+
+// FormatDigest returns the message of a digest of the week [<from>, <to>)
+func FormatDigest(progress []GoalProgress, from time.Time, to time.Time) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "**Your goals from %s to %s**", from.Format("Jan 2"), to.AddDate(0, 0, -1).Format("Jan 2"))
+	reached := 0
+	for _, entry := range progress {
+		mark := "❌"
+		if entry.Reached() {
+			mark = "✅"
+			reached++
+		}
+		switch entry.Goal.Frequency {
+		case models.Daily:
+			fmt.Fprintf(&message, "\n%s %d %s daily: reached on %d of 7 days", mark, entry.Goal.Amount, entry.Goal.Sport, entry.Done)
+		case models.Monthly:
+			fmt.Fprintf(&message, "\n%s %s monthly: %d of %d this month", mark, entry.Goal.Sport, entry.Done, entry.Target)
+		default:
+			fmt.Fprintf(&message, "\n%s %s weekly: %d of %d", mark, entry.Goal.Sport, entry.Done, entry.Target)
+		}
+	}
+	fmt.Fprintf(&message, "\nYou reached %d of %d goals.", reached, len(progress))
+	message.WriteString("\nStop these messages with /reminders enabled:False.")
+	return message.String()
+}
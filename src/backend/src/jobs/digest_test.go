@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeNotifier records the messages of users, who can be reached
+type fakeNotifier struct {
+	reachable map[models.Snowflake]bool
+	messages  map[models.Snowflake]string
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, userID models.Snowflake, message string) (bool, error) {
+	if !n.reachable[userID] {
+		return false, nil
+	}
+	n.messages[userID] = message
+	return true, nil
+}
+
+func TestWeeklyDigest(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.User{}, &models.Sport{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// monday morning, the digest covers the week from the 24th to the 30th of april
+	now := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	Now := func() time.Time { return now }
+	sports := &db.OrmSportRepository{DB: database, StreakService: db.NewStreakService(Now)}
+	goals := db.NewPersonalGoalsRepository(database)
+
+	for _, goal := range []models.PersonalGoal{
+		{ID: 1, UserID: 1, Amount: 10, Frequency: models.Daily, Sport: "pushup"},
+		{ID: 2, UserID: 1, Amount: 50, Frequency: models.Weekly, Sport: "squat"},
+		{ID: 3, UserID: 1, Amount: 100, Frequency: models.Monthly, Sport: "pushup"},
+		{ID: 4, UserID: 2, Amount: 10, Frequency: models.Weekly, Sport: "pushup"},
+	} {
+		if _, err := goals.Insert(&goal); err != nil {
+			t.Fatalf("failed to insert goal: %v", err)
+		}
+	}
+	for _, sport := range []models.Sport{
+		// counts for the monthly goal, but not for the week
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 40, Timedate: time.Date(2023, 4, 20, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 10, Timedate: time.Date(2023, 4, 24, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "pushup", Game: "league", Amount: 5, Timedate: time.Date(2023, 4, 25, 12, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "pushup", Game: "overwatch", Amount: 5, Timedate: time.Date(2023, 4, 25, 18, 0, 0, 0, time.UTC)},
+		{UserID: 1, Kind: "squat", Game: "league", Amount: 60, Timedate: time.Date(2023, 4, 30, 23, 0, 0, 0, time.UTC)},
+		// already in the next week
+		{UserID: 1, Kind: "squat", Game: "league", Amount: 60, Timedate: time.Date(2023, 5, 1, 7, 0, 0, 0, time.UTC)},
+	} {
+		if err := sports.InsertSport(sport); err != nil {
+			t.Fatalf("failed to insert sport: %v", err)
+		}
+	}
+
+	// user 2 turned off the messages of the bot
+	reminders := db.NewGormStreakReminderRepository(database)
+	if err := reminders.Save(&models.StreakReminder{UserID: 2, Disabled: true}); err != nil {
+		t.Fatalf("failed to save reminder: %v", err)
+	}
+	notifier := &fakeNotifier{reachable: map[models.Snowflake]bool{1: true, 2: true}, messages: map[models.Snowflake]string{}}
+	digest := NewWeeklyDigest(goals, sports, reminders, notifier, nil, Now)
+	users, sent, err := digest.SendAll(context.Background())
+	if err != nil || users != 2 || sent != 1 {
+		t.Fatalf("got %d users and %d sent digests, %v; want 2 and 1", users, sent, err)
+	}
+	if _, ok := notifier.messages[2]; ok {
+		t.Errorf("got a digest for user 2, who turned off the messages of the bot")
+	}
+
+	message := notifier.messages[1]
+	for _, want := range []string{
+		"Apr 24 to Apr 30",
+		"❌ 10 pushup daily: reached on 2 of 7 days",
+		"✅ squat weekly: 60 of 50",
+		"❌ pushup monthly: 60 of 100 this month",
+		"You reached 1 of 3 goals.",
+		"/reminders enabled:False",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("got digest %q, want it to contain %q", message, want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Package jobs contains the background jobs, which are run by the scheduler
+package jobs
+
+import (
+	"context"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Notifier sends a message to a user, e.g. as Discord direct message
+type Notifier interface {
+	// sends <message> to <userID>. Returns false, if the user can't be reached this way
+	Notify(ctx context.Context, userID models.Snowflake, message string) (bool, error)
+}
+
+// Mailer sends templated emails to users
+type Mailer interface {
+	// sends an email of <notificationType> rendered with <data> to <userID>. Returns false,
+	// if the user didn't opt in to this type or has no email address
+	Send(ctx context.Context, userID models.Snowflake, notificationType models.NotificationType, data any) (bool, error)
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// StreakWarningData is the data of the email of a streak warning
+type StreakWarningData struct {
+	Days int
+}
+
+// StreakWarning emails users, whose streak breaks at midnight UTC, since they were active
+// yesterday but not today yet. It should run once per day
+type StreakWarning struct {
+	Sports db.SportRepository
+	Mailer Mailer
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewStreakWarning(sports db.SportRepository, mailer Mailer, Now func() time.Time) *StreakWarning {
+	return &StreakWarning{Sports: sports, Mailer: mailer, Now: Now}
+}
+
+func (w *StreakWarning) Run(ctx context.Context) error {
+	sent, err := w.SendAll(ctx)
+	if sent > 0 {
+		log.Printf("Sent %d streak warning emails", sent)
+	}
+	return err
+}
+
+// SendAll emails every user with a streak at risk and returns the number of sent emails
+func (w *StreakWarning) SendAll(ctx context.Context) (int, error) {
+	userIDs, err := w.Sports.GetUserIDsLastActiveOn(w.Now().UTC().AddDate(0, 0, -1))
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		streak, err := w.Sports.GetCurrentStreak(userID)
+		if err != nil {
+			return sent, err
+		}
+		if streak.Days == 0 {
+			continue
+		}
+		ok, err := w.Mailer.Send(ctx, userID, models.NotificationStreakWarning, StreakWarningData{Days: streak.Days})
+		if err != nil {
+			log.Printf("Failed to email the streak warning to user %d: %v", userID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
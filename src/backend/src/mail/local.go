@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemorySender keeps all messages in memory instead of sending them. Used for tests
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(ctx context.Context, message Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+// Messages returns all messages sent so far
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+// FileSender writes every message as .eml file into <Directory> instead of sending it.
+// Used for development, the files can be opened with any mail client
+type FileSender struct {
+	Directory string
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewFileSender(directory string, Now func() time.Time) (*FileSender, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{Directory: directory, Now: Now}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	now := s.Now()
+	body, err := message.Bytes(now)
+	if err != nil {
+		return err
+	}
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), recipient)
+	return os.WriteFile(filepath.Join(s.Directory, name), body, 0o644)
+}
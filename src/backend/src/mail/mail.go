@@ -0,0 +1,89 @@
+// Package mail sends notifications by email. Emails are rendered from templates and sent
+// with a Sender like an SMTP server
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"time"
+)
+
+// Message is an email with a text and an HTML body
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// additional headers like List-Unsubscribe
+	Headers map[string]string
+}
+
+// Sender delivers messages, e.g. to an SMTP server
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Bytes encodes the message as multipart/alternative MIME message sent at <now>
+func (m Message) Bytes(now time.Time) ([]byte, error) {
+	var buffer bytes.Buffer
+	body := multipart.NewWriter(&buffer)
+
+	headers := map[string]string{
+		"From":         m.From,
+		"To":           m.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         now.Format(time.RFC1123Z),
+		"MIME-Version": "1.0",
+		"Content-Type": fmt.Sprintf("multipart/alternative; boundary=%q", body.Boundary()),
+	}
+	for key, value := range m.Headers {
+		headers[key] = value
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var message bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&message, "%s: %s\r\n", key, headers[key])
+	}
+	message.WriteString("\r\n")
+
+	// the last part is the preferred one
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	for _, part := range parts {
+		writer, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	message.Write(buffer.Bytes())
+	return message.Bytes(), nil
+}
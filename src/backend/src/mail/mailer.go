@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Mailer sends emails of the notification types users opted in to and logs every delivery.
+// It's used as notification channel and by jobs like the weekly digest
+type Mailer struct {
+	Users     db.UserRepository
+	Emails    repositories.EmailRepository
+	Sender    Sender
+	Templates *Templates
+	Tokens    *UnsubscribeTokens
+	// sender address, e.g. GoToHell <noreply@example.com>
+	From string
+	// URL of the backend, which serves the unsubscribe links
+	BackendURL  string
+	FrontendURL string
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewMailer(
+	users db.UserRepository,
+	emails repositories.EmailRepository,
+	sender Sender,
+	templates *Templates,
+	tokens *UnsubscribeTokens,
+	from string,
+	backendURL string,
+	frontendURL string,
+	Now func() time.Time,
+) *Mailer {
+	return &Mailer{
+		Users:       users,
+		Emails:      emails,
+		Sender:      sender,
+		Templates:   templates,
+		Tokens:      tokens,
+		From:        from,
+		BackendURL:  strings.TrimRight(backendURL, "/"),
+		FrontendURL: frontendURL,
+		Now:         Now,
+	}
+}
+
+// UnsubscribeURL returns the link, which unsubscribes <userID> from <notificationType>
+func (m *Mailer) UnsubscribeURL(userID models.Snowflake, notificationType models.NotificationType) string {
+	return fmt.Sprintf("%s/api/email/unsubscribe?token=%s", m.BackendURL, url.QueryEscape(m.Tokens.Sign(userID, notificationType)))
+}
+
+// Send renders the templates of <notificationType> with <data> and sends them to <userID>.
+// Returns false, if the user didn't opt in to this type or has no email address
+func (m *Mailer) Send(ctx context.Context, userID models.Snowflake, notificationType models.NotificationType, data any) (bool, error) {
+	subscribed, err := m.Emails.IsSubscribed(userID, notificationType)
+	if err != nil || !subscribed {
+		return false, err
+	}
+	user, err := m.Users.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	if user.Email == "" {
+		return false, nil
+	}
+
+	unsubscribeURL := m.UnsubscribeURL(userID, notificationType)
+	subject, text, html, err := m.Templates.Render(notificationType, TemplateData{
+		User:           *user,
+		Data:           data,
+		FrontendURL:    m.FrontendURL,
+		UnsubscribeURL: unsubscribeURL,
+	})
+	if err != nil {
+		return false, err
+	}
+	sendErr := m.Sender.Send(ctx, Message{
+		From:    m.From,
+		To:      user.Email,
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+		Headers: map[string]string{
+			// lets mail clients show an unsubscribe button (RFC 8058)
+			"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+
+	delivery := models.EmailDelivery{
+		UserID:  userID,
+		Type:    notificationType,
+		To:      user.Email,
+		Subject: subject,
+		Status:  models.EmailSent,
+		SentAt:  m.Now().UTC(),
+	}
+	if sendErr != nil {
+		delivery.Status = models.EmailFailed
+		delivery.Error = sendErr.Error()
+	}
+	if err := m.Emails.LogDelivery(&delivery); err != nil {
+		return sendErr == nil, err
+	}
+	if sendErr != nil {
+		return false, sendErr
+	}
+	return true, nil
+}
+
+// Deliver sends <notification> by email, if its user opted in to its type
+func (m *Mailer) Deliver(ctx context.Context, notification models.Notification) error {
+	actor, err := m.Users.GetUserByID(notification.ActorID)
+	if err != nil {
+		return err
+	}
+	_, err = m.Send(ctx, notification.UserID, notification.Type, NotificationData{
+		Notification: notification,
+		Actor:        actor.Public(),
+	})
+	return err
+}
@@ -0,0 +1,253 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// failingSender rejects every message like an unreachable mail server
+type failingSender struct{}
+
+func (failingSender) Send(ctx context.Context, message Message) error {
+	return errors.New("connection refused")
+}
+
+// newTestMailer builds a mailer with an in-memory database, in which user 1 has an email
+// address and opted in to weekly digests and user 2 has no email address
+func newTestMailer(t *testing.T, sender Sender) (*Mailer, *db.GormEmailRepository) {
+	t.Helper()
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("failed to migrate users table: %v", err)
+	}
+	emails := &db.GormEmailRepository{DB: database}
+	if err := emails.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate email tables: %v", err)
+	}
+
+	now := time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC)
+	for _, user := range []models.User{
+		{ID: 1, Username: "inu", Email: "inu@example.com"},
+		{ID: 2, Username: "nomail"},
+		{ID: 3, Username: "friend"},
+	} {
+		if err := database.Create(&user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	for _, userID := range []models.Snowflake{1, 2} {
+		types := []models.NotificationType{models.NotificationWeeklyDigest, models.NotificationFriendRequest}
+		if err := emails.SaveSubscriptions(userID, types, now); err != nil {
+			t.Fatalf("failed to save subscriptions: %v", err)
+		}
+	}
+
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("failed to load templates: %v", err)
+	}
+	mailer := NewMailer(
+		&db.GormUserRepository{DB: database},
+		emails,
+		sender,
+		templates,
+		NewUnsubscribeTokens([]byte("secret")),
+		"GoToHell <noreply@example.com>",
+		"https://api.example.com/",
+		"https://example.com",
+		func() time.Time { return now },
+	)
+	return mailer, emails
+}
+
+func testDigest() any {
+	from := time.Date(2023, 4, 24, 0, 0, 0, 0, time.UTC)
+	progress := []struct {
+		Goal    models.PersonalGoal
+		Done    int
+		Target  int
+		Reached bool
+	}{
+		{models.PersonalGoal{Amount: 10, Frequency: models.Daily, Sport: "pushup"}, 2, 7, false},
+		{models.PersonalGoal{Amount: 50, Frequency: models.Weekly, Sport: "squat"}, 60, 50, true},
+	}
+	return map[string]any{"From": from, "LastDay": from.AddDate(0, 0, 6), "Progress": progress, "Reached": 1}
+}
+
+// TestMailerSend verifies that only subscribed users with an address get emails and that every email is logged.
+func TestMailerSend(t *testing.T) {
+	sender := NewMemorySender()
+	mailer, emails := newTestMailer(t, sender)
+	ctx := context.Background()
+
+	var tests = []struct {
+		name             string
+		userID           models.Snowflake
+		notificationType models.NotificationType
+		want             bool
+	}{
+		{"Subscribed user gets the email", 1, models.NotificationWeeklyDigest, true},
+		{"User without subscription gets no email", 1, models.NotificationStreakWarning, false},
+		{"User without address gets no email", 2, models.NotificationWeeklyDigest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent, err := mailer.Send(ctx, tt.userID, tt.notificationType, testDigest())
+			if err != nil || sent != tt.want {
+				t.Errorf("got sent %v (%v), want %v", sent, err, tt.want)
+			}
+		})
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	message := messages[0]
+	if message.To != "inu@example.com" || message.Subject != "Your goals from Apr 24 to Apr 30" {
+		t.Errorf("got message to %q with subject %q", message.To, message.Subject)
+	}
+	for _, want := range []string{"Hi inu", "[ ] 10 pushup daily: reached on 2 of 7 days", "[x] squat weekly: 60 of 50", "You reached 1 of 2 goals."} {
+		if !strings.Contains(message.Text, want) {
+			t.Errorf("got text %q, want it to contain %q", message.Text, want)
+		}
+	}
+	if !strings.Contains(message.HTML, "<strong>1 of 2</strong>") {
+		t.Errorf("got html %q, want it to contain the reached goals", message.HTML)
+	}
+
+	deliveries, err := emails.FetchDeliveries(1, 10)
+	if err != nil || len(deliveries) != 1 || deliveries[0].Status != models.EmailSent {
+		t.Errorf("got deliveries %+v (%v), want one sent email", deliveries, err)
+	}
+}
+
+// TestMailerDeliverEscapesNames verifies that notifications are rendered with the actor and HTML is escaped.
+func TestMailerDeliverEscapesNames(t *testing.T) {
+	sender := NewMemorySender()
+	mailer, _ := newTestMailer(t, sender)
+	if err := mailer.Users.UpdateUser(&models.User{ID: 3, Username: "<b>friend</b>"}); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	notification := models.Notification{ID: 1, UserID: 1, Type: models.NotificationFriendRequest, ActorID: 3}
+	if err := mailer.Deliver(context.Background(), notification); err != nil {
+		t.Fatalf("failed to deliver notification: %v", err)
+	}
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	if messages[0].Subject != "<b>friend</b> sent you a friend request" {
+		t.Errorf("got subject %q", messages[0].Subject)
+	}
+	if strings.Contains(messages[0].HTML, "<b>friend</b>") || !strings.Contains(messages[0].HTML, "&lt;b&gt;friend&lt;/b&gt;") {
+		t.Errorf("got html %q, want the username escaped", messages[0].HTML)
+	}
+}
+
+// TestMailerLogsFailedDeliveries verifies that emails, which the mail server rejected, are logged as failed.
+func TestMailerLogsFailedDeliveries(t *testing.T) {
+	mailer, emails := newTestMailer(t, failingSender{})
+
+	sent, err := mailer.Send(context.Background(), 1, models.NotificationWeeklyDigest, testDigest())
+	if err == nil || sent {
+		t.Fatalf("got sent %v (%v), want an error", sent, err)
+	}
+	deliveries, err := emails.FetchDeliveries(1, 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("got deliveries %+v (%v), want one", deliveries, err)
+	}
+	if deliveries[0].Status != models.EmailFailed || deliveries[0].Error != "connection refused" {
+		t.Errorf("got delivery %+v, want a failed one with the error", deliveries[0])
+	}
+}
+
+// TestUnsubscribeLink verifies that the unsubscribe link of an email unsubscribes only from its type.
+func TestUnsubscribeLink(t *testing.T) {
+	sender := NewMemorySender()
+	mailer, emails := newTestMailer(t, sender)
+
+	if _, err := mailer.Send(context.Background(), 1, models.NotificationWeeklyDigest, testDigest()); err != nil {
+		t.Fatalf("failed to send email: %v", err)
+	}
+	header := sender.Messages()[0].Headers["List-Unsubscribe"]
+	link, err := url.Parse(strings.Trim(header, "<>"))
+	if err != nil || !strings.HasPrefix(header, "<https://api.example.com/api/email/unsubscribe?") {
+		t.Fatalf("got List-Unsubscribe %q (%v)", header, err)
+	}
+
+	token := link.Query().Get("token")
+	userID, notificationType, err := mailer.Tokens.Verify(token)
+	if err != nil || userID != 1 || notificationType != models.NotificationWeeklyDigest {
+		t.Fatalf("got user %d and type %q (%v), want 1 and weekly_digest", userID, notificationType, err)
+	}
+	for _, tampered := range []string{
+		strings.Replace(token, "1.", "2.", 1),
+		strings.Replace(token, "weekly_digest", "friend_request", 1),
+		"1.weekly_digest",
+		"",
+	} {
+		if _, _, err := mailer.Tokens.Verify(tampered); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+			t.Errorf("got %v for token %q, want ErrInvalidUnsubscribeToken", err, tampered)
+		}
+	}
+
+	if err := emails.Unsubscribe(userID, notificationType); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	subscriptions, err := emails.FetchSubscriptions(1)
+	if err != nil || len(subscriptions) != 1 || subscriptions[0] != models.NotificationFriendRequest {
+		t.Errorf("got subscriptions %v (%v), want only friend_request", subscriptions, err)
+	}
+}
+
+// TestMessageBytes verifies that encoded messages can be parsed by mail clients.
+func TestMessageBytes(t *testing.T) {
+	message := Message{
+		From:    "GoToHell <noreply@example.com>",
+		To:      "inu@example.com",
+		Subject: "Deine Ziele für diese Woche",
+		Text:    "text body",
+		HTML:    "<p>html body</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}
+	encoded, err := message.Bytes(time.Date(2023, 5, 1, 8, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("failed to encode message: %v", err)
+	}
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(encoded)))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != message.Subject {
+		t.Errorf("got subject %q (%v), want %q", subject, err, message.Subject)
+	}
+	if got := parsed.Header.Get("List-Unsubscribe"); got != "<https://example.com/unsubscribe>" {
+		t.Errorf("got List-Unsubscribe %q", got)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	for _, want := range []string{"text body", "<p>html body</p>", "Content-Type: text/html; charset=utf-8"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("got body %q, want it to contain %q", body, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender sends messages with an SMTP server. STARTTLS is used, when the server supports it
+type SMTPSender struct {
+	Host string
+	Port int
+	// no authentication is used without a username
+	Username string
+	Password string
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewSMTPSender(host string, port int, username string, password string, Now func() time.Time) *SMTPSender {
+	return &SMTPSender{Host: host, Port: port, Username: username, Password: password, Now: Now}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := message.Bytes(s.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	return smtp.SendMail(addr, auth, message.From, []string{message.To}, body)
+}
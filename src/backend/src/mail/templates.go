@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// TemplateData is passed to the templates of every notification type
+type TemplateData struct {
+	User    models.User
+	Subject string
+	// data of the notification type, e.g. the progress of a weekly digest
+	Data           any
+	FrontendURL    string
+	UnsubscribeURL string
+}
+
+// NotificationData is the data of notification types, which are shown in the inbox as well
+type NotificationData struct {
+	Notification models.Notification
+	Actor        models.PublicUser
+}
+
+// Templates contains a text and an HTML template for every notification type. The text
+// template defines the subject as well
+type Templates struct {
+	text map[models.NotificationType]*texttemplate.Template
+	html map[models.NotificationType]*htmltemplate.Template
+}
+
+// LoadTemplates parses the embedded templates of all notification types
+func LoadTemplates() (*Templates, error) {
+	templates := &Templates{
+		text: map[models.NotificationType]*texttemplate.Template{},
+		html: map[models.NotificationType]*htmltemplate.Template{},
+	}
+	for _, notificationType := range models.NotificationTypes {
+		text, err := texttemplate.ParseFS(templateFiles, "templates/layout.txt.tmpl", fmt.Sprintf("templates/%s.txt.tmpl", notificationType))
+		if err != nil {
+			return nil, err
+		}
+		html, err := htmltemplate.ParseFS(templateFiles, "templates/layout.html.tmpl", fmt.Sprintf("templates/%s.html.tmpl", notificationType))
+		if err != nil {
+			return nil, err
+		}
+		templates.text[notificationType] = text
+		templates.html[notificationType] = html
+	}
+	return templates, nil
+}
+
+// Render returns the subject, the text and the HTML body of an email of <notificationType>
+func (t *Templates) Render(notificationType models.NotificationType, data TemplateData) (string, string, string, error) {
+	text, ok := t.text[notificationType]
+	if !ok {
+		return "", "", "", fmt.Errorf("no email template for %s", notificationType)
+	}
+
+	var subject bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	data.Subject = strings.TrimSpace(subject.String())
+
+	var textBody bytes.Buffer
+	if err := text.ExecuteTemplate(&textBody, "layout.txt.tmpl", data); err != nil {
+		return "", "", "", err
+	}
+	var htmlBody bytes.Buffer
+	if err := t.html[notificationType].ExecuteTemplate(&htmlBody, "layout.html.tmpl", data); err != nil {
+		return "", "", "", err
+	}
+	return data.Subject, textBody.String(), htmlBody.String(), nil
+}
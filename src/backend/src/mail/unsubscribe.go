@@ -0,0 +1,59 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeTokens signs the tokens of unsubscribe links, so that users can unsubscribe from
+// one notification type without logging in, but nobody can unsubscribe other users.
+// Layout: <user ID>.<notification type>.<base64 HMAC-SHA256 of both>
+type UnsubscribeTokens struct {
+	Secret []byte
+}
+
+func NewUnsubscribeTokens(secret []byte) *UnsubscribeTokens {
+	return &UnsubscribeTokens{Secret: secret}
+}
+
+// Sign returns the token, which unsubscribes <userID> from <notificationType>
+func (t *UnsubscribeTokens) Sign(userID models.Snowflake, notificationType models.NotificationType) string {
+	payload := fmt.Sprintf("%d.%s", userID, notificationType)
+	return payload + "." + t.signature(payload)
+}
+
+// Verify returns the user and notification type of <token>
+func (t *UnsubscribeTokens) Verify(token string) (models.Snowflake, models.NotificationType, error) {
+	index := strings.LastIndex(token, ".")
+	if index < 0 {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	payload, signature := token[:index], token[index+1:]
+	if !hmac.Equal([]byte(signature), []byte(t.signature(payload))) {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+
+	userIDStr, notificationType, ok := strings.Cut(payload, ".")
+	if !ok {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	userID, err := models.NewSnowflakeFromString(userIDStr)
+	if err != nil || !models.NotificationType(notificationType).IsValid() {
+		return 0, "", ErrInvalidUnsubscribeToken
+	}
+	return userID, models.NotificationType(notificationType), nil
+}
+
+func (t *UnsubscribeTokens) signature(payload string) string {
+	mac := hmac.New(sha256.New, t.Secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
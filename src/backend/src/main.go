@@ -4,23 +4,40 @@
 // @securityDefinitions.apikey CookieAuth
 // @in cookie
 // @name discord_auth
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 
 package main
 
 import (
+	"context"
 	"encoding/gob"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/auth"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/calendar"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/config"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/connectors"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/controllers"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/discordbot"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/events"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/importer"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/jobs"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/mail"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/KuramaSyu/GoToHell/src/backend/src/routes"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/scheduler"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/webpush"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 )
 
@@ -35,22 +52,36 @@ func main() {
 
 	// Create router
 	r := gin.Default()
+	// the client IP, e.g. of rate limits, is only taken from X-Forwarded-For of trusted proxies
+	if err := r.SetTrustedProxies(appConfig.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Render every error response
+	r.Use(middleware.ErrorHandler())
 
 	// Configure CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{appConfig.FrontendURL},
 		AllowMethods:     []string{"GET", "POST", "DELETE", "PUT", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
 		AllowCredentials: true,
 	}))
 
-	// Setup sessions
-	store := cookie.NewStore([]byte(appConfig.SessionSecret))
-	r.Use(sessions.Sessions("discord_auth", store))
-
 	// Setup dependencies
 	Now := time.Now
 	sportRepository, database := db.InitORMRepository(Now)
+
+	// Setup server-side sessions
+	sessionRepo := db.NewGormSessionRepository(database)
+	store := auth.NewSessionStore(
+		sessionRepo,
+		appConfig.SessionIdleTimeout,
+		appConfig.SessionMaxAge,
+		Now,
+		[]byte(appConfig.SessionSecret),
+	)
+	r.Use(sessions.Sessions("discord_auth", store))
 	streakService := db.NewStreakService(Now)
 	userRepo := db.NewGormUserRepository(database)
 	friendshipRepo := db.NewGormFriendshipRepository(database)
@@ -60,16 +91,166 @@ func main() {
 		StreakService: streakService,
 	}
 	personalGoalRepo := db.NewPersonalGoalsRepository(database)
-	userDetailsFacade := db.NewUserDetailsFacade(&sportRepo, userRepo, personalGoalRepo, friendshipRepo)
+	userSettingsRepo := db.NewGormUserSettingsRepository(database)
+	friendInviteRepo := db.NewGormFriendInviteRepository(database)
+	tokenCipher, err := db.NewTokenCipher(appConfig.TokenEncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to create the token cipher: %v", err)
+	}
+	userIdentityRepo := db.NewGormUserIdentityRepository(database, tokenCipher)
+	apiTokenRepo := db.NewGormAPITokenRepository(database)
+	accountRepo := db.NewGormAccountRepository(database)
+	gameSessionRepo := db.NewGormGameSessionRepository(database)
+	deathCounter, err := db.NewDeathCounter(db.NewGormLiveDeathRepository(database), Now)
+	if err != nil {
+		log.Fatalf("Failed to load the live death counter: %v", err)
+	}
+	gameAccountRepo := db.NewGormGameAccountRepository(database)
+	gameEventRepo := db.NewGormGameEventRepository(database, tokenCipher)
+	gameConnectors := []connectors.Connector{}
+	if appConfig.Riot.APIKey != "" {
+		gameConnectors = append(gameConnectors, connectors.NewRiotConnector(appConfig.Riot.APIURL, appConfig.Riot.APIKey))
+	}
+	matchPoller := connectors.NewPoller(gameAccountRepo, gameConnectors, Now)
+	matchPoller.Interval = appConfig.Riot.PollInterval
+	streakReminderRepo := db.NewGormStreakReminderRepository(database)
+	notificationRepo := db.NewGormNotificationRepository(database)
+	emailRepo := db.NewGormEmailRepository(database)
+	unsubscribeTokens := mail.NewUnsubscribeTokens([]byte(appConfig.Email.UnsubscribeSecret))
+	notificationChannels := []db.NotificationChannel{}
+	var mailer *mail.Mailer
+	if appConfig.Email.Enabled() {
+		mailer, err = newMailer(appConfig, userRepo, emailRepo, unsubscribeTokens, Now)
+		if err != nil {
+			log.Fatalf("Failed to setup emails: %v", err)
+		}
+		notificationChannels = append(notificationChannels, mailer)
+	}
+	pushSubscriptionRepo := db.NewGormPushSubscriptionRepository(database)
+	var vapidKeys *webpush.VAPIDKeys
+	if appConfig.WebPush.Enabled() {
+		vapidKeys, err = webpush.ParseVAPIDKeys(appConfig.WebPush.VAPIDPrivateKey)
+		if err != nil {
+			log.Fatalf("VAPID_PRIVATE_KEY is invalid: %v", err)
+		}
+		pushSender := webpush.NewSender(vapidKeys, appConfig.WebPush.Subject, Now)
+		notificationChannels = append(notificationChannels, webpush.NewNotifier(pushSubscriptionRepo, userRepo, pushSender, appConfig.FrontendURL))
+	}
+	notificationService := db.NewNotificationService(notificationRepo, notificationChannels, Now)
+	visibilityService := db.NewVisibilityService(friendshipRepo, userSettingsRepo)
+	userDetailsFacade := db.NewUserDetailsFacade(&sportRepo, userRepo, personalGoalRepo, visibilityService)
+
+	// Setup login providers
+	discordProvider := auth.NewDiscordProvider(
+		appConfig.Discord.ClientID,
+		appConfig.Discord.ClientSecret,
+		appConfig.Discord.RedirectURL,
+		appConfig.Discord.APIURL,
+	)
+	providers := []auth.Provider{discordProvider}
+	for _, oidc := range appConfig.OIDCProviders {
+		provider, err := auth.NewOIDCProvider(
+			context.Background(),
+			oidc.Name,
+			oidc.IssuerURL,
+			oidc.ClientID,
+			oidc.ClientSecret,
+			oidc.RedirectURL,
+		)
+		if err != nil {
+			log.Fatalf("Failed to setup OIDC provider %s: %v", oidc.Name, err)
+		}
+		providers = append(providers, provider)
+	}
+	authService := auth.NewService(providers, userRepo, userIdentityRepo, Now)
+	profileRefresher := auth.NewProfileRefresher(authService, discordProvider, Now)
 
 	// Initialize controllers
-	sportsController := controllers.NewSportsController(sportRepository, Now)
-	authController := controllers.NewAuthController(appConfig.DiscordOAuthConfig, userRepo)
-	friendsController := controllers.NewFriendsController(userRepo, friendshipRepo)
-	overdueDeathController := controllers.NewOverdueDeathsController(overdueDeathRepo)
-	streakController := controllers.NewStreakController(&sportRepo, Now)
-	personalGoalsController := controllers.NewPersonalGoalsController(personalGoalRepo)
+	sportsController := controllers.NewSportsController(sportRepository, gameSessionRepo, visibilityService, Now)
+	authController := controllers.NewAuthController(authService, userSettingsRepo)
+	friendsController := controllers.NewFriendsController(userRepo, friendshipRepo, &sportRepo, visibilityService, notificationService)
+	overdueDeathController := controllers.NewOverdueDeathsController(overdueDeathRepo, visibilityService)
+	streakController := controllers.NewStreakController(&sportRepo, visibilityService, Now)
+	personalGoalsController := controllers.NewPersonalGoalsController(personalGoalRepo, visibilityService)
 	userDetailsController := controllers.NewPersonalDetailsController(userDetailsFacade)
+	userSettingsController := controllers.NewUserSettingsController(userSettingsRepo)
+	usersController := controllers.NewUsersController(userRepo)
+	friendInvitesController := controllers.NewFriendInvitesController(friendInviteRepo, userRepo, notificationService, Now)
+	apiTokensController := controllers.NewAPITokensController(apiTokenRepo, Now)
+	sessionsController := controllers.NewSessionsController(sessionRepo)
+	accountController := controllers.NewAccountController(accountRepo, appConfig.AccountDeletionGracePeriod, Now)
+	sportImporter, err := importer.New(&sportRepo)
+	if err != nil {
+		log.Fatalf("Failed to create the sport importer: %v", err)
+	}
+	sportImportController := controllers.NewSportImportController(sportImporter)
+	statsService := db.NewStatsService(&sportRepo, visibilityService, config.DefaultSportMultipliers(), config.DefaultGameMultipliers())
+	userStatsController := controllers.NewUserStatsController(statsService, Now)
+	gameSessionsController := controllers.NewGameSessionsController(gameSessionRepo, deathCounter, visibilityService, Now)
+	deathCounterController := controllers.NewDeathCounterController(deathCounter, gameSessionRepo)
+	gameAccountsController := controllers.NewGameAccountsController(gameAccountRepo, gameConnectors, Now)
+	notificationsController := controllers.NewNotificationsController(notificationRepo, Now)
+	emailSettingsController := controllers.NewEmailSettingsController(emailRepo, unsubscribeTokens, appConfig.Email.Enabled(), Now)
+	pushSubscriptionsController := controllers.NewPushSubscriptionsController(pushSubscriptionRepo, vapidKeys, Now)
+	calendarController := controllers.NewCalendarController(
+		db.NewGormCalendarFeedRepository(database),
+		calendar.NewFeed(personalGoalRepo, &sportRepo, appConfig.FrontendURL, Now),
+		appConfig.BackendURL,
+		Now,
+	)
+	gameEventsController := controllers.NewGameEventsController(
+		gameEventRepo,
+		events.NewProcessor(gameEventRepo, gameSessionRepo, deathCounter, Now),
+		Now,
+	)
+
+	// Setup background jobs. The death counter is kept in memory, so every process saves its own
+	jobScheduler := scheduler.New(db.NewGormJobRunRepository(database), Now)
+	jobScheduler.Add("cleanup", scheduler.MustParse("0 3 * * *"), jobs.NewCleanup(store, friendInviteRepo, notificationRepo, Now).Run)
+	jobScheduler.Add("purge-deleted-accounts", scheduler.MustParse("@hourly"), jobs.NewAccountPurge(accountRepo, Now).Run)
+	jobScheduler.Add("refresh-profiles", scheduler.Every(appConfig.Discord.ProfileRefreshInterval), refreshProfiles(profileRefresher))
+	jobScheduler.Add("poll-matches", scheduler.MustParse("@every 1m"), pollMatches(matchPoller))
+	jobScheduler.AddLocal("persist-death-counter", scheduler.Every(appConfig.DeathCounterPersistInterval), persistDeathCounter(deathCounter))
+	jobScheduler.AddLocal("flush-death-counter", scheduler.Every(appConfig.DeathCounterFlushInterval), flushDeathCounter(deathCounter))
+
+	// the weekly digest is sent by Discord and by email, when they are configured
+	var digestNotifier jobs.Notifier
+	var digestMailer jobs.Mailer
+	if mailer != nil {
+		digestMailer = mailer
+		// emailed once per day at the hour of the Discord reminders
+		streakWarning := jobs.NewStreakWarning(&sportRepo, mailer, Now)
+		jobScheduler.Add("streak-warning-email", scheduler.MustParse(fmt.Sprintf("0 %d * * *", appConfig.Discord.ReminderHour)), streakWarning.Run)
+	}
+
+	// Setup the Discord bot, whose commands are received as interactions
+	discordPublicKey, err := hex.DecodeString(appConfig.Discord.PublicKey)
+	if err != nil {
+		log.Fatalf("DISCORD_PUBLIC_KEY is not hex encoded: %v", err)
+	}
+	discordBot := discordbot.NewBot(userIdentityRepo, userRepo, &sportRepo, gameSessionRepo, deathCounter, friendshipRepo, visibilityService, streakReminderRepo, Now)
+	discordInteractionsController := controllers.NewDiscordInteractionsController(discordBot, discordPublicKey)
+	if appConfig.Discord.BotToken != "" {
+		discordClient := discordbot.NewClient(appConfig.Discord.APIURL, appConfig.Discord.ClientID, appConfig.Discord.BotToken, Now)
+		if appConfig.Discord.PublicKey != "" {
+			if err := discordClient.RegisterCommands(context.Background(), discordbot.Commands()); err != nil {
+				log.Printf("Failed to register the Discord commands: %v", err)
+			}
+		}
+		streakReminder := discordbot.NewStreakReminder(&sportRepo, userIdentityRepo, streakReminderRepo, discordClient, Now)
+		streakReminder.Hour = appConfig.Discord.ReminderHour
+		// reminders are sent at most once per day, so later runs only catch up on failed ones
+		jobScheduler.Add("streak-reminder", scheduler.MustParse(fmt.Sprintf("*/15 %d-23 * * *", streakReminder.Hour)), remindStreaks(streakReminder))
+		digestNotifier = discordbot.NewNotifier(userIdentityRepo, discordClient)
+	}
+	if digestNotifier != nil || digestMailer != nil {
+		weeklyDigest := jobs.NewWeeklyDigest(personalGoalRepo, &sportRepo, streakReminderRepo, digestNotifier, digestMailer, Now)
+		jobScheduler.Add("weekly-digest", scheduler.MustParse("0 8 * * 1"), weeklyDigest.Run)
+	}
+	go jobScheduler.Start(context.Background())
+
+	// Authenticate requests with personal API tokens
+	r.Use(middleware.BearerAuth(apiTokenRepo, userRepo, Now))
 
 	// Setup routes
 	routes.SetupRouter(
@@ -81,9 +262,120 @@ func main() {
 		streakController,
 		personalGoalsController,
 		userDetailsController,
+		userSettingsController,
+		usersController,
+		friendInvitesController,
+		apiTokensController,
+		sessionsController,
+		accountController,
+		sportImportController,
+		userStatsController,
+		gameSessionsController,
+		deathCounterController,
+		gameAccountsController,
+		gameEventsController,
+		discordInteractionsController,
+		notificationsController,
+		emailSettingsController,
+		pushSubscriptionsController,
+		calendarController,
+		Now,
 	)
 	// Start the server
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
+
+// returns the mailer, which sends emails with the configured SMTP server or writes them to files
+func newMailer(
+	appConfig *config.Config,
+	users db.UserRepository,
+	emails repositories.EmailRepository,
+	tokens *mail.UnsubscribeTokens,
+	Now func() time.Time,
+) (*mail.Mailer, error) {
+	var sender mail.Sender
+	if appConfig.Email.SMTPHost != "" {
+		sender = mail.NewSMTPSender(
+			appConfig.Email.SMTPHost,
+			appConfig.Email.SMTPPort,
+			appConfig.Email.SMTPUsername,
+			appConfig.Email.SMTPPassword,
+			Now,
+		)
+	} else {
+		fileSender, err := mail.NewFileSender(appConfig.Email.Directory, Now)
+		if err != nil {
+			return nil, err
+		}
+		sender = fileSender
+	}
+	templates, err := mail.LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return mail.NewMailer(
+		users,
+		emails,
+		sender,
+		templates,
+		tokens,
+		appConfig.Email.From,
+		appConfig.BackendURL,
+		appConfig.FrontendURL,
+		Now,
+	), nil
+}
+
+// returns the job, which fetches the Discord profiles of active users
+func refreshProfiles(refresher *auth.ProfileRefresher) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		refreshed, err := refresher.RefreshAll(ctx)
+		if refreshed > 0 {
+			log.Printf("Refreshed %d Discord profiles", refreshed)
+		}
+		return err
+	}
+}
+
+// returns the job, which saves the live death counter
+func persistDeathCounter(counter *db.DeathCounter) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := counter.Persist()
+		return err
+	}
+}
+
+// returns the job, which adds the deaths of the live death counter to the overdue deaths
+func flushDeathCounter(counter *db.DeathCounter) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		flushed, err := counter.Flush()
+		if flushed > 0 {
+			log.Printf("Flushed %d live death counts", flushed)
+		}
+		return err
+	}
+}
+
+// returns the job, which adds the deaths of new matches of linked game accounts to the overdue deaths
+func pollMatches(poller *connectors.Poller) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		processed, err := poller.PollAll(ctx)
+		if processed > 0 {
+			log.Printf("Added the deaths of %d new matches", processed)
+		}
+		return err
+	}
+}
+
+// returns the job, which reminds users of streaks ending at midnight
+func remindStreaks(reminder *discordbot.StreakReminder) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sent, err := reminder.RemindAll(ctx)
+		if sent > 0 {
+			log.Printf("Sent %d streak reminders", sent)
+		}
+		return err
+	}
+}
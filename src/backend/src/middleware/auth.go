@@ -1,23 +1,161 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-// RequireAuth checks if the user is authenticated
+const (
+	// session key of the logged in models.User
+	UserKey = "user"
+	// context key of the *models.APIToken the request was authenticated with
+	TokenKey = "api_token"
+	// context key of the models.User the API token belongs to
+	tokenUserKey = "api_token_user"
+	// context key set by AllowTokens, after the scope of the token was checked
+	scopeCheckedKey = "api_token_scope_checked"
+	// context key of the *models.User set by RequireAuth
+	currentUserKey = "current_user"
+)
+
+// the last usage of an API token is written at most once per interval, to avoid a write on every request
+const tokenTouchInterval = time.Minute
+
+var (
+	// the error of every 401 caused by a missing or invalid session
+	errNotLoggedIn     = apierror.Unauthorized("Not logged in")
+	errInvalidToken    = apierror.Unauthorized("Invalid API token")
+	errTokenNotAllowed = apierror.Forbidden("This endpoint can't be used with an API token")
+)
+
+// HashToken returns the hex encoded SHA-256 hash of an API token, which is stored instead of the token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerAuth authenticates requests with an `Authorization: Bearer <token>` header using a
+// personal API token. Requests without the header are left to the session.
+func BearerAuth(tokens repositories.APITokenRepository, users db.UserRepository, Now func() time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		raw, found := strings.CutPrefix(header, "Bearer ")
+		if !found || !strings.HasPrefix(raw, models.TokenPrefix) {
+			apierror.Abort(c, errInvalidToken)
+			return
+		}
+
+		now := Now().UTC()
+		token, err := tokens.FetchByHash(HashToken(raw))
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		if token == nil || !token.IsUsable(now) {
+			apierror.Abort(c, errInvalidToken)
+			return
+		}
+
+		user, err := users.GetUserByID(token.UserID)
+		if err != nil {
+			apierror.Abort(c, errInvalidToken)
+			return
+		}
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= tokenTouchInterval {
+			if err := tokens.Touch(token.ID, now); err != nil {
+				log.Printf("failed to update last usage of API token %d: %v", token.ID, err)
+			}
+		}
+
+		c.Set(tokenUserKey, *user)
+		c.Set(TokenKey, token)
+		c.Next()
+	}
+}
+
+// TokenFromContext returns the API token the request was authenticated with, or nil for sessions
+func TokenFromContext(c *gin.Context) *models.APIToken {
+	if token, ok := c.Get(TokenKey); ok {
+		return token.(*models.APIToken)
+	}
+	return nil
+}
+
+// AllowTokens lets requests authenticated with an API token pass the following RequireAuth.
+// Reading requests (GET and HEAD) need the <read> scope, all others the <write> scope.
+// An empty scope rejects API tokens for these requests. Session requests are not restricted.
+func AllowTokens(read models.TokenScope, write models.TokenScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := TokenFromContext(c)
+		if token == nil {
+			c.Next()
+			return
+		}
+
+		scope := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = read
+		}
+		if scope == "" {
+			apierror.Abort(c, errTokenNotAllowed)
+			return
+		}
+		if !token.HasScope(scope) {
+			apierror.Abort(c, apierror.Forbidden("API token is missing the %s scope", scope))
+			return
+		}
+		c.Set(scopeCheckedKey, true)
+		c.Next()
+	}
+}
+
+// RequireAuth checks if the user is authenticated by a session or an API token and puts
+// the user into the context, where handlers get it with CurrentUser.
+//
+// API tokens are only accepted, when AllowTokens checked their scope before, so that tokens
+// can't be used for anything they were not explicitly granted.
 func RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		session := sessions.Default(c)
-		user := session.Get("user")
-		if user == nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Not logged in"})
-			c.Abort()
+		if TokenFromContext(c) != nil {
+			if !c.GetBool(scopeCheckedKey) {
+				apierror.Abort(c, errTokenNotAllowed)
+				return
+			}
+			user := c.MustGet(tokenUserKey).(models.User)
+			c.Set(currentUserKey, &user)
+			c.Next()
+			return
+		}
+
+		// a missing session and a session with unexpected content are the same for the client
+		user, ok := sessions.Default(c).Get(UserKey).(models.User)
+		if !ok {
+			apierror.Abort(c, errNotLoggedIn)
 			return
 		}
-		c.Set("user", user)
+		c.Set(currentUserKey, &user)
 		c.Next()
 	}
 }
+
+// CurrentUser returns the user authenticated by RequireAuth.
+// It must only be used in handlers of routes using RequireAuth.
+func CurrentUser(c *gin.Context) *models.User {
+	return c.MustGet(currentUserKey).(*models.User)
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBearerAuthScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	users := db.NewGormUserRepository(database)
+	tokens := db.NewGormAPITokenRepository(database)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := users.CreateUser(&models.User{ID: 1, Username: "kurama"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	expired := now.Add(-time.Hour)
+	for _, token := range []models.APIToken{
+		{UserID: 1, Name: "reader", Hash: HashToken("gth_reader"), Scopes: []models.TokenScope{models.ScopeRead}},
+		{UserID: 1, Name: "logger", Hash: HashToken("gth_logger"), Scopes: []models.TokenScope{models.ScopeSportWrite}},
+		{UserID: 1, Name: "expired", Hash: HashToken("gth_expired"), Scopes: []models.TokenScope{models.ScopeRead}, ExpiresAt: &expired},
+	} {
+		if _, err := tokens.Create(&token); err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+	}
+
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.Use(sessions.Sessions("test", cookie.NewStore([]byte("secret"))))
+	r.Use(BearerAuth(tokens, users, func() time.Time { return now }))
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": CurrentUser(c).ID})
+	}
+	sports := r.Group("/sport", AllowTokens(models.ScopeRead, models.ScopeSportWrite), RequireAuth())
+	sports.GET("", handler)
+	sports.POST("", handler)
+	r.GET("/session-only", RequireAuth(), handler)
+
+	var tests = []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   int
+	}{
+		{"No token and no session", http.MethodGet, "/sport", "", http.StatusUnauthorized},
+		{"Token with read scope", http.MethodGet, "/sport", "Bearer gth_reader", http.StatusOK},
+		{"Token without write scope", http.MethodPost, "/sport", "Bearer gth_reader", http.StatusForbidden},
+		{"Token with write scope", http.MethodPost, "/sport", "Bearer gth_logger", http.StatusOK},
+		{"Token without read scope", http.MethodGet, "/sport", "Bearer gth_logger", http.StatusForbidden},
+		{"Token on route without scopes", http.MethodGet, "/session-only", "Bearer gth_reader", http.StatusForbidden},
+		{"Unknown token", http.MethodGet, "/sport", "Bearer gth_unknown", http.StatusUnauthorized},
+		{"Expired token", http.MethodGet, "/sport", "Bearer gth_expired", http.StatusUnauthorized},
+		{"Malformed header", http.MethodGet, "/sport", "Basic abc", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	// successful requests update the last usage
+	used, err := tokens.FetchByHash(HashToken("gth_reader"))
+	if err != nil || used == nil {
+		t.Fatalf("failed to fetch token: %v", err)
+	}
+	if used.LastUsedAt == nil || !used.LastUsedAt.Equal(now) {
+		t.Errorf("got last usage %v, want %v", used.LastUsedAt, now)
+	}
+
+	// the last usage is written at most once per minute, so only the second request writes it
+	for _, step := range []time.Duration{30 * time.Second, 40 * time.Second} {
+		now = now.Add(step)
+		req := httptest.NewRequest(http.MethodGet, "/sport", nil)
+		req.Header.Set("Authorization", "Bearer gth_reader")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	used, _ = tokens.FetchByHash(HashToken("gth_reader"))
+	if used.LastUsedAt == nil || !used.LastUsedAt.Equal(now) {
+		t.Errorf("got last usage %v, want %v", used.LastUsedAt, now)
+	}
+}
+
+// TestRequireAuthUnauthorizedBody verifies that every missing or invalid session gets the same reply
+func TestRequireAuthUnauthorizedBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.Use(sessions.Sessions("test", cookie.NewStore([]byte("secret"))))
+	r.GET("/invalid", func(c *gin.Context) {
+		// a session with a value of the wrong type
+		sessions.Default(c).Set(UserKey, "not a user")
+		c.Next()
+	}, RequireAuth(), func(c *gin.Context) {})
+	r.GET("/missing", RequireAuth(), func(c *gin.Context) {})
+
+	for _, path := range []string{"/invalid", "/missing"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: got status %d, want 401", path, w.Code)
+		}
+		if body := w.Body.String(); body != `{"error":"Not logged in","code":"unauthorized"}` {
+			t.Errorf("%s: got body %s", path, body)
+		}
+	}
+}
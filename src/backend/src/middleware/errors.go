@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders the last error added with `c.Error` as `apierror.Reply`.
+// Every error response of the API is rendered here.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		apiErr := apierror.From(c.Errors.Last().Err)
+		// clients only get a generic message for server errors, the cause is logged
+		if apiErr.Status >= http.StatusInternalServerError {
+			log.Printf("%s %s failed: %v", c.Request.Method, c.Request.URL.Path, apiErr)
+		}
+		c.JSON(apiErr.Status, apiErr.Reply())
+	}
+}
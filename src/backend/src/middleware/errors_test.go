@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var tests = []struct {
+		name    string
+		handler gin.HandlerFunc
+		status  int
+		code    apierror.Code
+		message string
+	}{
+		{
+			"Repository not found",
+			func(c *gin.Context) { apierror.Abort(c, repositories.NotFound("sport %d not found", 5)) },
+			http.StatusNotFound, apierror.CodeNotFound, "sport 5 not found",
+		},
+		{
+			"Wrapped repository conflict",
+			func(c *gin.Context) {
+				apierror.Abort(c, fmt.Errorf("creating: %w", repositories.Conflict("already friends")))
+			},
+			http.StatusConflict, apierror.CodeConflict, "creating: already friends",
+		},
+		{
+			"Repository forbidden",
+			func(c *gin.Context) { apierror.Abort(c, repositories.Forbidden("blocked")) },
+			http.StatusForbidden, apierror.CodeForbidden, "blocked",
+		},
+		{
+			"Repository gone",
+			func(c *gin.Context) { apierror.Abort(c, repositories.Gone("invite expired")) },
+			http.StatusGone, apierror.CodeGone, "invite expired",
+		},
+		{
+			"Gorm record not found",
+			func(c *gin.Context) { apierror.Abort(c, gorm.ErrRecordNotFound) },
+			http.StatusNotFound, apierror.CodeNotFound, gorm.ErrRecordNotFound.Error(),
+		},
+		{
+			"Unknown error",
+			func(c *gin.Context) { apierror.Abort(c, errors.New("disk full")) },
+			http.StatusInternalServerError, apierror.CodeInternal, apierror.InternalMessage,
+		},
+		{
+			"Explicit server error",
+			func(c *gin.Context) {
+				apierror.Abort(c, apierror.WithStatus(http.StatusServiceUnavailable, errors.New("dial tcp 10.0.0.5:25: refused")))
+			},
+			http.StatusServiceUnavailable, apierror.CodeInternal, apierror.InternalMessage,
+		},
+		{
+			"Explicit status",
+			func(c *gin.Context) {
+				apierror.Abort(c, apierror.WithStatus(http.StatusBadRequest, errors.New("bad id")))
+			},
+			http.StatusBadRequest, apierror.CodeBadRequest, "bad id",
+		},
+		{
+			"Empty body",
+			func(c *gin.Context) {
+				var req struct {
+					Amount int `json:"amount" binding:"required"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					apierror.Abort(c, apierror.InvalidBody(err))
+				}
+			},
+			http.StatusBadRequest, apierror.CodeInvalidBody, "invalid JSON format: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.POST("/", tt.handler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("got status %d, want %d", w.Code, tt.status)
+			}
+			var reply apierror.Reply
+			if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+				t.Fatalf("failed to decode reply %s: %v", w.Body.String(), err)
+			}
+			if reply.Code != tt.code || reply.Error != tt.message {
+				t.Errorf("got %+v, want code %s and message %q", reply, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+// TestErrorHandlerFieldDetails verifies that every field failing validation is listed in the details
+func TestErrorHandlerFieldDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/", func(c *gin.Context) {
+		var req struct {
+			Amount int    `json:"amount" binding:"required,gte=1"`
+			Kind   string `json:"kind" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apierror.Abort(c, apierror.InvalidBody(err))
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount": -1}`)))
+
+	var reply struct {
+		Details []apierror.FieldError `json:"details"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+		t.Fatalf("failed to decode reply %s: %v", w.Body.String(), err)
+	}
+	want := []apierror.FieldError{{Field: "Amount", Rule: "gte"}, {Field: "Kind", Rule: "required"}}
+	if len(reply.Details) != len(want) {
+		t.Fatalf("got details %+v, want %+v", reply.Details, want)
+	}
+	for i := range want {
+		if reply.Details[i] != want[i] {
+			t.Errorf("got detail %+v, want %+v", reply.Details[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/apierror"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter is an in-memory token bucket limiter. Every key (user or IP) has its own bucket
+// with <Burst> tokens, which refills with one token every <Interval>.
+type RateLimiter struct {
+	Burst    int
+	Interval time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(burst int, interval time.Duration, Now func() time.Time) *RateLimiter {
+	return &RateLimiter{
+		Burst:    burst,
+		Interval: interval,
+		Now:      Now,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of <key>. If the bucket is empty, false and the
+// duration until the next token is available are returned.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		l.prune(now)
+		b = &bucket{tokens: float64(l.Burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	// refill tokens for the time since the last request
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(float64(l.Burst), b.tokens+float64(elapsed)/float64(l.Interval))
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(l.Interval))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// removes buckets, which are completely refilled, since they behave like new ones
+func (l *RateLimiter) prune(now time.Time) {
+	full := time.Duration(l.Burst) * l.Interval
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// UserOrIPKey uses the ID of the user authenticated by RequireAuth as rate limit key and falls back to the client IP
+func UserOrIPKey(c *gin.Context) string {
+	if user, ok := c.Get(currentUserKey); ok {
+		return "user:" + strconv.FormatUint(uint64(user.(*models.User).ID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimit rejects requests with 429, when the bucket of the key returned by <keyFunc> is empty
+func RateLimit(limiter *RateLimiter, keyFunc func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.Allow(keyFunc(c))
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			apierror.Abort(c, apierror.New(
+				http.StatusTooManyRequests,
+				apierror.CodeTooManyRequests,
+				fmt.Sprintf("Too many requests, retry in %d seconds", seconds),
+			))
+			return
+		}
+		c.Next()
+	}
+}
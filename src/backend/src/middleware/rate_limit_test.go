@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2023, 1, 6, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, time.Second, func() time.Time { return now })
+
+	// the burst can be used right away
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("a"); !allowed {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+
+	allowed, retryAfter := limiter.Allow("a")
+	if allowed {
+		t.Fatalf("third request should be limited")
+	}
+	if retryAfter != time.Second {
+		t.Errorf("got retry after %v, want %v", retryAfter, time.Second)
+	}
+
+	// other keys have their own bucket
+	if allowed, _ := limiter.Allow("b"); !allowed {
+		t.Errorf("other key should be allowed")
+	}
+
+	// one token is refilled after one interval
+	now = now.Add(time.Second)
+	if allowed, _ := limiter.Allow("a"); !allowed {
+		t.Errorf("request after refill should be allowed")
+	}
+	if allowed, _ := limiter.Allow("a"); allowed {
+		t.Errorf("only one token should have been refilled")
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table containing the requested deletion of the account of <UserID>. The account and all
+// of its data are deleted after <DeleteAfter>, unless the user cancels the deletion before.
+// swagger:model AccountDeletion
+type AccountDeletion struct {
+	UserID      Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	RequestedAt time.Time `gorm:"not null" json:"requested_at"`
+	DeleteAfter time.Time `gorm:"not null;index" json:"delete_after"`
+}
+
+// AccountExport contains everything stored about a user
+type AccountExport struct {
+	User         User           `json:"user"`
+	Settings     UserSettings   `json:"settings"`
+	Identities   []UserIdentity `json:"identities"`
+	Sessions     []Session      `json:"sessions"`
+	APITokens    []APIToken     `json:"api_tokens"`
+	Sports       []Sport        `json:"sports"`
+	GameSessions []GameSession  `json:"game_sessions"`
+	GameAccounts []GameAccount  `json:"game_accounts"`
+	// matches of linked game accounts, whose deaths were added to the overdue deaths
+	ProcessedMatches []ProcessedMatch `json:"processed_matches"`
+	// events received from games and tools like OBS plugins
+	GameEvents    []GameEvent     `json:"game_events"`
+	OverdueDeaths []OverdueDeaths `json:"overdue_deaths"`
+	PersonalGoals []PersonalGoal  `json:"personal_goals"`
+	// settings of the streak reminders of the Discord bot
+	StreakReminders []StreakReminder `json:"streak_reminders"`
+	Friendships     []Friendships    `json:"friendships"`
+	FriendInvites   []FriendInvite   `json:"friend_invites"`
+	Notifications   []Notification   `json:"notifications"`
+	// notification types the user receives by email and the log of sent emails
+	EmailSubscriptions []EmailSubscription `json:"email_subscriptions"`
+	EmailDeliveries    []EmailDelivery     `json:"email_deliveries"`
+	// browsers, in which the user receives push notifications
+	PushSubscriptions []PushSubscription `json:"push_subscriptions"`
+	// the private calendar feed, without its token
+	CalendarFeeds []CalendarFeed `json:"calendar_feeds"`
+	// set, when the deletion of the account was requested
+	Deletion *AccountDeletion `json:"deletion,omitempty"`
+}
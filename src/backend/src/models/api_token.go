@@ -0,0 +1,59 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// TokenScope limits what an API token can be used for
+type TokenScope string
+
+const (
+	// read all data the user can see
+	ScopeRead TokenScope = "read"
+	// log, edit and delete sports and deaths
+	ScopeSportWrite TokenScope = "sport:write"
+	// create, edit and delete personal goals
+	ScopeGoalsWrite TokenScope = "goals:write"
+)
+
+// TokenPrefix is prepended to every API token, so that leaked tokens are easy to recognize
+const TokenPrefix = "gth_"
+
+func (s TokenScope) IsValid() bool {
+	switch s {
+	case ScopeRead, ScopeSportWrite, ScopeGoalsWrite:
+		return true
+	}
+	return false
+}
+
+// SQL Table representing a personal access token of the user <UserID>, which is sent as
+// `Authorization: Bearer <token>`. Only the SHA-256 hash of the token is stored.
+// swagger:model APIToken
+type APIToken struct {
+	ID     Snowflake `gorm:"primaryKey" json:"id"`
+	UserID Snowflake `gorm:"not null;index" json:"user_id" example:"348922315062044675"`
+	Name   string    `gorm:"not null" json:"name" example:"Stream Deck"`
+	// the first characters of the token, to tell tokens apart
+	Prefix     string       `gorm:"not null" json:"prefix" example:"gth_3Kd9"`
+	Hash       string       `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes     []TokenScope `gorm:"serializer:json;not null" json:"scopes" example:"read,sport:write"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// IsUsable returns whether or not the token can be used at <now>
+func (t *APIToken) IsUsable(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// HasScope returns whether or not the token was granted <scope>
+func (t *APIToken) HasScope(scope TokenScope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarTokenPrefix is prepended to every calendar feed token, so that leaked tokens are easy to recognize
+const CalendarTokenPrefix = "gthc_"
+
+// SQL Table containing the token of the private iCalendar feed of <UserID>. Calendar apps can't
+// send headers, so the token is part of the feed URL. Only the SHA-256 hash of the token is stored
+// swagger:model CalendarFeed
+type CalendarFeed struct {
+	UserID Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Hash   string    `gorm:"not null;uniqueIndex" json:"-"`
+	// the first characters of the token, to tell feed URLs apart
+	Prefix string `gorm:"not null" json:"prefix" example:"gthc_3Kd9"`
+	// when a calendar app fetched the feed the last time
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
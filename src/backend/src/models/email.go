@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table containing that <UserID> opted in to receive notifications of <Type> by email.
+// Users without a record don't get emails of this type
+// swagger:model EmailSubscription
+type EmailSubscription struct {
+	UserID    Snowflake        `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Type      NotificationType `gorm:"primaryKey" json:"type" example:"weekly_digest"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// EmailDeliveryStatus tells whether an email was accepted by the mail server
+type EmailDeliveryStatus string
+
+const (
+	EmailSent   EmailDeliveryStatus = "sent"
+	EmailFailed EmailDeliveryStatus = "failed"
+)
+
+// SQL Table logging every email sent to <UserID>
+// swagger:model EmailDelivery
+type EmailDelivery struct {
+	ID      Snowflake           `gorm:"primaryKey" json:"id"`
+	UserID  Snowflake           `gorm:"not null;index:idx_email_deliveries_user_sent,priority:1" json:"user_id" example:"348922315062044675"`
+	Type    NotificationType    `gorm:"not null" json:"type" example:"weekly_digest"`
+	To      string              `gorm:"not null" json:"to" example:"inu@example.com"`
+	Subject string              `gorm:"not null" json:"subject" example:"Your goals from Apr 24 to Apr 30"`
+	Status  EmailDeliveryStatus `gorm:"not null" json:"status" example:"sent"`
+	Error   string              `json:"error,omitempty"`
+	SentAt  time.Time           `gorm:"index:idx_email_deliveries_user_sent,priority:2" json:"sent_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table representing an invite code <Code> created by the user <CreatorID>.
+// Whoever redeems the code becomes an accepted friend of the creator. The code can be used
+// <MaxUses> times (0 means unlimited) until <ExpiresAt> or until it's revoked.
+// swagger:model FriendInvite
+type FriendInvite struct {
+	ID        Snowflake  `gorm:"primaryKey" json:"id"`
+	Code      string     `gorm:"uniqueIndex;not null" json:"code" example:"K7QX2MPA"`
+	CreatorID Snowflake  `gorm:"not null;index" json:"creator_id" example:"348922315062044675"`
+	MaxUses   int        `gorm:"not null" json:"max_uses" example:"5"`
+	Uses      int        `gorm:"not null" json:"uses" example:"2"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable returns whether or not the invite can still be redeemed at <now>
+func (i *FriendInvite) IsUsable(now time.Time) bool {
+	if i.RevokedAt != nil || !now.Before(i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
@@ -12,6 +12,16 @@ const (
 	Blocked  FriendshipStatus = "blocked"
 )
 
+// Direction of a friendship seen from one of the two users
+type FriendshipDirection string
+
+const (
+	// the other user sent the request
+	Incoming FriendshipDirection = "incoming"
+	// the user himself sent the request
+	Outgoing FriendshipDirection = "outgoing"
+)
+
 // SQL Table representing a friendship between person A <RequesterID> and person B <RecipientID>.
 // The status <Status> of the friendship is either pending, accepted or blocked
 type Friendships struct {
@@ -21,3 +31,34 @@ type Friendships struct {
 	Status      FriendshipStatus `json:"status"`
 	CreatedAt   time.Time        `json:"created_at"`
 }
+
+// A user, who is not related to the requesting user yet, ranked by the number of
+// accepted friends they have in common and the games both of them have played
+// swagger:model FriendSuggestion
+type FriendSuggestion struct {
+	User          PublicUser `json:"user"`
+	MutualFriends int        `json:"mutual_friends" example:"3"`
+	SharedGames   []string   `json:"shared_games" example:"overwatch,league"`
+}
+
+// FriendshipFilter restricts the friendships returned for a user. Empty fields match everything
+type FriendshipFilter struct {
+	Status    FriendshipStatus
+	Direction FriendshipDirection
+}
+
+// A friendship together with the other user of it and his current activity
+// swagger:model FriendEntry
+type FriendEntry struct {
+	Friendship    Friendships         `json:"friendship"`
+	Direction     FriendshipDirection `json:"direction" example:"incoming"`
+	Friend        PublicUser          `json:"friend"`
+	Discriminator string              `json:"discriminator" example:"0"`
+	// only set, if the friend allows the user to see his streak
+	CurrentStreak *DayStreak `json:"current_streak"`
+	// only set, if the friend allows the user to see his activities
+	LastActivity *time.Time `json:"last_activity"`
+
+	// whether or not the friend allows the user to see his streak
+	StreakVisible bool `json:"-"`
+}
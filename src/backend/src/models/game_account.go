@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table representing the account <ExternalID> of <UserID> at the game-stats connector
+// <Connector>, e.g. the Riot PUUID. The recent matches of linked accounts are polled and
+// their deaths are added to the overdue deaths
+// swagger:model GameAccount
+type GameAccount struct {
+	ID        Snowflake `gorm:"primaryKey" json:"id"`
+	UserID    Snowflake `gorm:"not null;uniqueIndex:idx_game_accounts_user_connector,priority:1" json:"user_id" example:"348922315062044675"`
+	Connector string    `gorm:"not null;uniqueIndex:idx_game_accounts_user_connector,priority:2;index:idx_game_accounts_connector_polled,priority:1" json:"connector" example:"riot"`
+	// ID of the account at the connector
+	ExternalID string `gorm:"not null" json:"-"`
+	// name of the account at the connector, e.g. the Riot ID
+	DisplayName  string     `json:"display_name" example:"Faker#KR1"`
+	LastPolledAt *time.Time `gorm:"index:idx_game_accounts_connector_polled,priority:2" json:"last_polled_at,omitempty"`
+	// matches ended before the account was linked are ignored, so that linking doesn't import the history
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SQL Table containing the matches, whose deaths were already added to the overdue deaths of
+// <UserID>. Matches are deduplicated by <Connector> and <MatchID>
+// swagger:model ProcessedMatch
+type ProcessedMatch struct {
+	UserID      Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Connector   string    `gorm:"primaryKey" json:"connector" example:"riot"`
+	MatchID     string    `gorm:"primaryKey" json:"match_id" example:"EUW1_6823451234"`
+	Game        string    `gorm:"not null" json:"game" example:"league"`
+	Deaths      int64     `gorm:"not null" json:"deaths" example:"7"`
+	EndedAt     time.Time `json:"ended_at"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// GameEventType is the kind of an event sent by a game or tool like an OBS plugin
+type GameEventType string
+
+const (
+	// the user died <Count> times
+	EventDeath GameEventType = "death"
+	// starts a live game session
+	EventMatchStart GameEventType = "match_start"
+	// closes the live game session
+	EventMatchEnd GameEventType = "match_end"
+)
+
+func (t GameEventType) IsValid() bool {
+	switch t {
+	case EventDeath, EventMatchStart, EventMatchEnd:
+		return true
+	}
+	return false
+}
+
+// GameEventStatus tells whether an event was applied
+type GameEventStatus string
+
+const (
+	EventProcessed GameEventStatus = "processed"
+	EventRejected  GameEventStatus = "rejected"
+)
+
+// EventSignatureScheme is prepended to the hex encoded HMAC in the signature header
+const EventSignatureScheme = "sha256="
+
+// EventSecretPrefix is prepended to every signing secret, so that leaked secrets are easy to recognize
+const EventSecretPrefix = "gths_"
+
+// SQL Table containing the secret of <UserID>, which signs the game events sent for him.
+// The secret is stored encrypted instead of hashed, since it's needed in plain text to verify the HMAC
+// swagger:model EventSigningSecret
+type EventSigningSecret struct {
+	UserID Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Secret string    `gorm:"not null" json:"-"`
+	// the first characters of the secret, to tell secrets apart
+	Prefix    string    `gorm:"not null" json:"prefix" example:"gths_3Kd9"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SQL Table containing every authenticated game event of <UserID> as sent, for auditing.
+// Events are deduplicated by <EventID>
+// swagger:model GameEvent
+type GameEvent struct {
+	UserID  Snowflake     `gorm:"primaryKey;autoIncrement:false;index:idx_game_events_user_received,priority:1" json:"user_id" example:"348922315062044675"`
+	EventID string        `gorm:"primaryKey" json:"event_id" example:"9b2f0c6e-4a51-4a0e-8d0b-5c1f0f2d7e11"`
+	Type    GameEventType `json:"type" example:"death"`
+	Game    string        `json:"game" example:"overwatch"`
+	// the request body as received
+	Payload    string          `gorm:"not null" json:"payload"`
+	Status     GameEventStatus `gorm:"not null" json:"status" example:"processed"`
+	Error      string          `json:"error,omitempty"`
+	ReceivedAt time.Time       `gorm:"index:idx_game_events_user_received,priority:2" json:"received_at"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+)
+
+// MatchOutcome is the optional result of the match played in a game session
+type MatchOutcome string
+
+const (
+	OutcomeWin  MatchOutcome = "win"
+	OutcomeLoss MatchOutcome = "loss"
+	OutcomeDraw MatchOutcome = "draw"
+)
+
+// IsValid returns whether or not the outcome is one of the known values or empty
+func (o MatchOutcome) IsValid() bool {
+	switch o {
+	case "", OutcomeWin, OutcomeLoss, OutcomeDraw:
+		return true
+	}
+	return false
+}
+
+// SQL Table representing one session of <UserID> playing <Game> from <StartedAt> to <EndedAt>.
+// A session without <EndedAt> is live. Sports can be linked to a session with `Sport.SessionID`
+// swagger:model GameSession
+type GameSession struct {
+	ID        Snowflake  `gorm:"primaryKey" json:"id"`
+	UserID    Snowflake  `gorm:"not null;index:idx_game_sessions_user_started,priority:1" json:"user_id" example:"348922315062044675"`
+	Game      string     `gorm:"not null" json:"game" example:"overwatch"`
+	StartedAt time.Time  `gorm:"not null;index:idx_game_sessions_user_started,priority:2" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Deaths    int64      `gorm:"not null;default:0" json:"deaths" example:"37"`
+	// deaths of this session, which were added to the overdue deaths of the game by the live
+	// death counter or when closing the session
+	OverdueDeaths int64        `gorm:"not null;default:0" json:"overdue_deaths" example:"5"`
+	Outcome       MatchOutcome `json:"outcome,omitempty" example:"win"`
+}
+
+// IsLive returns whether or not the session was not closed yet
+func (s *GameSession) IsLive() bool {
+	return s.EndedAt == nil
+}
+
+// Duration returns the time played in the session. Live sessions last until <now>
+func (s *GameSession) Duration(now time.Time) time.Duration {
+	end := now
+	if s.EndedAt != nil {
+		end = *s.EndedAt
+	}
+	if end.Before(s.StartedAt) {
+		return 0
+	}
+	return end.Sub(s.StartedAt)
+}
+
+// GameSessionStats are the statistics of one game session
+// swagger:model GameSessionStats
+type GameSessionStats struct {
+	DurationSeconds int64   `json:"duration_seconds" example:"7200"`
+	Deaths          int64   `json:"deaths" example:"37"`
+	DeathsPerHour   float64 `json:"deaths_per_hour" example:"18.5"`
+	// sum of the amounts of all linked sports
+	Exercises         int     `json:"exercises" example:"92"`
+	ExercisesPerDeath float64 `json:"exercises_per_death" example:"2.49"`
+	// amounts of the linked sports per kind
+	Sports []SportAmount `json:"sports"`
+}
+
+// NewGameSessionStats calculates the statistics of <session> with its linked <sports> at <now>
+func NewGameSessionStats(session *GameSession, sports []Sport, now time.Time) GameSessionStats {
+	stats := GameSessionStats{Deaths: session.Deaths, Sports: []SportAmount{}}
+	duration := session.Duration(now)
+	stats.DurationSeconds = int64(duration / time.Second)
+	if duration > 0 {
+		stats.DeathsPerHour = float64(session.Deaths) / duration.Hours()
+	}
+
+	kinds := map[string]int{}
+	for _, sport := range sports {
+		stats.Exercises += sport.Amount
+		if _, ok := kinds[sport.Kind]; !ok {
+			kinds[sport.Kind] = len(stats.Sports)
+			stats.Sports = append(stats.Sports, SportAmount{Kind: sport.Kind})
+		}
+		stats.Sports[kinds[sport.Kind]].Amount += sport.Amount
+	}
+	if session.Deaths > 0 {
+		stats.ExercisesPerDeath = float64(stats.Exercises) / float64(session.Deaths)
+	}
+	return stats
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table containing the last run of the background job <Name> and the lock, which ensures that
+// only one process runs the job at a time. The lock expires at <LockedUntil>, so that the job runs
+// again, when the process holding the lock died
+// swagger:model JobRun
+type JobRun struct {
+	Name      string     `gorm:"primaryKey" json:"name" example:"cleanup"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	// duration of the last run in milliseconds
+	LastDurationMs int64 `gorm:"not null;default:0" json:"last_duration_ms" example:"120"`
+	// error of the last run, empty if it succeeded
+	LastError   string     `json:"last_error,omitempty"`
+	LockedBy    string     `json:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// NormalizeGame returns <game> trimmed and in lower case, so that deaths counted by the API and
+// the bot end up in the same game
+func NormalizeGame(game string) string {
+	return strings.ToLower(strings.TrimSpace(game))
+}
+
+// SQL Table containing the deaths of <UserID> in <Game> counted by the live death counter, which
+// were not flushed to `OverdueDeaths` yet. It's a snapshot of the in-memory counter, which is
+// loaded again after a restart
+// swagger:model LiveDeathCount
+type LiveDeathCount struct {
+	UserID    Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Game      string    `gorm:"primaryKey;autoIncrement:false" json:"game" example:"overwatch"`
+	Count     int64     `gorm:"not null" json:"count" example:"3"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationType is the kind of event a notification informs about
+type NotificationType string
+
+const (
+	// <ActorID> sent the user a friend request
+	NotificationFriendRequest NotificationType = "friend_request"
+	// <ActorID> accepted the friend request of the user
+	NotificationFriendAccepted NotificationType = "friend_accepted"
+	// <ActorID> became a friend of the user by redeeming one of his invites
+	NotificationInviteRedeemed NotificationType = "invite_redeemed"
+	// the progress of the goals of the user in the past week. Only sent by email
+	NotificationWeeklyDigest NotificationType = "weekly_digest"
+	// the streak of the user ends at midnight. Only sent by email
+	NotificationStreakWarning NotificationType = "streak_warning"
+)
+
+// NotificationTypes contains every notification type
+var NotificationTypes = []NotificationType{
+	NotificationFriendRequest,
+	NotificationFriendAccepted,
+	NotificationInviteRedeemed,
+	NotificationWeeklyDigest,
+	NotificationStreakWarning,
+}
+
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case NotificationFriendRequest, NotificationFriendAccepted, NotificationInviteRedeemed,
+		NotificationWeeklyDigest, NotificationStreakWarning:
+		return true
+	}
+	return false
+}
+
+// SQL Table containing a notification of the user <UserID> about something <ActorID> did.
+// <SubjectID> is the ID of the changed record, e.g. the friendship. IDs are increasing,
+// so that they are used as cursor
+// swagger:model Notification
+type Notification struct {
+	ID        Snowflake        `gorm:"primaryKey;index:idx_notifications_user_id,priority:2" json:"id"`
+	UserID    Snowflake        `gorm:"not null;index:idx_notifications_user_id,priority:1" json:"user_id" example:"348922315062044675"`
+	Type      NotificationType `gorm:"not null" json:"type" example:"friend_request"`
+	ActorID   Snowflake        `gorm:"index" json:"actor_id,omitempty" example:"318922315062044675"`
+	SubjectID Snowflake        `json:"subject_id,omitempty" example:"12"`
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// NotificationFilter restricts the notifications returned for a user
+type NotificationFilter struct {
+	// only notifications, which were not read yet
+	UnreadOnly bool
+	// only notifications with an ID lower than <Before>. 0 means no restriction
+	Before Snowflake
+	Limit  int
+}
@@ -1,5 +1,9 @@
 package models
 
+import (
+	"time"
+)
+
 type TimeFrequency string
 
 const (
@@ -20,3 +24,20 @@ type PersonalGoal struct {
 	// just a constraint to use UserID as foreign key
 	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 }
+
+// Window returns the UTC period of the goal, which contains <t>. Weeks start on monday
+func (g *PersonalGoal) Window(t time.Time) (start time.Time, end time.Time) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch g.Frequency {
+	case Weekly:
+		// days since monday, sunday is the last day of the week
+		offset := (int(day.Weekday()) + 6) % 7
+		start = day.AddDate(0, 0, -offset)
+		return start, start.AddDate(0, 0, 7)
+	case Monthly:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
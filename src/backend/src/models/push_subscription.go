@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table containing a Push API subscription of a browser of the user <UserID>. Notifications
+// are encrypted with the keys <P256dh> and <Auth> of the browser and sent to <Endpoint>, which
+// belongs to the push service of the browser
+// swagger:model PushSubscription
+type PushSubscription struct {
+	ID       Snowflake `gorm:"primaryKey" json:"id"`
+	UserID   Snowflake `gorm:"not null;index" json:"user_id" example:"348922315062044675"`
+	Endpoint string    `gorm:"not null;uniqueIndex" json:"endpoint" example:"https://fcm.googleapis.com/fcm/send/dXp..."`
+	// base64url encoded public key of the browser
+	P256dh string `gorm:"not null" json:"-"`
+	// base64url encoded authentication secret of the browser
+	Auth string `gorm:"not null" json:"-"`
+	// name of the device shown in the settings, e.g. Firefox on Android
+	Device    string    `json:"device,omitempty" example:"Firefox on Android"`
+	CreatedAt time.Time `json:"created_at"`
+}
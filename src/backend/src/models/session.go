@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table representing a server-side login session. The cookie of the client only contains
+// the signed <ID>, the session values are stored in <Data>.
+// A session expires at <ExpiresAt> or when it was not used for the idle timeout of the store.
+// swagger:model Session
+type Session struct {
+	// secret ID of the session, which is only sent inside the signed cookie
+	ID string `gorm:"primaryKey" json:"-"`
+	// ID used to list and revoke the session
+	PublicID string `gorm:"not null;uniqueIndex" json:"id" example:"3f9c0a1b2d4e"`
+	// 0 until the user logged in
+	UserID     Snowflake `gorm:"not null;index" json:"-"`
+	Data       []byte    `json:"-"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; Win64; x64) ..."`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `gorm:"not null;index" json:"last_seen_at"`
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// IsActive returns whether or not the session can still be used at <now>
+func (s *Session) IsActive(now time.Time, idleTimeout time.Duration) bool {
+	if !now.Before(s.ExpiresAt) {
+		return false
+	}
+	return idleTimeout <= 0 || now.Sub(s.LastSeenAt) < idleTimeout
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Snowflake uint64
@@ -66,3 +68,45 @@ func (a *SnowflakeArray) UnmarshalText(text []byte) error {
 	a.IDs = snowflakes
 	return nil
 }
+
+// the epoch of generated snowflakes, 2025-01-01 UTC in milliseconds
+const snowflakeEpoch int64 = 1735689600000
+
+// SnowflakeGenerator creates unique, time ordered snowflakes for records, which don't
+// get their ID from Discord or the database, like users registered with other providers.
+// Layout: 42 bits milliseconds since `snowflakeEpoch`, 10 bits worker, 12 bits sequence
+type SnowflakeGenerator struct {
+	Worker uint64
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+
+	mu       sync.Mutex
+	lastMs   int64
+	sequence uint64
+}
+
+func NewSnowflakeGenerator(worker uint64, Now func() time.Time) *SnowflakeGenerator {
+	return &SnowflakeGenerator{Worker: worker & 0x3FF, Now: Now}
+}
+
+// Next returns a new snowflake. If more than 4096 snowflakes are requested within one
+// millisecond, the next millisecond is used
+func (g *SnowflakeGenerator) Next() Snowflake {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.Now().UnixMilli() - snowflakeEpoch
+	if ms <= g.lastMs {
+		// same millisecond or clock moved backwards
+		ms = g.lastMs
+		g.sequence = (g.sequence + 1) & 0xFFF
+		if g.sequence == 0 {
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = ms
+	return Snowflake(uint64(ms)<<22 | g.Worker<<12 | g.sequence)
+}
@@ -10,9 +10,11 @@ type Sport struct {
 	ID       Snowflake `gorm:"primaryKey" json:"id"`
 	Kind     string    `json:"kind"`
 	Amount   int       `json:"amount"`
-	Timedate time.Time `json:"timedate"`
-	UserID   Snowflake `json:"user_id"`
-	Game     string    `json:"game"`
+	Timedate time.Time `gorm:"index:idx_sports_user_timedate,priority:2" json:"timedate"`
+	UserID   Snowflake `gorm:"index:idx_sports_game_user,priority:2;index:idx_sports_user_timedate,priority:1" json:"user_id"`
+	Game     string    `gorm:"index:idx_sports_game_user,priority:1" json:"game"`
+	// game session, the sport was done for
+	SessionID *Snowflake `gorm:"index" json:"session_id,omitempty"`
 }
 
 // Row which is sent by the user. The rest will be added from
@@ -28,6 +30,9 @@ type PostSportRequest struct {
 	// The amount of Exercises done
 	Amount int `json:"amount" binding:"required" example:"42"`
 
+	// The game session, this sport-record belongs to. Defaults to the live session of the game
+	SessionID *Snowflake `json:"session_id,omitempty" example:"42"`
+
 	// when the sport was done as UTC time - currently set by the API
 	Timedate time.Time `json:"timedate,omitempty" example:"1751897680.372402"`
 
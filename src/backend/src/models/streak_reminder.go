@@ -0,0 +1,11 @@
+package models
+
+// SQL Table containing whether <UserID> wants to be reminded of his streak and get the weekly digest
+// by a direct message of the Discord bot and when he was reminded last. Users without a row are reminded
+// swagger:model StreakReminder
+type StreakReminder struct {
+	UserID   Snowflake `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	Disabled bool      `gorm:"not null;default:false" json:"disabled" example:"false"`
+	// UTC day of the last reminder in the format YYYY-MM-DD
+	LastSentOn string `json:"last_sent_on,omitempty" example:"2023-05-01"`
+}
@@ -3,8 +3,11 @@ package models
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
+const discordCDNURL = "https://cdn.discordapp.com"
+
 // Discord User Representation
 type User struct {
 	ID            Snowflake `json:"id"`
@@ -14,10 +17,47 @@ type User struct {
 	Email         string    `json:"email"`
 }
 
-// GetAvatarURL returns the user's Discord avatar URL
+// PublicUser contains only the fields of a user, which can be shown to everyone
+// swagger:model PublicUser
+type PublicUser struct {
+	ID       Snowflake `json:"id" example:"348922315062044675"`
+	Username string    `json:"username" example:"inu"`
+	Avatar   string    `json:"avatar" example:"8342729096ea3675442027381ff50dfe"`
+}
+
+// Public strips all private fields like the email from the user
+func (u *User) Public() PublicUser {
+	return PublicUser{
+		ID:       u.ID,
+		Username: u.Username,
+		Avatar:   u.Avatar,
+	}
+}
+
+// GetAvatarURL returns the user's Discord avatar URL.
+// Animated avatars (hash starts with `a_`) are GIFs, users without an avatar
+// get the default avatar Discord would show for them
 func (u *User) GetAvatarURL() string {
-	return fmt.Sprintf("https://cdn.discordapp.com/avatars/%v/%v.png", u.ID, u.Avatar)
+	if u.Avatar == "" {
+		return fmt.Sprintf("%s/embed/avatars/%d.png", discordCDNURL, u.defaultAvatarIndex())
+	}
+	extension := "png"
+	if strings.HasPrefix(u.Avatar, "a_") {
+		extension = "gif"
+	}
+	return fmt.Sprintf("%s/avatars/%v/%s.%s", discordCDNURL, u.ID, u.Avatar, extension)
+}
+
+// returns the index of the default avatar. Users with the new username system have the
+// discriminator 0 and their avatar depends on the ID, legacy users on the discriminator
+func (u *User) defaultAvatarIndex() uint64 {
+	discriminator, err := strconv.Atoi(u.Discriminator)
+	if err != nil || discriminator == 0 {
+		return (uint64(u.ID) >> 22) % 6
+	}
+	return uint64(discriminator % 5)
 }
+
 func (s *User) ParseJS() JsUser {
 
 	return JsUser{
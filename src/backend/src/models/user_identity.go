@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table linking an account <Subject> of an auth provider <Provider> (discord, google, ...)
+// to an internal user <UserID>. One user can have several identities, but every
+// identity belongs to exactly one user.
+// swagger:model UserIdentity
+type UserIdentity struct {
+	ID        Snowflake `gorm:"primaryKey" json:"id"`
+	UserID    Snowflake `gorm:"not null;index" json:"user_id" example:"348922315062044675"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"provider" example:"discord"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"subject" example:"348922315062044675"`
+	Username  string    `json:"username" example:"inu"`
+	CreatedAt time.Time `json:"created_at"`
+	// OAuth tokens of the provider, used to refresh the profile in the background.
+	// They are encrypted in the database by the repository
+	AccessToken    string     `json:"-"`
+	RefreshToken   string     `json:"-"`
+	TokenExpiresAt *time.Time `json:"-"`
+	// last time the account information was fetched from the provider
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// SQL Table containing the privacy settings of a user <UserID>. Every visibility
+// controls who can see the according resource. Users without a record use `DefaultUserSettings`.
+// swagger:model UserSettings
+type UserSettings struct {
+	UserID                  Snowflake  `gorm:"primaryKey;autoIncrement:false" json:"user_id" example:"348922315062044675"`
+	ActivitiesVisibility    Visibility `gorm:"not null" json:"activities_visibility" example:"friends"`
+	StreakVisibility        Visibility `gorm:"not null" json:"streak_visibility" example:"public"`
+	GoalsVisibility         Visibility `gorm:"not null" json:"goals_visibility" example:"friends"`
+	OverdueDeathsVisibility Visibility `gorm:"not null" json:"overdue_deaths_visibility" example:"private"`
+	ProfileVisibility       Visibility `gorm:"not null" json:"profile_visibility" example:"friends"`
+
+	// whether or not the user can be found by his username when sending friend requests
+	Discoverable bool      `gorm:"not null" json:"discoverable" example:"true"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DefaultUserSettings returns the settings used for users, which have not changed anything yet
+func DefaultUserSettings(userID Snowflake) UserSettings {
+	return UserSettings{
+		UserID:                  userID,
+		ActivitiesVisibility:    DefaultVisibility,
+		StreakVisibility:        DefaultVisibility,
+		GoalsVisibility:         DefaultVisibility,
+		OverdueDeathsVisibility: DefaultVisibility,
+		ProfileVisibility:       DefaultVisibility,
+		Discoverable:            false,
+	}
+}
+
+// VisibilityOf returns the visibility the user has chosen for <resource>
+func (s *UserSettings) VisibilityOf(resource Resource) Visibility {
+	switch resource {
+	case ResourceActivities:
+		return s.ActivitiesVisibility
+	case ResourceStreak:
+		return s.StreakVisibility
+	case ResourceGoals:
+		return s.GoalsVisibility
+	case ResourceOverdueDeaths:
+		return s.OverdueDeathsVisibility
+	case ResourceProfile:
+		return s.ProfileVisibility
+	default:
+		return DefaultVisibility
+	}
+}
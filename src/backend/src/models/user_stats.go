@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+)
+
+// StatsBucketSize is the time span aggregated to one bucket of the statistics
+type StatsBucketSize string
+
+const (
+	BucketDay   StatsBucketSize = "day"
+	BucketWeek  StatsBucketSize = "week"
+	BucketMonth StatsBucketSize = "month"
+)
+
+// IsValid returns whether or not the bucket size is one of the known values
+func (b StatsBucketSize) IsValid() bool {
+	return b == BucketDay || b == BucketWeek || b == BucketMonth
+}
+
+// StatsQuery selects the sports aggregated to statistics. <From> and <To> are UTC days, both inclusive
+type StatsQuery struct {
+	From   time.Time
+	To     time.Time
+	Bucket StatsBucketSize
+}
+
+// Sum of the amounts of one sport in one game on one UTC day <Date>
+type SportDayTotal struct {
+	Date       string `json:"date" example:"2025-03-01"`
+	Kind       string `json:"kind" example:"pushup"`
+	Game       string `json:"game" example:"league"`
+	Amount     int    `json:"amount" example:"120"`
+	Activities int    `json:"activities" example:"4"`
+}
+
+// StatsTotal is the aggregate of several sports
+// swagger:model StatsTotal
+type StatsTotal struct {
+	Amount int `json:"amount" example:"1240"`
+	// amounts divided by the base multiplier of their sport and the multiplier of their game.
+	// This makes sports and games comparable, e.g. 25 push-ups (multiplier 2.5) weigh as much
+	// as 100 seconds plank (multiplier 10) and 15 push-ups in a game with multiplier 1.5 as 10 in one with 1
+	Weighted float64 `json:"weighted" example:"352.5"`
+	// number of sport entries
+	Activities int `json:"activities" example:"31"`
+}
+
+// Add adds <amount> of a sport with <multiplier> to the total
+func (t *StatsTotal) Add(amount int, multiplier float64, activities int) {
+	t.Amount += amount
+	t.Weighted += float64(amount) / multiplier
+	t.Activities += activities
+}
+
+// StatsDay is the total of one UTC day
+// swagger:model StatsDay
+type StatsDay struct {
+	Date string `json:"date" example:"2025-03-01"`
+	StatsTotal
+}
+
+// StatsBucket is the total of the day, week (starting on monday) or month starting at <Start>
+// swagger:model StatsBucket
+type StatsBucket struct {
+	Start string `json:"start" example:"2025-02-24"`
+	StatsTotal
+}
+
+// swagger:model StatsSport
+type StatsSport struct {
+	Kind string `json:"kind" example:"pushup"`
+	StatsTotal
+}
+
+// swagger:model StatsGame
+type StatsGame struct {
+	Game string `json:"game" example:"league"`
+	StatsTotal
+}
+
+// swagger:model StatsWeekday
+type StatsWeekday struct {
+	Weekday string `json:"weekday" example:"monday"`
+	// number of days of this weekday with at least one sport
+	ActiveDays int `json:"active_days" example:"12"`
+	StatsTotal
+}
+
+// UserStats are the aggregated sports of a user in a date range
+// swagger:model UserStats
+type UserStats struct {
+	UserID Snowflake       `json:"user_id" example:"348922315062044675"`
+	From   string          `json:"from" example:"2024-03-01"`
+	To     string          `json:"to" example:"2025-03-01"`
+	Bucket StatsBucketSize `json:"bucket" example:"week"`
+	Total  StatsTotal      `json:"total"`
+	// number of days with at least one sport
+	ActiveDays                  int     `json:"active_days" example:"87"`
+	AveragePerActiveDay         float64 `json:"average_per_active_day" example:"14.25"`
+	WeightedAveragePerActiveDay float64 `json:"weighted_average_per_active_day" example:"4.05"`
+	// day with the highest weighted total, nil without sports
+	BestDay *StatsDay `json:"best_day"`
+	// totals of every active day for a heatmap
+	Days []StatsDay `json:"days"`
+	// totals of every bucket in the range, including empty ones
+	Buckets []StatsBucket `json:"buckets"`
+	// totals per sport, highest weighted total first
+	Sports []StatsSport `json:"sports"`
+	// totals per game, highest weighted total first
+	Games []StatsGame `json:"games"`
+	// totals per weekday, starting with monday
+	Weekdays []StatsWeekday `json:"weekdays"`
+}
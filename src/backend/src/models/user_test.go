@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestGetAvatarURL(t *testing.T) {
+	var tests = []struct {
+		name string
+		user User
+		want string
+	}{
+		{"Static avatar", User{ID: 348922315062044675, Avatar: "8342729096ea3675442027381ff50dfe"}, "https://cdn.discordapp.com/avatars/348922315062044675/8342729096ea3675442027381ff50dfe.png"},
+		{"Animated avatar", User{ID: 348922315062044675, Avatar: "a_1269e74af4df7417b13759eae50c83dc"}, "https://cdn.discordapp.com/avatars/348922315062044675/a_1269e74af4df7417b13759eae50c83dc.gif"},
+		// (348922315062044675 >> 22) % 6 = 3
+		{"Default avatar of unique username", User{ID: 348922315062044675, Discriminator: "0"}, "https://cdn.discordapp.com/embed/avatars/3.png"},
+		{"Default avatar without discriminator", User{ID: 348922315062044675}, "https://cdn.discordapp.com/embed/avatars/3.png"},
+		{"Default avatar of legacy username", User{ID: 348922315062044675, Discriminator: "1337"}, "https://cdn.discordapp.com/embed/avatars/2.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.GetAvatarURL(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
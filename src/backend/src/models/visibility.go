@@ -0,0 +1,49 @@
+package models
+
+// Visibility describes who is allowed to see a user scoped resource
+type Visibility string
+
+const (
+	// everyone who is logged in
+	Public Visibility = "public"
+	// the owner and users with an accepted friendship
+	FriendsOnly Visibility = "friends"
+	// only the owner
+	Private Visibility = "private"
+)
+
+// Resource is a kind of user scoped data which can be read by other users
+type Resource string
+
+const (
+	ResourceActivities    Resource = "activities"
+	ResourceStreak        Resource = "streak"
+	ResourceGoals         Resource = "goals"
+	ResourceOverdueDeaths Resource = "overdue_deaths"
+	ResourceProfile       Resource = "profile"
+)
+
+// DefaultVisibility is used for every resource, where the owner has not set anything else
+const DefaultVisibility = FriendsOnly
+
+// IsValid returns whether or not the visibility is one of the known values
+func (v Visibility) IsValid() bool {
+	return v == Public || v == FriendsOnly || v == Private
+}
+
+// Allows returns whether or not a viewer can see a resource with this visibility.
+// <isOwner> is true for the owner himself, <isFriend> for users with an accepted friendship
+// Use `db.IVisibilityService` for checks, which looks up both of them
+func (v Visibility) Allows(isOwner bool, isFriend bool) bool {
+	switch {
+	case isOwner:
+		return true
+	case v == Public:
+		return true
+	case v == FriendsOnly:
+		return isFriend
+	default:
+		// private or unknown values are treated as private
+		return false
+	}
+}
@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/KuramaSyu/GoToHell/src/backend/src/controllers"
 	_ "github.com/KuramaSyu/GoToHell/src/backend/src/docs" // load docs
+	"github.com/KuramaSyu/GoToHell/src/backend/src/middleware"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,7 +22,38 @@ func SetupRouter(
 	streakController *controllers.StreakController,
 	personalGoalsController *controllers.PersonalGoalsController,
 	userDetailsController *controllers.PersonalDetailsController,
+	userSettingsController *controllers.UserSettingsController,
+	usersController *controllers.UsersController,
+	friendInvitesController *controllers.FriendInvitesController,
+	apiTokensController *controllers.APITokensController,
+	sessionsController *controllers.SessionsController,
+	accountController *controllers.AccountController,
+	sportImportController *controllers.SportImportController,
+	userStatsController *controllers.UserStatsController,
+	gameSessionsController *controllers.GameSessionsController,
+	deathCounterController *controllers.DeathCounterController,
+	gameAccountsController *controllers.GameAccountsController,
+	gameEventsController *controllers.GameEventsController,
+	discordInteractionsController *controllers.DiscordInteractionsController,
+	notificationsController *controllers.NotificationsController,
+	emailSettingsController *controllers.EmailSettingsController,
+	pushSubscriptionsController *controllers.PushSubscriptionsController,
+	calendarController *controllers.CalendarController,
+	Now func() time.Time,
 ) {
+	// allows bursts of 10 searches and one more every 2 seconds
+	searchLimiter := middleware.NewRateLimiter(10, 2*time.Second, Now)
+	// allows bursts of 30 counted deaths and 10 more per second
+	deathCounterLimiter := middleware.NewRateLimiter(30, 100*time.Millisecond, Now)
+	// allows bursts of 30 game events and 10 more per second
+	gameEventLimiter := middleware.NewRateLimiter(30, 100*time.Millisecond, Now)
+
+	// every group with authenticated routes uses requireAuth. Groups which can be used with an
+	// API token declare the scopes for reading and writing requests with AllowTokens before.
+	requireAuth := middleware.RequireAuth()
+	readOnlyTokens := middleware.AllowTokens(models.ScopeRead, "")
+	sportTokens := middleware.AllowTokens(models.ScopeRead, models.ScopeSportWrite)
+	goalsTokens := middleware.AllowTokens(models.ScopeRead, models.ScopeGoalsWrite)
 
 	// API routes
 	api := r.Group("/api")
@@ -31,36 +66,110 @@ func SetupRouter(
 		api.GET("/default", sportsController.Default)
 
 		// route for sport
-		sports := api.Group("/sports")
+		sports := api.Group("/sports", sportTokens, requireAuth)
 		sports.GET("", sportsController.GetSports)
 		sports.GET("/total", sportsController.GetTotalResults)
 		sports.POST("", sportsController.PostSport)
 		sports.PATCH("", sportsController.Patch)
 		sports.DELETE("/:id", sportsController.DeleteSport)
+		sports.POST("/import", sportImportController.Post)
 
-		streak := api.Group("/streak")
+		streak := api.Group("/streak", readOnlyTokens, requireAuth)
 		streak.GET("", streakController.Get)
 
 		// route for friendships
-		friends := api.Group("/friends")
+		friends := api.Group("/friends", readOnlyTokens, requireAuth)
 		friends.GET("", friendController.GetFriends)
 		friends.POST("", friendController.PostFriendship)
 		friends.DELETE("/:id", friendController.DeleteFriendship)
 		friends.PUT("", friendController.UpdateFriendship)
+		friends.GET("/suggestions", friendController.GetSuggestions)
+
+		// route for invite codes, which create friendships without a pending step
+		invites := friends.Group("/invites")
+		invites.GET("", friendInvitesController.Get)
+		invites.POST("", friendInvitesController.Post)
+		invites.DELETE("/:code", friendInvitesController.Delete)
+		invites.POST("/:code/redeem", friendInvitesController.Redeem)
 
 		// route for overdue deaths
-		overdueDeaths := api.Group("/overdue-deaths")
+		overdueDeaths := api.Group("/overdue-deaths", sportTokens, requireAuth)
 		overdueDeaths.POST("", overdueDeathsController.Post)
 		overdueDeaths.PUT("", overdueDeathsController.Put)
 		overdueDeaths.DELETE("", overdueDeathsController.Delete)
 		overdueDeaths.PATCH("", overdueDeathsController.Patch)
 		overdueDeaths.GET("", overdueDeathsController.Get)
+		// live death counter, which doesn't write to the database on every death
+		overdueDeaths.POST("/increment", middleware.RateLimit(deathCounterLimiter, middleware.UserOrIPKey), deathCounterController.Increment)
+		overdueDeaths.GET("/live", deathCounterController.GetLive)
+
+		// route for game sessions, to which sports are linked
+		gameSessions := api.Group("/game-sessions", sportTokens, requireAuth)
+		gameSessions.GET("", gameSessionsController.Get)
+		gameSessions.POST("", gameSessionsController.Post)
+		gameSessions.GET("/live", gameSessionsController.GetLive)
+		gameSessions.GET("/:id", gameSessionsController.GetByID)
+		gameSessions.PATCH("/:id", gameSessionsController.Patch)
+		gameSessions.POST("/:id/close", gameSessionsController.Close)
+
+		// route for accounts of game-stats connectors like riot, whose matches become overdue deaths
+		gameAccounts := api.Group("/game-accounts", requireAuth)
+		gameAccounts.GET("", gameAccountsController.Get)
+		gameAccounts.POST("", gameAccountsController.Post)
+		gameAccounts.DELETE("/:id", gameAccountsController.Delete)
+
+		// route for the signing secret and the received events of games and tools like OBS plugins
+		gameEvents := api.Group("/events", requireAuth)
+		gameEvents.GET("", gameEventsController.Get)
+		gameEvents.GET("/secret", gameEventsController.GetSecret)
+		gameEvents.POST("/secret", gameEventsController.PostSecret)
+		gameEvents.DELETE("/secret", gameEventsController.DeleteSecret)
+
+		// interactions endpoint of the Discord bot. Interactions are signed by Discord instead of using a session
+		api.POST("/discord/interactions", discordInteractionsController.Post)
+
+		// route for the notification inbox of the logged in user
+		notifications := api.Group("/notifications", readOnlyTokens, requireAuth)
+		notifications.GET("", notificationsController.Get)
+		notifications.GET("/unread-count", notificationsController.GetUnreadCount)
+		notifications.POST("/read-all", notificationsController.MarkAllRead)
+		notifications.POST("/:id/read", notificationsController.MarkRead)
+
+		// route for the privacy settings of the logged in user
+		settings := api.Group("/settings", requireAuth)
+		settings.GET("", userSettingsController.Get)
+		settings.PATCH("", userSettingsController.Patch)
+		settings.GET("/email", emailSettingsController.Get)
+		settings.PUT("/email", emailSettingsController.Put)
+		settings.GET("/email/deliveries", emailSettingsController.GetDeliveries)
+		settings.GET("/calendar", calendarController.GetFeed)
+		settings.POST("/calendar", calendarController.PostFeed)
+		settings.DELETE("/calendar", calendarController.DeleteFeed)
+
+		// route for the unsubscribe links of emails. The links are signed instead of using a session.
+		// GET only asks for confirmation, since mail scanners prefetch links
+		api.GET("/email/unsubscribe", emailSettingsController.GetUnsubscribe)
+		api.POST("/email/unsubscribe", emailSettingsController.Unsubscribe)
+
+		// route for the calendar feeds. Calendar apps authenticate with the token in the URL instead of a session
+		api.GET("/calendar/:token", calendarController.GetICS)
+
+		// route for the browsers, in which the logged in user receives push notifications
+		api.GET("/push/public-key", pushSubscriptionsController.GetPublicKey)
+		push := api.Group("/push/subscriptions", requireAuth)
+		push.GET("", pushSubscriptionsController.Get)
+		push.POST("", pushSubscriptionsController.Post)
+		push.DELETE("/:id", pushSubscriptionsController.Delete)
+
+		// route for finding other users
+		users := api.Group("/users", readOnlyTokens, requireAuth)
+		users.GET("/search", middleware.RateLimit(searchLimiter, middleware.UserOrIPKey), usersController.Search)
 
 		// user scoped routes
 		user := api.Group("/user/:user_id")
 
 		// route for personal goals
-		personalGoals := user.Group("/goals")
+		personalGoals := user.Group("/goals", goalsTokens, requireAuth)
 		personalGoals.GET("", personalGoalsController.Get)
 		personalGoals.POST("", personalGoalsController.Post)
 		personalGoals.PATCH("", personalGoalsController.Patch)
@@ -68,7 +177,30 @@ func SetupRouter(
 		personalGoals.DELETE("", personalGoalsController.Delete)
 
 		// route for retrieving details
-		user.GET("/details", userDetailsController.Get)
+		user.GET("/details", readOnlyTokens, requireAuth, userDetailsController.Get)
+
+		// route for receiving game events. Events are authenticated with their signature instead of a session
+		user.POST("/events", middleware.RateLimit(gameEventLimiter, middleware.UserOrIPKey), gameEventsController.Post)
+
+		// route for aggregated statistics like a calendar heatmap
+		user.GET("/stats", readOnlyTokens, requireAuth, userStatsController.Get)
+
+		// route for personal access tokens. Managing tokens requires a session
+		tokens := api.Group("/tokens", requireAuth)
+		tokens.GET("", apiTokensController.Get)
+		tokens.POST("", apiTokensController.Post)
+		tokens.DELETE("/:id", apiTokensController.Delete)
+
+		// route for the login sessions of the logged in user
+		userSessions := api.Group("/sessions", requireAuth)
+		userSessions.GET("", sessionsController.Get)
+		userSessions.DELETE("/:id", sessionsController.Delete)
+
+		// route for deleting and exporting the account of the logged in user
+		me := api.Group("/me", requireAuth)
+		me.DELETE("", accountController.Delete)
+		me.DELETE("/deletion", accountController.CancelDeletion)
+		me.GET("/export", accountController.Export)
 
 		// route for swagger API docs
 		api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -77,9 +209,16 @@ func SetupRouter(
 	// Auth routes
 	auth := api.Group("/auth")
 	{
-		auth.GET("/discord", authController.Login)
-		auth.GET("/discord/callback", authController.Callback)
-		auth.GET("/user", authController.GetUser)
+		auth.GET("/user", readOnlyTokens, requireAuth, authController.GetUser)
 		auth.GET("/logout", authController.Logout)
+		auth.GET("/providers", authController.GetProviders)
+
+		// route for the accounts linked to the logged in user
+		identities := auth.Group("/identities", requireAuth)
+		identities.GET("", authController.GetIdentities)
+		identities.DELETE("/:provider", authController.DeleteIdentity)
+		// login and callback of every provider, e.g. /auth/discord and /auth/discord/callback
+		auth.GET("/:provider", authController.Login)
+		auth.GET("/:provider/callback", authController.Callback)
 	}
 }
@@ -0,0 +1,210 @@
+// Package scheduler runs background jobs on cron-like schedules. The last run of every job is
+// persisted, so that jobs are not repeated after a restart, and a lock in the database ensures,
+// that only one process runs a job at a time.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+)
+
+const (
+	defaultTickInterval = time.Second
+	// runs taking longer lose their lock, so that another process can run the job
+	defaultJobTimeout = 10 * time.Minute
+)
+
+// Job is a function, which runs on a schedule
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func(ctx context.Context) error
+	// maximum duration of a run. The context of the run is cancelled and the lock expires afterwards
+	Timeout time.Duration
+	// local jobs run in every process without a lock or a persisted run, e.g. to save in-memory state
+	Local bool
+}
+
+// Scheduler runs the due jobs every <TickInterval>
+type Scheduler struct {
+	Runs repositories.JobRunRepository
+	// identifies this process in the locks of the jobs
+	Owner        string
+	TickInterval time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+
+	jobs []*Job
+	// time, when the first tick happened. Jobs, which never ran, are scheduled from then
+	startedAt time.Time
+	// names of the jobs running in this process
+	mu      sync.Mutex
+	running map[string]bool
+	// last runs of the local jobs
+	localRuns map[string]time.Time
+	wg        sync.WaitGroup
+}
+
+func New(runs repositories.JobRunRepository, Now func() time.Time) *Scheduler {
+	return &Scheduler{
+		Runs:         runs,
+		Owner:        newOwner(),
+		TickInterval: defaultTickInterval,
+		Now:          Now,
+		running:      map[string]bool{},
+		localRuns:    map[string]time.Time{},
+	}
+}
+
+// returns the hostname and a random suffix, so that two processes on one host differ
+func newOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 4)
+	rand.Read(b)
+	return host + "-" + hex.EncodeToString(b)
+}
+
+// Add registers the job <name>, which calls <run> on <schedule>
+func (s *Scheduler) Add(name string, schedule Schedule, run func(ctx context.Context) error) *Job {
+	job := &Job{Name: name, Schedule: schedule, Run: run, Timeout: defaultJobTimeout}
+	s.jobs = append(s.jobs, job)
+	return job
+}
+
+// AddLocal registers the local job <name>, which calls <run> on <schedule> in every process
+func (s *Scheduler) AddLocal(name string, schedule Schedule, run func(ctx context.Context) error) *Job {
+	job := s.Add(name, schedule, run)
+	job.Local = true
+	return job
+}
+
+// Jobs returns the registered jobs
+func (s *Scheduler) Jobs() []*Job {
+	return s.jobs
+}
+
+// Start runs the due jobs every <TickInterval> until <ctx> is done and waits for running jobs
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.TickInterval)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			s.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue starts every due job, which is not locked, in its own goroutine
+func (s *Scheduler) RunDue(ctx context.Context) {
+	now := s.Now().UTC()
+	if s.startedAt.IsZero() {
+		s.startedAt = now
+	}
+	for _, job := range s.jobs {
+		if err := s.runIfDue(ctx, job, now); err != nil {
+			log.Printf("Failed to schedule job %s: %v", job.Name, err)
+		}
+	}
+}
+
+// Wait blocks until all running jobs finished
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) runIfDue(ctx context.Context, job *Job, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[job.Name] {
+		return nil
+	}
+
+	if job.Local {
+		since, ok := s.localRuns[job.Name]
+		if !ok {
+			since = s.startedAt
+		}
+		if !isDue(job, since, now) {
+			return nil
+		}
+	} else {
+		run, err := s.Runs.Fetch(job.Name)
+		if err != nil {
+			return err
+		}
+		since := s.startedAt
+		if run.LastRunAt != nil {
+			since = *run.LastRunAt
+		}
+		if !isDue(job, since, now) {
+			return nil
+		}
+		locked, err := s.Runs.Lock(job.Name, s.Owner, run.LastRunAt, now, now.Add(job.Timeout))
+		if err != nil || !locked {
+			return err
+		}
+	}
+	s.running[job.Name] = true
+	s.wg.Add(1)
+	go s.run(ctx, job, now)
+	return nil
+}
+
+// returns whether <job>, which last ran at <since>, is due at <now>
+func isDue(job *Job, since time.Time, now time.Time) bool {
+	next := job.Schedule.Next(since)
+	return !next.IsZero() && !next.After(now)
+}
+
+// runs <job> and stores the run
+func (s *Scheduler) run(ctx context.Context, job *Job, startedAt time.Time) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.Name)
+		if job.Local {
+			s.localRuns[job.Name] = startedAt
+		}
+		s.mu.Unlock()
+	}()
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	started := time.Now()
+	err := runRecovered(jobCtx, job)
+	if err != nil {
+		log.Printf("Job %s failed: %v", job.Name, err)
+	}
+	if job.Local {
+		return
+	}
+	if finishErr := s.Runs.Finish(job.Name, s.Owner, startedAt, time.Since(started), err); finishErr != nil {
+		log.Printf("Failed to store the run of job %s: %v", job.Name, finishErr)
+	}
+}
+
+// runs <job> and turns a panic into an error, so that one job can't stop the others
+func runRecovered(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// a monday
+	from := time.Date(2023, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2023, 5, 1, 12, 31, 0, 0, time.UTC)},
+		{"every 15 minutes", "*/15 * * * *", time.Date(2023, 5, 1, 12, 45, 0, 0, time.UTC)},
+		{"evening", "0 20 * * *", time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC)},
+		{"earlier hour is tomorrow", "0 3 * * *", time.Date(2023, 5, 2, 3, 0, 0, 0, time.UTC)},
+		{"hour range", "*/15 20-23 * * *", time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC)},
+		{"next monday", "0 8 * * 1", time.Date(2023, 5, 8, 8, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2023, 5, 7, 0, 0, 0, 0, time.UTC)},
+		{"list", "0 0 * * 3,5", time.Date(2023, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 3 * 2", time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{"next year", "0 0 1 1 *", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"monthly shorthand", "@monthly", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("failed to parse %q: %v", tt.expr, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{"cron", "0 20 * * *", false},
+		{"interval", "@every 90s", false},
+		{"shorthand", "@daily", false},
+		{"too few fields", "0 20 * *", true},
+		{"minute out of range", "60 * * * *", true},
+		{"reversed range", "0 5-3 * * *", true},
+		{"zero step", "*/0 * * * *", true},
+		{"not a number", "0 x * * *", true},
+		{"invalid interval", "@every soon", true},
+		{"negative interval", "@every -1m", true},
+		{"unknown shorthand", "@sometimes", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.expr); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want an error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// returns a scheduler with an in-memory database and a clock, which is set with the returned function
+func newTestScheduler(t *testing.T, database *gorm.DB, start time.Time) (*Scheduler, func(time.Time)) {
+	t.Helper()
+	var clock atomic.Value
+	clock.Store(start)
+	scheduler := New(db.NewGormJobRunRepository(database), func() time.Time { return clock.Load().(time.Time) })
+	return scheduler, func(t time.Time) { clock.Store(t) }
+}
+
+func newTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	// every connection to :memory: would be its own database
+	sqlDB, _ := database.DB()
+	sqlDB.SetMaxOpenConns(1)
+	return database
+}
+
+func TestScheduler(t *testing.T) {
+	database := newTestDatabase(t)
+	start := time.Date(2023, 5, 1, 19, 59, 0, 0, time.UTC)
+	scheduler, setNow := newTestScheduler(t, database, start)
+
+	var evening, failing, local atomic.Int32
+	scheduler.Add("evening", MustParse("0 20 * * *"), func(ctx context.Context) error {
+		evening.Add(1)
+		return nil
+	})
+	scheduler.Add("failing", Every(time.Minute), func(ctx context.Context) error {
+		failing.Add(1)
+		panic("broken job")
+	})
+	scheduler.AddLocal("local", Every(time.Minute), func(ctx context.Context) error {
+		local.Add(1)
+		return errors.New("local jobs can fail too")
+	})
+
+	run := func(now time.Time) {
+		setNow(now)
+		scheduler.RunDue(context.Background())
+		scheduler.Wait()
+	}
+	run(start)
+	if evening.Load() != 0 || failing.Load() != 0 || local.Load() != 0 {
+		t.Fatalf("jobs ran before they were due: %d, %d, %d", evening.Load(), failing.Load(), local.Load())
+	}
+
+	run(start.Add(time.Minute))
+	run(start.Add(time.Minute + 30*time.Second))
+	if evening.Load() != 1 || failing.Load() != 1 || local.Load() != 1 {
+		t.Errorf("got runs %d, %d, %d, want every job once", evening.Load(), failing.Load(), local.Load())
+	}
+	failed, err := scheduler.Runs.Fetch("failing")
+	if err != nil {
+		t.Fatalf("failed to fetch run: %v", err)
+	}
+	if failed.LastError != "panic: broken job" || failed.LockedBy != "" || failed.LastRunAt == nil {
+		t.Errorf("got run %+v, want a released run with the panic as error", failed)
+	}
+	if local, _ := scheduler.Runs.Fetch("local"); local.LastRunAt != nil {
+		t.Errorf("got persisted local run %+v, want none", local)
+	}
+
+	// a restarted process knows, that the evening job already ran today
+	restarted, setRestartedNow := newTestScheduler(t, database, start.Add(2*time.Minute))
+	var restartedEvening atomic.Int32
+	restarted.Add("evening", MustParse("0 20 * * *"), func(ctx context.Context) error {
+		restartedEvening.Add(1)
+		return nil
+	})
+	restarted.RunDue(context.Background())
+	restarted.Wait()
+	if restartedEvening.Load() != 0 {
+		t.Errorf("got %d runs after the restart, want 0", restartedEvening.Load())
+	}
+
+	// the next evening only one of both processes runs the job
+	nextEvening := start.Add(24*time.Hour + time.Minute)
+	setNow(nextEvening)
+	setRestartedNow(nextEvening)
+	blocked := make(chan struct{})
+	scheduler.jobs[0].Run = func(ctx context.Context) error {
+		evening.Add(1)
+		<-blocked
+		return nil
+	}
+	scheduler.RunDue(context.Background())
+	restarted.RunDue(context.Background())
+	close(blocked)
+	scheduler.Wait()
+	restarted.Wait()
+	if total := evening.Load() + restartedEvening.Load(); total != 2 || restartedEvening.Load() != 0 {
+		t.Errorf("got %d runs of the first and %d of the restarted process, want 2 and 0", evening.Load(), restartedEvening.Load())
+	}
+}
@@ -0,0 +1,98 @@
+// Package webpush sends encrypted notifications to browsers with the Web Push protocol.
+// Payloads are encrypted per RFC 8291 and requests are authenticated with VAPID (RFC 8292)
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// size of the single encrypted record, which contains the whole payload
+	recordSize = 4096
+	saltLength = 16
+	// 16 bytes GCM tag and 1 byte padding delimiter
+	recordOverhead = 17
+	// header of the aes128gcm content coding: salt, record size, key id length and the 65 byte key
+	headerLength = saltLength + 4 + 1 + 65
+)
+
+// MaxPayloadSize is the largest plaintext, which fits into one record
+const MaxPayloadSize = recordSize - headerLength - recordOverhead
+
+var ErrPayloadTooLarge = fmt.Errorf("payload is larger than %d bytes", MaxPayloadSize)
+
+// Encrypt encrypts <plaintext> for the user agent with the public key <uaPublic> and the
+// authentication secret <authSecret> of a push subscription (RFC 8291). A new key pair and
+// salt are used for every message
+func Encrypt(plaintext []byte, uaPublic []byte, authSecret []byte) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(plaintext, uaPublic, authSecret, asPrivate, salt)
+}
+
+// encrypt encrypts <plaintext> with the application server key <asPrivate> and <salt>
+func encrypt(plaintext []byte, uaPublic []byte, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	if len(authSecret) == 0 {
+		return nil, errors.New("missing authentication secret")
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	// combines the shared secret with the authentication secret (RFC 8291 section 3.3)
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	// derives key and nonce of the record (RFC 8188 section 2.2 and 2.3)
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 0, headerLength+len(plaintext)+recordOverhead)
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	// 0x02 marks the last record, no further padding is added
+	record := append(append([]byte{}, plaintext...), 0x02)
+	return gcm.Seal(body, nonce, record, nil), nil
+}
@@ -0,0 +1,103 @@
+package webpush
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/api/repositories"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+// Message is the payload of a push message, which the service worker of the frontend shows
+// as notification
+type Message struct {
+	Type  models.NotificationType `json:"type"`
+	Title string                  `json:"title"`
+	Body  string                  `json:"body,omitempty"`
+	// opened, when the notification is clicked
+	URL string `json:"url"`
+	// ID of the notification in the inbox, which can be marked as read
+	NotificationID models.Snowflake `json:"notification_id,omitempty"`
+}
+
+// Notifier sends notifications to every push subscription of their user and deletes
+// subscriptions, which expired
+type Notifier struct {
+	Subscriptions repositories.PushSubscriptionRepository
+	Users         db.UserRepository
+	Sender        *Sender
+	FrontendURL   string
+}
+
+func NewNotifier(
+	subscriptions repositories.PushSubscriptionRepository,
+	users db.UserRepository,
+	sender *Sender,
+	frontendURL string,
+) *Notifier {
+	return &Notifier{
+		Subscriptions: subscriptions,
+		Users:         users,
+		Sender:        sender,
+		FrontendURL:   strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+// returns the title of <notificationType>, which was caused by <actor>, and the page of the
+// frontend, which shows it
+func describe(notificationType models.NotificationType, actor string) (string, string) {
+	switch notificationType {
+	case models.NotificationFriendRequest:
+		return actor + " sent you a friend request", "/friends"
+	case models.NotificationFriendAccepted:
+		return actor + " accepted your friend request", "/friends"
+	case models.NotificationInviteRedeemed:
+		return actor + " used your invite", "/friends"
+	}
+	return "New notification from " + actor, "/"
+}
+
+// Deliver sends <notification> to every browser, in which its user subscribed to push messages
+func (n *Notifier) Deliver(ctx context.Context, notification models.Notification) error {
+	subscriptions, err := n.Subscriptions.FetchByUserID(notification.UserID)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+	actor, err := n.Users.GetUserByID(notification.ActorID)
+	if err != nil {
+		return err
+	}
+	title, path := describe(notification.Type, actor.Username)
+	payload, err := json.Marshal(Message{
+		Type:           notification.Type,
+		Title:          title,
+		URL:            n.FrontendURL + path,
+		NotificationID: notification.ID,
+	})
+	if err != nil {
+		return err
+	}
+	return n.Send(ctx, subscriptions, payload)
+}
+
+// Send sends <payload> to <subscriptions>. Expired subscriptions are deleted, so that
+// browsers, which unsubscribed or were uninstalled, are not tried again
+func (n *Notifier) Send(ctx context.Context, subscriptions []models.PushSubscription, payload []byte) error {
+	var errs []error
+	for _, subscription := range subscriptions {
+		err := n.Sender.Send(ctx, subscription, payload)
+		if errors.Is(err, ErrSubscriptionGone) {
+			log.Printf("Deleting expired push subscription %d of user %d", subscription.ID, subscription.UserID)
+			err = n.Subscriptions.DeleteByEndpoint(subscription.Endpoint)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("push subscription %d: %w", subscription.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
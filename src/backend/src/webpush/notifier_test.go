@@ -0,0 +1,271 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/db"
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// browser holds the keys, which a browser creates for a push subscription
+type browser struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newBrowser(t *testing.T) browser {
+	t.Helper()
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate browser key: %v", err)
+	}
+	auth := make([]byte, authSecretLength)
+	rand.Read(auth)
+	return browser{private: private, auth: auth}
+}
+
+// subscription returns the subscription of the browser for <endpoint>
+func (b browser) subscription(userID models.Snowflake, endpoint string) *models.PushSubscription {
+	return &models.PushSubscription{
+		UserID:   userID,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(b.private.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.auth),
+	}
+}
+
+// decrypt decrypts a message encrypted for the browser like a user agent (RFC 8291)
+func (b browser) decrypt(body []byte) ([]byte, error) {
+	salt, keyLength := body[:saltLength], int(body[saltLength+4])
+	asPublic := body[saltLength+5 : saltLength+5+keyLength]
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := b.private.ECDH(asKey)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), b.private.PublicKey().Bytes()...), asPublic...)
+	ikm, _ := hkdf.Key(sha256.New, ecdhSecret, b.auth, string(keyInfo), 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, body[saltLength+5+keyLength:], nil)
+	if err != nil {
+		return nil, err
+	}
+	record = []byte(strings.TrimRight(string(record), "\x00"))
+	return record[:len(record)-1], nil
+}
+
+// verifyVAPID verifies the VAPID JWT of <authorization> like a push service and returns its claims
+func verifyVAPID(authorization string) (map[string]any, bool) {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ", ") {
+		if value, ok := strings.CutPrefix(part, "t="); ok {
+			token = value
+		} else if value, ok := strings.CutPrefix(part, "k="); ok {
+			key = value
+		}
+	}
+	parts := strings.Split(token, ".")
+	public, err := DecodeKey(key)
+	if len(parts) != 3 || err != nil || len(public) != 65 {
+		return nil, false
+	}
+	signature, err := DecodeKey(parts[2])
+	if err != nil || len(signature) != 64 {
+		return nil, false
+	}
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(public[1:33]),
+		Y:     new(big.Int).SetBytes(public[33:]),
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(publicKey, hash[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return nil, false
+	}
+	claims := map[string]any{}
+	encoded, _ := DecodeKey(parts[1])
+	return claims, json.Unmarshal(encoded, &claims) == nil
+}
+
+// pushService is a stand-in for the push service of a browser. Subscriptions with an endpoint
+// ending in /gone are reported as expired
+type pushService struct {
+	*httptest.Server
+	t        *testing.T
+	keys     *VAPIDKeys
+	browsers map[string]browser
+
+	mu       sync.Mutex
+	received map[string][]Message
+}
+
+func newPushService(t *testing.T, keys *VAPIDKeys) *pushService {
+	service := &pushService{t: t, keys: keys, browsers: map[string]browser{}, received: map[string][]Message{}}
+	service.Server = httptest.NewServer(http.HandlerFunc(service.handle))
+	t.Cleanup(service.Close)
+	return service
+}
+
+func (s *pushService) handle(w http.ResponseWriter, r *http.Request) {
+	claims, ok := verifyVAPID(r.Header.Get("Authorization"))
+	if !ok || claims["aud"] != s.URL || !strings.HasSuffix(r.Header.Get("Authorization"), "k="+s.keys.PublicKey) {
+		s.t.Errorf("got invalid VAPID authorization %q with claims %v", r.Header.Get("Authorization"), claims)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") != "86400" {
+		s.t.Errorf("got Content-Encoding %q and TTL %q", r.Header.Get("Content-Encoding"), r.Header.Get("TTL"))
+	}
+	if strings.HasSuffix(r.URL.Path, "/gone") {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	plaintext, err := s.browsers[r.URL.Path].decrypt(body)
+	if err != nil {
+		s.t.Errorf("failed to decrypt message for %s: %v", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var message Message
+	if err := json.Unmarshal(plaintext, &message); err != nil {
+		s.t.Errorf("failed to parse message %q: %v", plaintext, err)
+	}
+	s.mu.Lock()
+	s.received[r.URL.Path] = append(s.received[r.URL.Path], message)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+// subscribe registers a new browser of <userID> at the push service
+func (s *pushService) subscribe(t *testing.T, userID models.Snowflake, path string) *models.PushSubscription {
+	b := newBrowser(t)
+	s.browsers[path] = b
+	return b.subscription(userID, s.URL+path)
+}
+
+// TestNotifierDeliver verifies that notifications reach every browser of the user encrypted and
+// that expired subscriptions are deleted.
+func TestNotifierDeliver(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	if err := database.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("failed to migrate users table: %v", err)
+	}
+	for _, user := range []models.User{{ID: 1, Username: "inu"}, {ID: 2, Username: "friend"}} {
+		if err := database.Create(&user).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	subscriptions := &db.GormPushSubscriptionRepository{DB: database}
+	if err := subscriptions.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate push subscriptions table: %v", err)
+	}
+
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("failed to generate VAPID keys: %v", err)
+	}
+	if parsed, err := ParseVAPIDKeys(keys.PrivateKey()); err != nil || parsed.PublicKey != keys.PublicKey {
+		t.Fatalf("got public key %v (%v) after parsing, want %s", parsed, err, keys.PublicKey)
+	}
+	service := newPushService(t, keys)
+	for _, subscription := range []*models.PushSubscription{
+		service.subscribe(t, 1, "/phone"),
+		service.subscribe(t, 1, "/laptop/gone"),
+		service.subscribe(t, 2, "/friend"),
+	} {
+		if _, err := subscriptions.Save(subscription); err != nil {
+			t.Fatalf("failed to save subscription: %v", err)
+		}
+	}
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sender := NewSender(keys, "mailto:admin@example.com", func() time.Time { return now })
+	notifier := NewNotifier(subscriptions, &db.GormUserRepository{DB: database}, sender, "https://example.com/")
+	notification := models.Notification{ID: 5, UserID: 1, Type: models.NotificationFriendRequest, ActorID: 2}
+	if err := notifier.Deliver(context.Background(), notification); err != nil {
+		t.Fatalf("failed to deliver notification: %v", err)
+	}
+
+	want := Message{
+		Type:           models.NotificationFriendRequest,
+		Title:          "friend sent you a friend request",
+		URL:            "https://example.com/friends",
+		NotificationID: 5,
+	}
+	if got := service.received["/phone"]; len(got) != 1 || got[0] != want {
+		t.Errorf("got messages %+v on the phone, want %+v", got, want)
+	}
+	if got := service.received["/friend"]; len(got) != 0 {
+		t.Errorf("got messages %+v for another user, want none", got)
+	}
+
+	remaining, err := subscriptions.FetchByUserID(1)
+	if err != nil || len(remaining) != 1 || remaining[0].Endpoint != service.URL+"/phone" {
+		t.Errorf("got subscriptions %+v (%v), want only the phone", remaining, err)
+	}
+}
+
+// TestSaveMovesEndpoint verifies that subscribing again with the same endpoint replaces the
+// subscription, even when another user logged in to the browser.
+func TestSaveMovesEndpoint(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite db: %v", err)
+	}
+	subscriptions := &db.GormPushSubscriptionRepository{DB: database}
+	if err := subscriptions.InitRepo(); err != nil {
+		t.Fatalf("failed to migrate push subscriptions table: %v", err)
+	}
+
+	first, err := subscriptions.Save(newBrowser(t).subscription(1, "https://push.example.com/1"))
+	if err != nil {
+		t.Fatalf("failed to save subscription: %v", err)
+	}
+	second, err := subscriptions.Save(newBrowser(t).subscription(2, "https://push.example.com/1"))
+	if err != nil {
+		t.Fatalf("failed to save subscription: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Errorf("got ID %d, want the ID %d of the replaced subscription", second.ID, first.ID)
+	}
+	for userID, want := range map[models.Snowflake]int{1: 0, 2: 1} {
+		got, err := subscriptions.FetchByUserID(userID)
+		if err != nil || len(got) != want {
+			t.Errorf("user %d: got %d subscriptions (%v), want %d", userID, len(got), err, want)
+		}
+	}
+	if err := subscriptions.Delete(second.ID, 1); err == nil {
+		t.Errorf("deleted the subscription of another user")
+	}
+}
@@ -0,0 +1,150 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KuramaSyu/GoToHell/src/backend/src/models"
+)
+
+const (
+	// how long push services keep messages for offline browsers
+	defaultTTL = 24 * time.Hour
+	// how long the VAPID JWT is valid. Push services reject tokens valid for more than 24 hours
+	vapidExpiration  = 12 * time.Hour
+	authSecretLength = 16
+)
+
+// hosts of the push services of the browsers. Entries starting with a dot match all subdomains.
+// Other endpoints are rejected, so that users can't make the server send requests into its own network
+var pushServiceHosts = []string{
+	// Chrome and other Chromium based browsers
+	"fcm.googleapis.com",
+	// Firefox
+	"updates.push.services.mozilla.com",
+	// Safari
+	"web.push.apple.com",
+	// Edge
+	".notify.windows.com",
+}
+
+// ErrSubscriptionGone is returned, when the push service reports that the subscription
+// expired or was unsubscribed. The subscription should be deleted
+var ErrSubscriptionGone = errors.New("push subscription expired")
+
+// ValidateKeys returns an error, if <p256dh> is not a P-256 public key or <auth> is not
+// a 16 byte authentication secret
+func ValidateKeys(p256dh string, auth string) error {
+	public, err := DecodeKey(p256dh)
+	if err != nil {
+		return fmt.Errorf("p256dh is not base64url encoded: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(public); err != nil {
+		return fmt.Errorf("p256dh is not a P-256 public key: %w", err)
+	}
+	secret, err := DecodeKey(auth)
+	if err != nil {
+		return fmt.Errorf("auth is not base64url encoded: %w", err)
+	}
+	if len(secret) != authSecretLength {
+		return fmt.Errorf("auth has %d bytes instead of %d", len(secret), authSecretLength)
+	}
+	return nil
+}
+
+// ValidateEndpoint returns an error, if <endpoint> is not a https URL of a known push service
+func ValidateEndpoint(endpoint string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme != "https" || parsed.User != nil || parsed.Port() != "" {
+		return fmt.Errorf("invalid push endpoint: %s", endpoint)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range pushServiceHosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("push endpoint %s is not served by a known push service", endpoint)
+}
+
+// Sender encrypts messages and sends them to the push services of subscriptions
+type Sender struct {
+	Client *http.Client
+	Keys   *VAPIDKeys
+	// contact of the operator for push services, e.g. mailto:admin@example.com
+	Subject string
+	// how long push services keep messages for offline browsers
+	TTL time.Duration
+	// returns the current time
+	// used for DI and tests
+	Now func() time.Time
+}
+
+func NewSender(keys *VAPIDKeys, subject string, Now func() time.Time) *Sender {
+	return &Sender{
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+			// push services don't redirect, following them would bypass the checked endpoint
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Keys:    keys,
+		Subject: subject,
+		TTL:     defaultTTL,
+		Now:     Now,
+	}
+}
+
+// Send encrypts <payload> for <subscription> and sends it to its push service. Returns
+// ErrSubscriptionGone, when the push service doesn't know the subscription anymore
+func (s *Sender) Send(ctx context.Context, subscription models.PushSubscription, payload []byte) error {
+	public, err := DecodeKey(subscription.P256dh)
+	if err != nil {
+		return err
+	}
+	secret, err := DecodeKey(subscription.Auth)
+	if err != nil {
+		return err
+	}
+	body, err := Encrypt(payload, public, secret)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.Keys.Authorization(subscription.Endpoint, s.Subject, s.Now().Add(vapidExpiration))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(s.TTL.Seconds())))
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case res.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("push service responded with %s: %s", res.Status, message)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package webpush
+
+import "testing"
+
+// TestValidateEndpoint verifies that only https endpoints of known push services are accepted.
+func TestValidateEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{"https://fcm.googleapis.com/fcm/send/abc", true},
+		{"https://updates.push.services.mozilla.com/wpush/v2/abc", true},
+		{"https://web.push.apple.com/abc", true},
+		{"https://wns2-db5p.notify.windows.com/w/?token=abc", true},
+		{"https://notify.windows.com.evil.example/w/", false},
+		{"https://evilnotify.windows.com/w/", false},
+		{"http://fcm.googleapis.com/fcm/send/abc", false},
+		{"https://fcm.googleapis.com:8443/fcm/send/abc", false},
+		{"https://user@fcm.googleapis.com/fcm/send/abc", false},
+		{"https://127.0.0.1/push", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			if err := ValidateEndpoint(tt.endpoint); (err == nil) != tt.valid {
+				t.Errorf("got %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VAPIDKeys is the key pair, which identifies the application server to push services (RFC 8292).
+// Browsers only accept messages signed with the public key their subscription was created with
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+	// base64url encoded private key, as it's configured
+	privateKey string
+	// base64url encoded uncompressed public key, which the frontend passes to
+	// pushManager.subscribe() as applicationServerKey
+	PublicKey string
+}
+
+// GenerateVAPIDKeys returns a new random key pair
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ParseVAPIDKeys(base64.RawURLEncoding.EncodeToString(key.Bytes()))
+}
+
+// ParseVAPIDKeys returns the key pair of the base64url encoded P-256 <privateKey>
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	raw, err := DecodeKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID private key is not base64url encoded: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	public := key.PublicKey().Bytes()
+	return &VAPIDKeys{
+		private: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(public[1:33]),
+				Y:     new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		privateKey: base64.RawURLEncoding.EncodeToString(raw),
+		PublicKey:  base64.RawURLEncoding.EncodeToString(public),
+	}, nil
+}
+
+// PrivateKey returns the base64url encoded private key, which is configured as VAPID_PRIVATE_KEY
+func (k *VAPIDKeys) PrivateKey() string {
+	return k.privateKey
+}
+
+// Authorization returns the Authorization header for requests to <endpoint>. The JWT is signed
+// for the origin of <endpoint>, names <subject> as contact and expires at <expires>
+func (k *VAPIDKeys) Authorization(endpoint string, subject string, expires time.Time) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": expires.Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	// ES256 signatures are r and s with 32 bytes each (RFC 7518 section 3.4)
+	hash := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, hash[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, k.PublicKey), nil
+}
+
+// DecodeKey decodes a key of a push subscription or VAPID key pair. Browsers encode them as
+// base64url, but some add padding
+func DecodeKey(key string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(key, "="))
+}